@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// isEnabled reports whether flights are matched against the airport.
+// Airports are enabled unless configured or switched off.
+func (a AirportConfig) isEnabled() bool {
+	return a.Enabled == nil || *a.Enabled
+}
+
+// normalizeEnabled makes the enabled state explicit so listings show it
+func (a *AirportConfig) normalizeEnabled() {
+	enabled := a.isEnabled()
+	a.Enabled = &enabled
+}
+
+// applyEnabledOverrides applies the runtime toggles to freshly loaded
+// airports, so a config reload does not undo them. Callers hold airportsMu.
+func (at *AirportTracker) applyEnabledOverrides(airports []AirportConfig) {
+	for i := range airports {
+		if enabled, ok := at.enabledOverrides[strings.ToUpper(airports[i].ICAO)]; ok {
+			airports[i].Enabled = &enabled
+		}
+	}
+}
+
+// setAirportEnabled switches an airport on or off until the next restart,
+// returning the updated airport. The airport slice is replaced rather than
+// modified, as airportList promises.
+func (at *AirportTracker) setAirportEnabled(code string, enabled bool) (AirportConfig, bool) {
+	at.airportsMu.Lock()
+	defer at.airportsMu.Unlock()
+	for i, airport := range at.airports {
+		if !strings.EqualFold(airport.ICAO, code) {
+			continue
+		}
+		airports := append([]AirportConfig(nil), at.airports...)
+		airports[i].Enabled = &enabled
+		at.airports = airports
+		at.enabledOverrides[strings.ToUpper(airport.ICAO)] = enabled
+		return airports[i], true
+	}
+	return AirportConfig{}, false
+}
+
+// POST /api/v1/airports/{code}/enable and /disable - Start or stop matching
+// flights against an airport without editing its config. Flights already
+// tracked there age out as usual.
+func (at *AirportTracker) handleSetAirportEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := mux.Vars(r)["code"]
+		airport, ok := at.setAirportEnabled(code, enabled)
+		if !ok {
+			at.metrics.unknownAirports.Inc()
+			http.Error(w, fmt.Sprintf("Unknown airport %q", code), http.StatusNotFound)
+			return
+		}
+		slog.Info("airport toggled", "airport", airport.ICAO, "enabled", enabled, "remote_addr", r.RemoteAddr)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(airport)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDisabledAirportMatchesNoFlights(t *testing.T) {
+	disabled := testAirport("KOFF", 45, -73)
+	disabled.Enabled = ptr(false)
+	at := newTestTracker(t, testAirport("KON", 40, -73), disabled)
+	track(t, at, testUpdate("aaa001", 40.05, -73), testUpdate("bbb002", 45.05, -73))
+
+	if _, ok := at.flights.Get("aaa001"); !ok {
+		t.Error("flight at the enabled airport not tracked")
+	}
+	if _, ok := at.flights.Get("bbb002"); ok {
+		t.Error("flight at the disabled airport tracked")
+	}
+
+	var airports []AirportConfig
+	decodeBody(t, call(at.handleListAirports, http.MethodGet, "/api/v1/airports", nil), &airports)
+	for _, airport := range airports {
+		want := airport.ICAO == "KON"
+		if airport.Enabled == nil || *airport.Enabled != want {
+			t.Errorf("%s listed with enabled %v, want %v", airport.ICAO, airport.Enabled, want)
+		}
+	}
+}
+
+func TestToggleAirportAtRuntime(t *testing.T) {
+	at := newTestTracker(t)
+	toggle := func(action string) AirportConfig {
+		t.Helper()
+		var airport AirportConfig
+		handler := at.handleSetAirportEnabled(action == "enable")
+		decodeBody(t, call(handler, http.MethodPost, "/api/v1/airports/ktst/"+action, map[string]string{"code": "ktst"}), &airport)
+		return airport
+	}
+
+	if airport := toggle("disable"); airport.ICAO != "KTST" || airport.isEnabled() {
+		t.Fatalf("disable returned %+v", airport)
+	}
+	track(t, at, testUpdate("aaa001", 40.05, -73))
+	if _, ok := at.flights.Get("aaa001"); ok {
+		t.Error("flight tracked at a disabled airport")
+	}
+
+	// A reload keeps the runtime toggle
+	if rec := call(at.handleConfigReload, http.MethodPost, "/api/v1/config/reload", nil); rec.Code != http.StatusOK {
+		t.Fatalf("reload code = %d", rec.Code)
+	}
+	if at.airportList()[0].isEnabled() {
+		t.Error("reload re-enabled the airport")
+	}
+
+	if airport := toggle("enable"); !airport.isEnabled() {
+		t.Fatalf("enable returned %+v", airport)
+	}
+	track(t, at, testUpdate("aaa001", 40.05, -73))
+	if _, ok := at.flights.Get("aaa001"); !ok {
+		t.Error("flight not tracked after re-enabling the airport")
+	}
+}
+
+func TestToggleUnknownAirport(t *testing.T) {
+	at := newTestTracker(t)
+	rec := call(at.handleSetAirportEnabled(false), http.MethodPost, "/api/v1/airports/KNONE/disable", map[string]string{"code": "KNONE"})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("code = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
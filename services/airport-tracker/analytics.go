@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultAltitudeBucketM = 1000.0
+	maxAltitudeBuckets     = 100
+)
+
+// AltitudeBucket counts flights whose effective altitude is in [MinM, MaxM)
+type AltitudeBucket struct {
+	MinM  float64 `json:"min_m"`
+	MaxM  float64 `json:"max_m"`
+	Count int     `json:"count"`
+}
+
+// altitudeHistogram buckets flights by effective altitude. With bucketSize
+// set buckets are that many meters wide starting at 0 (or below, for
+// negative altitudes); otherwise bucketCount equal buckets span the observed
+// range. Flights without an altitude are counted separately as unknown.
+// An error is returned if the range would need more than maxBuckets buckets.
+func altitudeHistogram(flights []TrackedFlight, bucketSize float64, bucketCount, maxBuckets int) ([]AltitudeBucket, int, error) {
+	altitudes := make([]float64, 0, len(flights))
+	unknown := 0
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, flight := range flights {
+		altitude, ok := effectiveAltitude(flight.FlightUpdate)
+		if !ok {
+			unknown++
+			continue
+		}
+		altitudes = append(altitudes, altitude)
+		lo, hi = math.Min(lo, altitude), math.Max(hi, altitude)
+	}
+	if len(altitudes) == 0 {
+		return []AltitudeBucket{}, unknown, nil
+	}
+
+	start := math.Min(0, lo)
+	var n int
+	if bucketSize > 0 {
+		start = math.Floor(start/bucketSize) * bucketSize
+		span := math.Floor((hi-start)/bucketSize) + 1
+		if span > float64(maxBuckets) {
+			return nil, unknown, fmt.Errorf("bucket size %gm needs %.0f buckets, more than %d", bucketSize, span, maxBuckets)
+		}
+		n = int(span)
+	} else {
+		// The highest altitude lands in the last bucket via the clamp below
+		bucketSize = math.Max((hi-start)/float64(bucketCount), 1)
+		n = bucketCount
+	}
+
+	buckets := make([]AltitudeBucket, n)
+	for i := range buckets {
+		buckets[i].MinM = start + float64(i)*bucketSize
+		buckets[i].MaxM = buckets[i].MinM + bucketSize
+	}
+	for _, altitude := range altitudes {
+		i := int(math.Floor((altitude - start) / bucketSize))
+		if i >= n {
+			i = n - 1
+		}
+		buckets[i].Count++
+	}
+	return buckets, unknown, nil
+}
+
+// GET /api/v1/flights/altitude-histogram?bucket_size=|buckets= - Count
+// tracked flights per altitude band
+func (at *AirportTracker) handleAltitudeHistogram(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	bucketSize, err := queryFloat(r, "bucket_size", 0)
+	if err != nil || (query.Get("bucket_size") != "" && bucketSize == 0) {
+		http.Error(w, fmt.Sprintf("invalid bucket_size %q", query.Get("bucket_size")), http.StatusBadRequest)
+		return
+	}
+	bucketCount := 0
+	if raw := query.Get("buckets"); raw != "" {
+		bucketCount, err = strconv.Atoi(raw)
+		if err != nil || bucketCount < 1 || bucketCount > maxAltitudeBuckets {
+			http.Error(w, fmt.Sprintf("invalid buckets %q: expected 1-%d", raw, maxAltitudeBuckets), http.StatusBadRequest)
+			return
+		}
+	}
+	if bucketSize == 0 && bucketCount == 0 {
+		bucketSize = defaultAltitudeBucketM
+	}
+
+	flights := at.uniqueAircraft(at.listFlights(nil))
+	buckets, unknown, err := altitudeHistogram(flights, bucketSize, bucketCount, maxAltitudeBuckets)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"buckets": buckets,
+		"unknown": unknown,
+		"count":   len(flights),
+	})
+}
+
+// unknownCountry labels flights that report no origin country
+const unknownCountry = "unknown"
+
+// CountryCount is the number of tracked aircraft from one origin country
+type CountryCount struct {
+	Country string `json:"country"`
+	Count   int    `json:"count"`
+}
+
+// countryCounts counts distinct aircraft by origin country, most common
+// first and then by name. An aircraft tracked at several airports
+// (TRACKING_MODE=all) is counted once.
+func countryCounts(flights []TrackedFlight) []CountryCount {
+	seen := map[string]bool{}
+	counts := map[string]int{}
+	for _, flight := range flights {
+		if seen[flight.ICAO24] {
+			continue
+		}
+		seen[flight.ICAO24] = true
+		country := strings.TrimSpace(flight.OriginCountry)
+		if country == "" {
+			country = unknownCountry
+		}
+		counts[country]++
+	}
+
+	result := make([]CountryCount, 0, len(counts))
+	for country, count := range counts {
+		result = append(result, CountryCount{Country: country, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Country < result[j].Country
+	})
+	return result
+}
+
+// GET /api/v1/flights/countries - Tracked aircraft per origin country
+func (at *AirportTracker) handleFlightCountries(w http.ResponseWriter, r *http.Request) {
+	countries := countryCounts(at.uniqueAircraft(at.listFlights(nil)))
+	total := 0
+	for _, c := range countries {
+		total += c.Count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"countries": countries,
+		"count":     total,
+	})
+}
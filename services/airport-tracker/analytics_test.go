@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// altitudeFlights stores one flight per altitude, plus one with only a
+// geometric altitude and one with none
+func altitudeFlights(at *AirportTracker, altitudes ...float64) {
+	for i, altitude := range altitudes {
+		storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: fmt.Sprintf("aaa%03d", i), BaroAltitude: ptr(altitude)}, AirportCode: "KTST"})
+	}
+	storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "geo001", GeoAltitude: ptr(2500.0)}, AirportCode: "KTST"})
+	storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "unk001"}, AirportCode: "KTST"})
+}
+
+func TestAltitudeHistogramBuckets(t *testing.T) {
+	at := newTestTracker(t)
+	altitudeFlights(at, -100, 500, 1500, 1600, 3200)
+
+	rec := call(at.handleAltitudeHistogram, http.MethodGet, "/api/v1/flights/altitude-histogram?bucket_size=1000", nil)
+	var body struct {
+		Buckets []AltitudeBucket `json:"buckets"`
+		Unknown int              `json:"unknown"`
+		Count   int              `json:"count"`
+	}
+	decodeBody(t, rec, &body)
+	want := []AltitudeBucket{
+		{MinM: -1000, MaxM: 0, Count: 1},
+		{MinM: 0, MaxM: 1000, Count: 1},
+		{MinM: 1000, MaxM: 2000, Count: 2},
+		{MinM: 2000, MaxM: 3000, Count: 1},
+		{MinM: 3000, MaxM: 4000, Count: 1},
+	}
+	if fmt.Sprint(body.Buckets) != fmt.Sprint(want) {
+		t.Errorf("buckets = %v, want %v", body.Buckets, want)
+	}
+	if body.Unknown != 1 || body.Count != 7 {
+		t.Errorf("unknown = %d, count = %d; want 1 and 7", body.Unknown, body.Count)
+	}
+}
+
+func TestAltitudeHistogramBucketCount(t *testing.T) {
+	at := newTestTracker(t)
+	altitudeFlights(at, 0, 1000, 4000)
+
+	rec := call(at.handleAltitudeHistogram, http.MethodGet, "/api/v1/flights/altitude-histogram?buckets=2", nil)
+	var body struct {
+		Buckets []AltitudeBucket `json:"buckets"`
+	}
+	decodeBody(t, rec, &body)
+	// 0-2000 holds 0 and 1000; the top bucket holds 2500 and the maximum
+	if len(body.Buckets) != 2 || body.Buckets[0].Count != 2 || body.Buckets[1].Count != 2 {
+		t.Errorf("buckets = %v, want two buckets of two", body.Buckets)
+	}
+}
+
+func TestAltitudeHistogramRejectsBadParameters(t *testing.T) {
+	at := newTestTracker(t)
+	altitudeFlights(at, 0, 40000)
+	for _, query := range []string{"?bucket_size=0", "?bucket_size=-5", "?buckets=0", "?buckets=101", "?bucket_size=100"} {
+		if rec := call(at.handleAltitudeHistogram, http.MethodGet, "/api/v1/flights/altitude-histogram"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: code = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestFlightCountries(t *testing.T) {
+	t.Setenv("TRACKING_MODE", TrackingAll)
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 40.18, -73))
+	for i, country := range []string{"Germany", "United States", "France", "United States", " Germany ", "United States", ""} {
+		storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: fmt.Sprintf("abc%03d", i), OriginCountry: country}, AirportCode: "KAAA"})
+	}
+	// Tracked at both airports but counted once
+	storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "abc000", OriginCountry: "Germany"}, AirportCode: "KBBB"})
+
+	var body struct {
+		Countries []CountryCount `json:"countries"`
+		Count     int            `json:"count"`
+	}
+	decodeBody(t, call(at.handleFlightCountries, http.MethodGet, "/api/v1/flights/countries", nil), &body)
+	want := []CountryCount{
+		{Country: "United States", Count: 3},
+		{Country: "Germany", Count: 2},
+		{Country: "France", Count: 1},
+		{Country: unknownCountry, Count: 1},
+	}
+	if fmt.Sprint(body.Countries) != fmt.Sprint(want) {
+		t.Errorf("countries = %v, want %v", body.Countries, want)
+	}
+	if body.Count != 7 {
+		t.Errorf("count = %d, want 7", body.Count)
+	}
+}
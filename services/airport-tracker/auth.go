@@ -0,0 +1,28 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// requireAPIKey guards write and admin endpoints when API_KEY is set.
+// The key is accepted from X-API-Key, or from dapr-api-token so the Dapr
+// sidecar can deliver pub/sub messages when APP_API_TOKEN is set to the
+// same value. With no key configured it returns next unchanged.
+func requireAPIKey(key string, next http.Handler) http.Handler {
+	if key == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-API-Key")
+		if got == "" {
+			got = r.Header.Get("dapr-api-token")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+			w.Header().Set("WWW-Authenticate", `APIKey header="X-API-Key"`)
+			http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
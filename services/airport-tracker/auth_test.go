@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	served := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) }
+	for _, tc := range []struct {
+		name   string
+		key    string
+		header string
+		value  string
+		code   int
+	}{
+		{"missing key", "s3cret", "", "", http.StatusUnauthorized},
+		{"wrong key", "s3cret", "X-API-Key", "guess", http.StatusUnauthorized},
+		{"correct key", "s3cret", "X-API-Key", "s3cret", http.StatusAccepted},
+		{"Dapr app token", "s3cret", "dapr-api-token", "s3cret", http.StatusAccepted},
+		{"no key configured", "", "", "", http.StatusAccepted},
+	} {
+		req := httptest.NewRequest(http.MethodPost, flightUpdateRoute, nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		rec := httptest.NewRecorder()
+		requireAPIKey(tc.key, http.HandlerFunc(served)).ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s: code = %d, want %d", tc.name, rec.Code, tc.code)
+		}
+		if tc.code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 without WWW-Authenticate", tc.name)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultRedisKeyPrefix = "airport-tracker:flight:"
+	defaultRedisTTL       = 5 * time.Minute
+	backendTimeout        = time.Second
+)
+
+// FlightBackend is where the list endpoints read tracked flights from.
+// processFlightUpdate always writes the local store first and then mirrors
+// the result to the backend, so a shared backend lets every replica answer
+// queries for the whole fleet.
+type FlightBackend interface {
+	Save(ctx context.Context, key string, flight TrackedFlight) error
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) ([]TrackedFlight, error)
+}
+
+// memoryBackend serves reads straight from the local store; writes are
+// no-ops because the store is already up to date.
+type memoryBackend struct {
+	store *flightStore
+}
+
+func (b memoryBackend) Save(ctx context.Context, key string, flight TrackedFlight) error { return nil }
+
+func (b memoryBackend) Delete(ctx context.Context, key string) error { return nil }
+
+func (b memoryBackend) List(ctx context.Context) ([]TrackedFlight, error) {
+	return b.store.Collect(nil), nil
+}
+
+// redisFlightClient is the subset of the go-redis client used by
+// redisBackend, so tests can substitute a double.
+type redisFlightClient interface {
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+}
+
+// redisBackend mirrors each flight as a JSON string under prefix+key, where
+// key is the flight's store key (see flightKey).
+// Keys expire after ttl so flights from a crashed replica do not linger.
+type redisBackend struct {
+	client redisFlightClient
+	prefix string
+	ttl    time.Duration
+}
+
+func (b *redisBackend) Save(ctx context.Context, key string, flight TrackedFlight) error {
+	data, err := json.Marshal(flight)
+	if err != nil {
+		return err
+	}
+	return b.client.Set(ctx, b.prefix+key, data, b.ttl).Err()
+}
+
+func (b *redisBackend) Delete(ctx context.Context, key string) error {
+	return b.client.Del(ctx, b.prefix+key).Err()
+}
+
+func (b *redisBackend) List(ctx context.Context) ([]TrackedFlight, error) {
+	flights := []TrackedFlight{}
+	var cursor uint64
+	for {
+		keys, next, err := b.client.Scan(ctx, cursor, b.prefix+"*", 500).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			values, err := b.client.MGet(ctx, keys...).Result()
+			if err != nil {
+				return nil, err
+			}
+			for i, value := range values {
+				raw, ok := value.(string)
+				if !ok {
+					continue // expired between SCAN and MGET
+				}
+				var flight TrackedFlight
+				if err := json.Unmarshal([]byte(raw), &flight); err != nil {
+					return nil, fmt.Errorf("failed to decode %s: %w", keys[i], err)
+				}
+				flights = append(flights, flight)
+			}
+		}
+		if next == 0 {
+			return flights, nil
+		}
+		cursor = next
+	}
+}
+
+// newBackendFromEnv returns the Redis backend when REDIS_ADDR is set and the
+// in-memory backend otherwise.
+func newBackendFromEnv(store *flightStore) FlightBackend {
+	addr := envString("REDIS_ADDR", "")
+	if addr == "" {
+		return memoryBackend{store: store}
+	}
+
+	prefix := envString("REDIS_KEY_PREFIX", defaultRedisKeyPrefix)
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: envString("REDIS_PASSWORD", ""),
+	})
+	slog.Info("mirroring tracked flights to Redis", "address", addr)
+	return &redisBackend{
+		client: client,
+		prefix: prefix,
+		ttl:    time.Duration(envInt("REDIS_FLIGHT_TTL_SECONDS", int(defaultRedisTTL/time.Second))) * time.Second,
+	}
+}
+
+// listFlights returns the flights accepted by match from the configured
+// backend, falling back to the local store if the backend is unavailable.
+func (at *AirportTracker) listFlights(match func(*TrackedFlight) bool) []TrackedFlight {
+	if _, ok := at.backend.(memoryBackend); ok {
+		return at.flights.Collect(match)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+	all, err := at.backend.List(ctx)
+	if err != nil {
+		slog.Warn("failed to list flights from backend, using local state", "error", err)
+		return at.flights.Collect(match)
+	}
+	if match == nil {
+		return all
+	}
+	flights := []TrackedFlight{}
+	for i := range all {
+		if match(&all[i]) {
+			flights = append(flights, all[i])
+		}
+	}
+	return flights
+}
+
+// snapshotFlights is listFlights(nil) as of a single instant: the in-memory
+// store is copied with all of its shards locked at once
+func (at *AirportTracker) snapshotFlights() []TrackedFlight {
+	if _, ok := at.backend.(memoryBackend); ok {
+		return at.flights.Snapshot()
+	}
+	return at.listFlights(nil)
+}
+
+// lookupFlight returns one flight by its normalized ICAO24 address, the
+// entry at the nearest airport when it is tracked at several. The in-memory
+// backend reads the store by key instead of scanning.
+func (at *AirportTracker) lookupFlight(icao24 string) (TrackedFlight, bool) {
+	if _, ok := at.backend.(memoryBackend); ok {
+		return at.localFlight(icao24)
+	}
+	flights := at.listFlights(func(flight *TrackedFlight) bool {
+		return flight.ICAO24 == icao24
+	})
+	if len(flights) == 0 {
+		return TrackedFlight{}, false
+	}
+	nearest := flights[0]
+	for _, flight := range flights[1:] {
+		if flight.DistanceKm < nearest.DistanceKm {
+			nearest = flight
+		}
+	}
+	return nearest, true
+}
+
+// mirrorFlight writes a freshly tracked flight to the backend. Failures are
+// logged; the local store remains authoritative for this replica.
+func (at *AirportTracker) mirrorFlight(flight TrackedFlight) {
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+	if err := at.backend.Save(ctx, at.flightKey(flight.ICAO24, flight.AirportCode), flight); err != nil {
+		slog.Warn("failed to mirror flight", "icao24", flight.ICAO24, "error", err)
+	}
+}
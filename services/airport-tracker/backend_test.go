@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeBackend is a shared backend held in memory, standing in for Redis
+type fakeBackend struct {
+	mu      sync.Mutex
+	flights map[string]TrackedFlight
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{flights: map[string]TrackedFlight{}}
+}
+
+func (b *fakeBackend) Save(ctx context.Context, key string, flight TrackedFlight) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flights[key] = flight
+	return nil
+}
+
+func (b *fakeBackend) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.flights, key)
+	return nil
+}
+
+func (b *fakeBackend) List(ctx context.Context) ([]TrackedFlight, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	flights := []TrackedFlight{}
+	for _, flight := range b.flights {
+		flights = append(flights, flight)
+	}
+	return flights, nil
+}
+
+func deleteRequest(at *AirportTracker, icao24 string) *httptest.ResponseRecorder {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/api/v1/flights/"+icao24, nil), map[string]string{"icao24": icao24})
+	rec := httptest.NewRecorder()
+	at.handleDeleteFlight(rec, req)
+	return rec
+}
+
+func TestDeleteFlightTrackedOnlyInSharedBackend(t *testing.T) {
+	at := newTestTracker(t)
+	backend := newFakeBackend()
+	at.backend = backend
+	// Tracked by another replica: present in the backend, not locally
+	backend.Save(context.Background(), "abc123", TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "abc123"}, AirportCode: "KTST"})
+
+	if _, ok := at.lookupFlight("abc123"); !ok {
+		t.Fatal("flight not visible through the backend")
+	}
+	if rec := deleteRequest(at, "ABC123"); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE code = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if _, ok := at.lookupFlight("abc123"); ok {
+		t.Error("flight still visible after DELETE")
+	}
+	if rec := deleteRequest(at, "abc123"); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE code = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDeleteFlightRemovesLocalAndBackendEntries(t *testing.T) {
+	at := newTestTracker(t)
+	backend := newFakeBackend()
+	at.backend = backend
+	if _, err := at.processFlightUpdate(context.Background(), testUpdate("abc123", 40.05, -73)); err != nil {
+		t.Fatal(err)
+	}
+	if rec := deleteRequest(at, "abc123"); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE code = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if _, ok := at.flights.Get("abc123"); ok {
+		t.Error("flight still in the local store")
+	}
+	if flights, _ := backend.List(context.Background()); len(flights) != 0 {
+		t.Errorf("backend still holds %d flights", len(flights))
+	}
+}
+
+// fakeRedis is a redisFlightClient holding string values in memory. SCAN
+// returns pageSize keys at a time so the cursor is exercised.
+type fakeRedis struct {
+	mu       sync.Mutex
+	values   map[string]string
+	ttls     map[string]time.Duration
+	pageSize int
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: map[string]string{}, ttls: map[string]time.Duration{}, pageSize: 2}
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[key] = string(value.([]byte))
+	r.ttls[key] = expiration
+	cmd := redis.NewStatusCmd(ctx)
+	cmd.SetVal("OK")
+	return cmd
+}
+
+func (r *fakeRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, key := range keys {
+		if _, ok := r.values[key]; ok {
+			delete(r.values, key)
+			n++
+		}
+	}
+	cmd := redis.NewIntCmd(ctx)
+	cmd.SetVal(n)
+	return cmd
+}
+
+// Scan supports only "prefix*" patterns
+func (r *fakeRedis) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []string
+	for key := range r.values {
+		if strings.HasPrefix(key, strings.TrimSuffix(match, "*")) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	start := min(int(cursor), len(keys))
+	end := min(start+r.pageSize, len(keys))
+	next := uint64(end)
+	if end == len(keys) {
+		next = 0
+	}
+	cmd := redis.NewScanCmd(ctx, nil)
+	cmd.SetVal(keys[start:end], next)
+	return cmd
+}
+
+func (r *fakeRedis) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if value, ok := r.values[key]; ok {
+			values[i] = value
+		}
+	}
+	cmd := redis.NewSliceCmd(ctx)
+	cmd.SetVal(values)
+	return cmd
+}
+
+func TestRedisBackendRoundTrip(t *testing.T) {
+	client := newFakeRedis()
+	backend := &redisBackend{client: client, prefix: defaultRedisKeyPrefix, ttl: time.Minute}
+	ctx := context.Background()
+	for _, icao24 := range []string{"aaa001", "bbb002", "ccc003"} {
+		flight := TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: icao24}, AirportCode: "KTST", Status: StatusNearby}
+		if err := backend.Save(ctx, icao24, flight); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ttl := client.ttls[defaultRedisKeyPrefix+"aaa001"]; ttl != time.Minute {
+		t.Errorf("ttl = %v, want %v", ttl, time.Minute)
+	}
+	client.values["other:key"] = "not a flight"
+
+	flights, err := backend.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flights) != 3 {
+		t.Fatalf("listed %d flights across SCAN pages, want 3", len(flights))
+	}
+
+	if err := backend.Delete(ctx, "bbb002"); err != nil {
+		t.Fatal(err)
+	}
+	if flights, _ := backend.List(ctx); len(flights) != 2 {
+		t.Errorf("listed %d flights after delete, want 2", len(flights))
+	}
+}
+
+func TestRedisBackendSkipsKeysExpiredBeforeMGet(t *testing.T) {
+	client := newFakeRedis()
+	backend := &redisBackend{client: &expiringRedis{fakeRedis: client}, prefix: defaultRedisKeyPrefix, ttl: time.Minute}
+	ctx := context.Background()
+	backend.Save(ctx, "aaa001", TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "aaa001"}})
+	backend.Save(ctx, "bbb002", TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "bbb002"}})
+
+	flights, err := backend.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flights) != 1 || flights[0].ICAO24 != "bbb002" {
+		t.Errorf("flights = %+v, want only bbb002", flights)
+	}
+}
+
+// expiringRedis expires aaa001 between SCAN and MGET
+type expiringRedis struct {
+	*fakeRedis
+}
+
+func (r *expiringRedis) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	r.Del(ctx, defaultRedisKeyPrefix+"aaa001")
+	return r.fakeRedis.MGet(ctx, keys...)
+}
+
+func TestReplicasShareFlightsThroughRedis(t *testing.T) {
+	client := newFakeRedis()
+	first, second := newTestTracker(t), newTestTracker(t)
+	for _, at := range []*AirportTracker{first, second} {
+		at.backend = &redisBackend{client: client, prefix: defaultRedisKeyPrefix, ttl: time.Minute}
+	}
+	track(t, first, testUpdate("abc123", 40.05, -73))
+
+	if second.flights.Len() != 0 {
+		t.Fatal("second replica tracked the flight locally")
+	}
+	flight, ok := second.lookupFlight("abc123")
+	if !ok || flight.AirportCode != "KTST" {
+		t.Fatalf("second replica lookup = %+v, %v; want the flight at KTST", flight, ok)
+	}
+	if flights := second.listFlights(nil); len(flights) != 1 {
+		t.Errorf("second replica listed %d flights, want 1", len(flights))
+	}
+}
+
+func TestMemoryBackendIsTheDefault(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "")
+	if _, ok := newBackendFromEnv(newFlightStore(1)).(memoryBackend); !ok {
+		t.Error("backend without REDIS_ADDR is not the in-memory one")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// batchEntryResult reports what happened to one entry of a batch that was
+// not processed, by its index in the request
+type batchEntryResult struct {
+	Index   int    `json:"index"`
+	ICAO24  string `json:"icao24,omitempty"`
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// POST /api/v1/flights/batch - Ingest an array of flight updates, in any of
+// the shapes extractFlightEntries accepts, reporting on each entry
+func (at *AirportTracker) handleFlightBatch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := at.extractFlightEntries(r, body)
+	if err != nil {
+		at.metrics.decodeErrors.Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := eventTraceContext(r, body)
+	counts := map[string]int{
+		OutcomeProcessed: 0,
+		OutcomeSkipped:   0,
+		OutcomeQueued:    0,
+		OutcomeRejected:  0,
+		OutcomeRetry:     0,
+	}
+	results := []batchEntryResult{}
+	for i, entry := range entries {
+		var flight FlightUpdate
+		if err := json.Unmarshal(entry, &flight); err != nil {
+			at.metrics.decodeErrors.Inc()
+			counts[OutcomeRejected]++
+			results = append(results, batchEntryResult{Index: i, Outcome: OutcomeRejected, Reason: err.Error()})
+			continue
+		}
+
+		outcome, err := at.ingest(ctx, flight)
+		var panicErr *processingPanicError
+		if errors.As(err, &panicErr) {
+			counts[OutcomeRejected]++
+			results = append(results, batchEntryResult{Index: i, ICAO24: flight.ICAO24, Outcome: OutcomeRejected, Reason: err.Error()})
+			continue
+		}
+		if err != nil {
+			counts[OutcomeRetry]++
+			results = append(results, batchEntryResult{Index: i, ICAO24: flight.ICAO24, Outcome: OutcomeRetry, Reason: err.Error()})
+			if errors.Is(err, errQueueFull) {
+				// Later entries would be refused too
+				for j := i + 1; j < len(entries); j++ {
+					counts[OutcomeRetry]++
+					results = append(results, batchEntryResult{Index: j, Outcome: OutcomeRetry, Reason: err.Error()})
+				}
+				break
+			}
+			continue
+		}
+		counts[outcome.result]++
+		if outcome.result != OutcomeProcessed && outcome.result != OutcomeQueued {
+			results = append(results, batchEntryResult{Index: i, ICAO24: flight.ICAO24, Outcome: outcome.result, Reason: outcome.reason})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":     len(entries),
+		"processed": counts[OutcomeProcessed],
+		"queued":    counts[OutcomeQueued],
+		"skipped":   counts[OutcomeSkipped],
+		"rejected":  counts[OutcomeRejected],
+		"retry":     counts[OutcomeRetry],
+		"results":   results,
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// batchEntries is a mix of two trackable updates, one outside every
+// geofence, one without a position fix and one that does not decode
+func batchEntries() string {
+	now := time.Now().Unix()
+	update := func(icao24 string, lat, lon float64) string {
+		return fmt.Sprintf(`{"icao24":%q,"latitude":%v,"longitude":%v,"time_position":%d,"last_contact":%d}`, icao24, lat, lon, now, now)
+	}
+	return "[" + strings.Join([]string{
+		update("aaa001", 40.05, -73),
+		update("bbb002", 10, 10),
+		update("ccc003", 0, 0),
+		`{"icao24":42}`,
+		update("ddd004", 40.1, -73),
+	}, ",") + "]"
+}
+
+func TestFlightBatch(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"plain array", "application/json", batchEntries()},
+		{"cloudevent", "application/cloudevents+json",
+			`{"specversion":"1.0","type":"flight.update","source":"feeder","id":"1","datacontenttype":"application/json","data":` + batchEntries() + `}`},
+		{"batch field", "application/json", `{"flights":` + batchEntries() + `}`},
+	} {
+		at := newTestTracker(t)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/flights/batch", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		rec := httptest.NewRecorder()
+		at.handleFlightBatch(rec, req)
+
+		var body struct {
+			Total     int                `json:"total"`
+			Processed int                `json:"processed"`
+			Skipped   int                `json:"skipped"`
+			Rejected  int                `json:"rejected"`
+			Results   []batchEntryResult `json:"results"`
+		}
+		decodeBody(t, rec, &body)
+		if body.Total != 5 || body.Processed != 2 || body.Skipped != 2 || body.Rejected != 1 {
+			t.Errorf("%s: %+v, want 5 total, 2 processed, 2 skipped, 1 rejected", tc.name, body)
+		}
+		var reported []string
+		for _, r := range body.Results {
+			reported = append(reported, fmt.Sprintf("%d:%s", r.Index, r.Outcome))
+		}
+		if got := strings.Join(reported, " "); got != "1:skipped 2:skipped 3:rejected" {
+			t.Errorf("%s: results %s, want the unprocessed entries by index", tc.name, got)
+		}
+		if at.flights.Len() != 2 {
+			t.Errorf("%s: %d flights tracked, want 2", tc.name, at.flights.Len())
+		}
+	}
+}
+
+func TestFlightBatchRejectsMalformedBody(t *testing.T) {
+	at := newTestTracker(t)
+	for _, body := range []string{`[{"icao24":`, `{"flights":{"icao24":"abc123"}}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/flights/batch", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		at.handleFlightBatch(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: code = %d, want 400", body, rec.Code)
+		}
+	}
+}
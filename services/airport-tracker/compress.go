@@ -0,0 +1,126 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const defaultGzipMinBytes = 1024
+
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+// compressResponses gzips GET responses of at least minBytes for clients
+// that accept it. Smaller responses and those that already set a
+// Content-Encoding (e.g. /metrics) pass through, as do WebSocket upgrades;
+// a Flush before minBytes are written, as Server-Sent Events do, also
+// commits the response uncompressed.
+func compressResponses(minBytes int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+				r.Header.Get("Upgrade") != "" || !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			gw := &gzipResponseWriter{ResponseWriter: w, minBytes: minBytes, status: http.StatusOK}
+			defer gw.finish()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is large enough to be worth compressing
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minBytes    int
+	status      int
+	wroteHeader bool
+	buf         []byte
+	decided     bool
+	gz          *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if !g.wroteHeader {
+		g.status, g.wroteHeader = status, true
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= g.minBytes {
+		if err := g.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide commits the response, compressed when large is true and nothing
+// else has encoded it, and writes out the buffered bytes
+func (g *gzipResponseWriter) decide(large bool) error {
+	g.decided = true
+	header := g.ResponseWriter.Header()
+	if large && header.Get("Content-Encoding") == "" && g.status == http.StatusOK {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		g.gz = gzipWriters.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+	buffered := g.buf
+	g.buf = nil
+	if len(buffered) == 0 {
+		return nil
+	}
+	if g.gz != nil {
+		_, err := g.gz.Write(buffered)
+		return err
+	}
+	_, err := g.ResponseWriter.Write(buffered)
+	return err
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.decide(false)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish completes the response once the handler returns
+func (g *gzipResponseWriter) finish() {
+	if !g.decided {
+		g.decide(false)
+	}
+	if g.gz != nil {
+		g.gz.Close()
+		gzipWriters.Put(g.gz)
+		g.gz = nil
+	}
+}
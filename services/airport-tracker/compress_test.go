@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func gzipGet(handler http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/flights/all", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLargeResponsesAreGzipped(t *testing.T) {
+	at := newTestTracker(t)
+	now := time.Now()
+	at.clock = func() time.Time { return now } // ages match across requests
+	for i := 0; i < 50; i++ {
+		track(t, at, testUpdate(fmt.Sprintf("abc%03d", i), 40.05, -73))
+	}
+	handler := compressResponses(defaultGzipMinBytes)(http.HandlerFunc(at.handleAllFlights))
+
+	plain := gzipGet(handler, "")
+	if plain.Header().Get("Content-Encoding") != "" || plain.Body.Len() < defaultGzipMinBytes {
+		t.Fatalf("without Accept-Encoding: encoding %q, %d bytes", plain.Header().Get("Content-Encoding"), plain.Body.Len())
+	}
+
+	rec := gzipGet(handler, "deflate, gzip;q=0.8")
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("headers = %v, want gzip varying on Accept-Encoding", rec.Header())
+	}
+	if rec.Body.Len() >= plain.Body.Len() {
+		t.Errorf("compressed %d bytes, plain %d", rec.Body.Len(), plain.Body.Len())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, plain.Body.Bytes()) {
+		t.Error("decompressed body differs from the uncompressed response")
+	}
+}
+
+func TestSmallAndEncodedResponsesPassThrough(t *testing.T) {
+	small := compressResponses(defaultGzipMinBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"healthy"}`))
+	}))
+	if rec := gzipGet(small, "gzip"); rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{"status":"healthy"}` {
+		t.Errorf("small response: encoding %q, body %q", rec.Header().Get("Content-Encoding"), rec.Body)
+	}
+
+	large := bytes.Repeat([]byte("x"), 4*defaultGzipMinBytes)
+	encoded := compressResponses(defaultGzipMinBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write(large)
+	}))
+	if rec := gzipGet(encoded, "gzip"); rec.Header().Get("Content-Encoding") != "br" || !bytes.Equal(rec.Body.Bytes(), large) {
+		t.Error("already encoded response was compressed again")
+	}
+
+	refused := compressResponses(defaultGzipMinBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(large)
+	}))
+	if rec := gzipGet(refused, "gzip;q=0"); rec.Header().Get("Content-Encoding") != "" {
+		t.Error("gzip;q=0 response was compressed")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Confidence scoring
+//
+//	confidence = source × freshness × sanity, rounded to two decimals
+//
+// source reflects how the position was obtained (OpenSky position_source):
+// ADS-B 1.0, ASTERIX 0.9, MLAT 0.75, FLARM 0.6, anything else 0.5.
+//
+// freshness is 1.0 while the observation time (see observedAt) is at most
+// confidenceFreshFor old and falls linearly to confidenceFloor at
+// confidenceStaleAfter and beyond.
+//
+// sanity starts at 1.0 and is multiplied by 0.5 when the implied ground
+// speed differs from the reported velocity by more than
+// confidenceMaxSpeedDiscrepancyMS (a likely position jump), and by 0.8 when
+// the update reports no altitude.
+const (
+	confidenceFreshFor              = 10 * time.Second
+	confidenceStaleAfter            = 5 * time.Minute
+	confidenceFloor                 = 0.2
+	confidenceMaxSpeedDiscrepancyMS = 100.0
+)
+
+var positionSourceConfidence = map[int]float64{
+	0: 1.0,  // ADS-B
+	1: 0.9,  // ASTERIX
+	2: 0.75, // MLAT
+	3: 0.6,  // FLARM
+}
+
+// flightConfidence scores how trustworthy an update's state is as of now
+func flightConfidence(update FlightUpdate, now, observed time.Time, speedDiscrepancy *float64) float64 {
+	source, ok := positionSourceConfidence[update.PositionSource]
+	if !ok {
+		source = 0.5
+	}
+
+	var freshness float64
+	switch age := now.Sub(observed); {
+	case age <= confidenceFreshFor:
+		freshness = 1
+	case age >= confidenceStaleAfter:
+		freshness = confidenceFloor
+	default:
+		frac := float64(age-confidenceFreshFor) / float64(confidenceStaleAfter-confidenceFreshFor)
+		freshness = 1 - frac*(1-confidenceFloor)
+	}
+
+	sanity := 1.0
+	if speedDiscrepancy != nil && math.Abs(*speedDiscrepancy) > confidenceMaxSpeedDiscrepancyMS {
+		sanity *= 0.5
+	}
+	if _, ok := effectiveAltitude(update); !ok {
+		sanity *= 0.8
+	}
+
+	return math.Round(source*freshness*sanity*100) / 100
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlightConfidence(t *testing.T) {
+	now := time.Now()
+	withAltitude := FlightUpdate{BaroAltitude: ptr(1000.0)}
+	source := func(update FlightUpdate, positionSource int) FlightUpdate {
+		update.PositionSource = positionSource
+		return update
+	}
+	for _, tc := range []struct {
+		name        string
+		update      FlightUpdate
+		age         time.Duration
+		discrepancy *float64
+		want        float64
+	}{
+		{"fresh ADS-B", source(withAltitude, 0), 5 * time.Second, ptr(10.0), 1},
+		{"aging MLAT", source(withAltitude, 2), 2 * time.Minute, nil, 0.52},
+		{"unknown source", source(withAltitude, 7), 0, nil, 0.5},
+		{"stale FLARM, jumping, no altitude", source(FlightUpdate{}, 3), 10 * time.Minute, ptr(-150.0), 0.05},
+	} {
+		if got := flightConfidence(tc.update, now, now.Add(-tc.age), tc.discrepancy); got != tc.want {
+			t.Errorf("%s: confidence %g, want %g", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTrackedFlightCarriesConfidence(t *testing.T) {
+	at := newTestTracker(t)
+	good := testUpdate("aaa001", 40.05, -73)
+	good.BaroAltitude = ptr(1000.0)
+	poor := testUpdate("bbb002", 40.05, -73)
+	poor.PositionSource = 3
+	track(t, at, good, poor)
+
+	high, _ := at.flights.Get("aaa001")
+	low, _ := at.flights.Get("bbb002")
+	if high.Confidence != 1 || low.Confidence != 0.48 {
+		t.Errorf("confidence = %g and %g, want 1 and 0.48", high.Confidence, low.Confidence)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRadiusInNauticalMilesConvertsToKm(t *testing.T) {
+	airport := testAirport("KNMI", 40, -73)
+	airport.RadiusKm, airport.RadiusUnit = 10, "NM"
+	airports, err := parseAirportConfig(writeAirports(t, airport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := airports[0]
+	if math.Abs(got.RadiusKm-18.52) > 1e-9 || got.RadiusUnit != RadiusUnitKm {
+		t.Errorf("radius = %v %s, want 18.52 km", got.RadiusKm, got.RadiusUnit)
+	}
+	if got.ConfiguredRadius != 10 || got.ConfiguredRadiusUnit != RadiusUnitNm {
+		t.Errorf("configured radius = %v %s, want 10 nm", got.ConfiguredRadius, got.ConfiguredRadiusUnit)
+	}
+
+	// 15 km out is inside 10 nm but would be outside a 10 km radius
+	lat, lon := destinationPoint(40, -73, 0, 15)
+	if !got.contains(lat, lon, haversineDistance(lat, lon, 40, -73)) {
+		t.Error("point 15 km out is outside a 10 nm geofence")
+	}
+	lat, lon = destinationPoint(40, -73, 0, 19)
+	if got.contains(lat, lon, haversineDistance(lat, lon, 40, -73)) {
+		t.Error("point 19 km out is inside a 10 nm geofence")
+	}
+}
+
+func TestThresholdsInFeetConvertToMeters(t *testing.T) {
+	airport := testAirport("KFTI", 40, -73)
+	airport.ArrivalThresholdM, airport.DepartureThresholdM, airport.AltitudeUnit = 10000, 5000, "Ft"
+	airports, err := parseAirportConfig(writeAirports(t, airport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := airports[0]
+	if math.Abs(got.ArrivalThresholdM-3048) > 0.01 || math.Abs(got.DepartureThresholdM-1524) > 0.01 {
+		t.Errorf("thresholds = %v/%v m, want 3048/1524", got.ArrivalThresholdM, got.DepartureThresholdM)
+	}
+	if got.AltitudeUnit != AltitudeUnitMeters {
+		t.Errorf("altitude_unit = %q, want %q", got.AltitudeUnit, AltitudeUnitMeters)
+	}
+}
+
+func TestUnknownUnitsAreRejected(t *testing.T) {
+	radius := testAirport("KBAD", 40, -73)
+	radius.RadiusUnit = "mi"
+	altitude := testAirport("KBAE", 41, -73)
+	altitude.AltitudeUnit = "yd"
+	_, err := parseAirportConfig(writeAirports(t, radius, altitude))
+	if err == nil {
+		t.Fatal("config with unknown units loaded")
+	}
+	for _, want := range []string{"radius_unit", "altitude_unit"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestUnknownDuplicateICAOModeFailsWithoutDuplicates(t *testing.T) {
+	t.Setenv("DUPLICATE_ICAO_MODE", "newest")
+	_, err := NewAirportTracker(writeAirports(t, testAirport("KONE", 40, -73)))
+	if err == nil || !strings.Contains(err.Error(), "DUPLICATE_ICAO_MODE") {
+		t.Fatalf("err = %v, want an unknown DUPLICATE_ICAO_MODE error", err)
+	}
+}
+
+func TestDuplicateICAOModes(t *testing.T) {
+	first, second := testAirport("KDUP", 40, -73), testAirport("kdup", 41, -73)
+	for _, tc := range []struct {
+		mode  string
+		codes string
+		lat   float64
+	}{
+		{DuplicateICAOFirst, "KDUP", 40},
+		{DuplicateICAOLast, "kdup", 41},
+		{DuplicateICAOSuffix, "KDUP kdup-2", 40},
+	} {
+		resolved, err := resolveDuplicateICAOs([]AirportConfig{first, second}, tc.mode)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.mode, err)
+		}
+		var codes []string
+		for _, airport := range resolved {
+			codes = append(codes, airport.ICAO)
+		}
+		if strings.Join(codes, " ") != tc.codes || resolved[0].Latitude != tc.lat {
+			t.Errorf("%s: codes %v, first latitude %v; want %s, %v", tc.mode, codes, resolved[0].Latitude, tc.codes, tc.lat)
+		}
+	}
+	if _, err := resolveDuplicateICAOs([]AirportConfig{first, second}, DuplicateICAOError); err == nil {
+		t.Error("error mode accepted duplicates")
+	}
+}
+
+func TestAbsurdRadiusWarnsByDefault(t *testing.T) {
+	logs := captureLogs(t, "warn")
+	airport := testAirport("KBIG", 40, -73)
+	airport.RadiusKm = 5000
+	at := newTestTracker(t, airport)
+	if len(at.airportList()) != 1 {
+		t.Fatal("airport with an absurd radius was not loaded in warn mode")
+	}
+	if !strings.Contains(logs.String(), "suspicious airport radius") || !strings.Contains(logs.String(), "KBIG") {
+		t.Errorf("no radius warning logged: %s", logs)
+	}
+}
+
+func TestAbsurdRadiusRejectedWhenConfigured(t *testing.T) {
+	t.Setenv("RADIUS_CHECK_MODE", "reject")
+	t.Setenv("MAX_RADIUS_KM", "100")
+	airport := testAirport("KBIG", 40, -73)
+	airport.RadiusKm = 101
+	_, err := NewAirportTracker(writeAirports(t, airport))
+	if err == nil || !strings.Contains(err.Error(), "KBIG radius 101.0 km exceeds 100.0 km") {
+		t.Errorf("err = %v, want a suspicious radius error", err)
+	}
+}
+
+func TestNonPositiveRadiusRejected(t *testing.T) {
+	for _, radius := range []float64{0, -5} {
+		airport := testAirport("KZER", 40, -73)
+		airport.RadiusKm = radius
+		if _, err := parseAirportConfig(writeAirports(t, airport)); err == nil || !strings.Contains(err.Error(), "radius_km must be positive") {
+			t.Errorf("radius %v: err = %v", radius, err)
+		}
+	}
+}
+
+func TestAirportConfigValidation(t *testing.T) {
+	valid := testAirport("KVAL", 40, -73)
+	if airports, err := parseAirportConfig(writeAirports(t, valid)); err != nil || len(airports) != 1 {
+		t.Fatalf("valid baseline: %d airports, err = %v", len(airports), err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*AirportConfig)
+		want   string
+	}{
+		{"latitude above range", func(a *AirportConfig) { a.Latitude = 200 }, "latitude 200 out of range"},
+		{"latitude below range", func(a *AirportConfig) { a.Latitude = -90.5 }, "latitude -90.5 out of range"},
+		{"longitude out of range", func(a *AirportConfig) { a.Longitude = 180.1 }, "longitude 180.1 out of range"},
+		{"negative radius", func(a *AirportConfig) { a.RadiusKm = -1 }, "radius_km must be positive"},
+		{"empty icao", func(a *AirportConfig) { a.ICAO = " " }, "airport 0: icao is required"},
+	} {
+		airport := valid
+		tc.modify(&airport)
+		_, err := parseAirportConfig(writeAirports(t, airport))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	duplicate := testAirport("kval", 41, -73)
+	if _, err := parseAirportConfig(writeAirports(t, valid, duplicate)); err == nil {
+		t.Error("duplicate ICAO codes accepted")
+	}
+}
+
+func TestAirportConfigValidationReportsEveryProblem(t *testing.T) {
+	first := testAirport("KONE", 200, -73)
+	second := testAirport("KTWO", 40, -181)
+	second.RadiusKm = 0
+	_, err := NewAirportTracker(writeAirports(t, first, second))
+	if err == nil {
+		t.Fatal("tracker started with an invalid config")
+	}
+	for _, want := range []string{"KONE: latitude", "KTWO: longitude", "KTWO: radius_km"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestInvalidTimezoneRejected(t *testing.T) {
+	airport := testAirport("KBAD", 40, -73)
+	airport.Timezone = "Mars/Olympus_Mons"
+	if _, err := parseAirportConfig(writeAirports(t, airport)); err == nil || !strings.Contains(err.Error(), "timezone") {
+		t.Fatalf("err = %v, want an invalid timezone error", err)
+	}
+}
+
+func TestConfigReloadSwapsAirports(t *testing.T) {
+	at := newTestTracker(t)
+	airportCodes := func() []string {
+		var airports []AirportConfig
+		decodeBody(t, call(at.handleListAirports, http.MethodGet, "/api/v1/airports", nil), &airports)
+		var codes []string
+		for _, airport := range airports {
+			codes = append(codes, airport.ICAO)
+		}
+		return codes
+	}
+
+	data, _ := json.Marshal([]AirportConfig{testAirport("KNEW", 41, -74), testAirport("KTWO", 42, -74)})
+	if err := os.WriteFile(at.configPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if rec := call(at.handleConfigReload, http.MethodPost, "/api/v1/config/reload", nil); rec.Code != http.StatusOK {
+		t.Fatalf("reload code = %d, body %s", rec.Code, rec.Body)
+	}
+	if codes := fmt.Sprint(airportCodes()); codes != "[KNEW KTWO]" {
+		t.Errorf("airports after reload = %s, want [KNEW KTWO]", codes)
+	}
+
+	if err := os.WriteFile(at.configPath, []byte(`[{"icao": "KBAD", "latitude": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if rec := call(at.handleConfigReload, http.MethodPost, "/api/v1/config/reload", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid reload code = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if codes := fmt.Sprint(airportCodes()); codes != "[KNEW KTWO]" {
+		t.Errorf("airports after failed reload = %s, want the previous config kept", codes)
+	}
+}
+
+func TestGetAirport(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	for _, code := range []string{"KBBB", "kbbb", " KbBb "} {
+		var airport AirportConfig
+		decodeBody(t, call(at.handleGetAirport, http.MethodGet, "/api/v1/airports/x", map[string]string{"code": code}), &airport)
+		if airport.ICAO != "KBBB" || airport.Latitude != 45 {
+			t.Errorf("%q: got %+v, want KBBB", code, airport)
+		}
+	}
+
+	rec := call(at.handleGetAirport, http.MethodGet, "/api/v1/airports/KNONE", map[string]string{"code": "KNONE"})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("absent code: %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if !strings.Contains(scrape(t, at), "airport_tracker_unknown_airport_requests_total 1") {
+		t.Error("absent code not counted as an unknown airport request")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// readAirportFile parses one airports.json file without validating it
+func readAirportFile(path string) ([]AirportConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	var airports []AirportConfig
+	if err := json.Unmarshal(data, &airports); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return airports, nil
+}
+
+// readAirportDir merges every *.json file in dir, in name order. An airport
+// defined identically in several files is kept once; differing definitions
+// of the same ICAO code in different files are an error. Duplicates within
+// one file are left to DUPLICATE_ICAO_MODE.
+func readAirportDir(dir string) ([]AirportConfig, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.json airport config files in %s", dir)
+	}
+	sort.Strings(paths)
+
+	type origin struct {
+		airport AirportConfig
+		file    string
+	}
+	defined := map[string]origin{}
+	var merged []AirportConfig
+	for _, path := range paths {
+		airports, err := readAirportFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file := filepath.Base(path)
+		for _, airport := range airports {
+			code := strings.ToUpper(strings.TrimSpace(airport.ICAO))
+			if code == "" {
+				merged = append(merged, airport) // reported by validation
+				continue
+			}
+			if prev, ok := defined[code]; ok && prev.file != file {
+				if !reflect.DeepEqual(prev.airport, airport) {
+					return nil, fmt.Errorf("airport %s is defined differently in %s and %s", airport.ICAO, prev.file, file)
+				}
+				continue
+			}
+			if _, ok := defined[code]; !ok {
+				defined[code] = origin{airport: airport, file: file}
+			}
+			merged = append(merged, airport)
+		}
+	}
+	return merged, nil
+}
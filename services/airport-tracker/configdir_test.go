@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeAirportDir writes one JSON config file per entry of files into a
+// temporary directory and returns it
+func writeAirportDir(t *testing.T, files map[string][]AirportConfig) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, airports := range files {
+		data, err := json.Marshal(airports)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestConfigDirectoryMergesFiles(t *testing.T) {
+	shared := testAirport("KJFK", 40.64, -73.78)
+	dir := writeAirportDir(t, map[string][]AirportConfig{
+		"east.json": {shared, testAirport("KBOS", 42.37, -71.01)},
+		"west.json": {testAirport("KLAX", 33.94, -118.41), shared}, // identical, kept once
+	})
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("not config"), 0o644)
+
+	at, err := NewAirportTracker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer at.Close()
+	var codes []string
+	for _, airport := range at.airportList() {
+		codes = append(codes, airport.ICAO)
+	}
+	if got := strings.Join(codes, " "); got != "KJFK KBOS KLAX" {
+		t.Errorf("airports = %s, want KJFK KBOS KLAX in file order", got)
+	}
+}
+
+func TestConfigDirectoryRejectsConflicts(t *testing.T) {
+	moved := testAirport("kjfk", 41, -73.78)
+	dir := writeAirportDir(t, map[string][]AirportConfig{
+		"east.json": {testAirport("KJFK", 40.64, -73.78)},
+		"west.json": {moved},
+	})
+	_, err := parseAirportConfig(dir)
+	if err == nil || !strings.Contains(err.Error(), "defined differently in east.json and west.json") {
+		t.Errorf("err = %v, want a conflict naming both files", err)
+	}
+
+	if _, err := parseAirportConfig(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no *.json airport config files") {
+		t.Errorf("empty directory: err = %v", err)
+	}
+}
+
+func TestConfigFileStillSupported(t *testing.T) {
+	airports, err := parseAirportConfig(writeAirports(t, testAirport("KONE", 40, -73)))
+	if err != nil || len(airports) != 1 || airports[0].ICAO != "KONE" {
+		t.Errorf("single file: %v, %v", airports, err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// corsPolicy holds the origins allowed to call the API from a browser.
+// "*" allows any origin; an empty policy allows none.
+type corsPolicy struct {
+	origins map[string]bool
+	any     bool
+}
+
+// corsPolicyFromEnv reads CORS_ALLOWED_ORIGINS, a comma-separated list
+func corsPolicyFromEnv() corsPolicy {
+	policy := corsPolicy{origins: map[string]bool{}}
+	for _, origin := range strings.Split(envString("CORS_ALLOWED_ORIGINS", ""), ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		switch origin {
+		case "":
+		case "*":
+			policy.any = true
+		default:
+			policy.origins[strings.ToLower(origin)] = true
+		}
+	}
+	return policy
+}
+
+func (p corsPolicy) allows(origin string) bool {
+	return origin != "" && (p.any || p.origins[strings.ToLower(origin)])
+}
+
+// middleware adds CORS headers for allowed origins and answers preflight
+// requests itself, since routes only accept their own methods
+func (p corsPolicy) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !p.allows(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin applies the policy to WebSocket upgrades. Same-origin
+// requests are always accepted.
+func (p corsPolicy) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || p.allows(origin) {
+		return true
+	}
+	return strings.EqualFold(strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://"), r.Host)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// corsRequest sends a request with origin through a policy read from
+// CORS_ALLOWED_ORIGINS, reporting whether the wrapped handler ran
+func corsRequest(t *testing.T, allowed, method, origin string, header http.Header) (*httptest.ResponseRecorder, bool) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", allowed)
+	reached := false
+	handler := corsPolicyFromEnv().middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	req := httptest.NewRequest(method, "/api/v1/flights/all", nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, reached
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	rec, reached := corsRequest(t, "https://dash.example.com/, https://ops.example.com", http.MethodGet, "https://DASH.example.com", nil)
+	if !reached {
+		t.Fatal("request from an allowed origin not served")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://DASH.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+	if rec.Header().Get("Vary") != "Origin" {
+		t.Errorf("Vary = %q, want Origin", rec.Header().Get("Vary"))
+	}
+
+	if rec, _ := corsRequest(t, "*", http.MethodGet, "https://anywhere.example", nil); rec.Header().Get("Access-Control-Allow-Origin") != "https://anywhere.example" {
+		t.Error("wildcard policy did not allow an arbitrary origin")
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	for _, allowed := range []string{"", "https://dash.example.com"} {
+		rec, reached := corsRequest(t, allowed, http.MethodGet, "https://evil.example", nil)
+		if !reached {
+			t.Errorf("%q: request not passed through", allowed)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%q: Access-Control-Allow-Origin = %q for a disallowed origin", allowed, got)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	preflight := http.Header{"Access-Control-Request-Method": {"GET"}, "Access-Control-Request-Headers": {"Content-Type"}}
+	rec, reached := corsRequest(t, "https://dash.example.com", http.MethodOptions, "https://dash.example.com", preflight)
+	if reached || rec.Code != http.StatusNoContent {
+		t.Fatalf("preflight code = %d, reached handler %v; want 204 answered by the middleware", rec.Code, reached)
+	}
+	for name, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://dash.example.com",
+		"Access-Control-Allow-Methods": "GET, OPTIONS",
+		"Access-Control-Allow-Headers": "Content-Type",
+	} {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// A disallowed preflight gets no CORS headers, so the browser blocks it
+	if rec, _ := corsRequest(t, "https://dash.example.com", http.MethodOptions, "https://evil.example", preflight); rec.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Error("preflight from a disallowed origin answered")
+	}
+}
+
+func TestWebSocketOriginCheck(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://dash.example.com")
+	policy := corsPolicyFromEnv()
+	for origin, want := range map[string]bool{
+		"":                         true,
+		"https://dash.example.com": true,
+		"http://tracker.local":     true, // same origin as the Host
+		"https://evil.example":     false,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "http://tracker.local/ws", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if got := policy.checkOrigin(req); got != want {
+			t.Errorf("origin %q: checkOrigin = %v, want %v", origin, got, want)
+		}
+	}
+}
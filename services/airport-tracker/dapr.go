@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultDaprHTTPPort   = 3500
+	defaultPubSubName     = "pubsub"
+	statusChangeQueueSize = 256
+	daprPublishTimeout    = 2 * time.Second
+
+	defaultPublishMaxAttempts = 4
+	defaultPublishBackoff     = 200 * time.Millisecond
+	maxPublishBackoff         = 5 * time.Second
+)
+
+// FlightStatusChange is published whenever a flight's status or airport
+// changes. OldStatus is empty the first time a flight is tracked.
+type FlightStatusChange struct {
+	ICAO24      string    `json:"icao24"`
+	Callsign    string    `json:"callsign"`
+	AirportCode string    `json:"airport_code"`
+	OldAirport  string    `json:"old_airport_code,omitempty"`
+	OldStatus   string    `json:"old_status,omitempty"`
+	NewStatus   string    `json:"new_status"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// statusChangePublisher publishes status changes to a Dapr topic through
+// the sidecar's HTTP API. Publish only enqueues; a single worker posts the
+// events, retrying failures with exponential backoff, so ingestion is never
+// held up. Events are dropped when the queue is full, and logged as dead
+// letters once their attempts are exhausted.
+type statusChangePublisher struct {
+	endpoint    string
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration // wait before the first retry; doubles per retry
+	events      chan FlightStatusChange
+	done        chan struct{}
+	stopped     chan struct{}
+
+	published atomic.Uint64
+	retried   atomic.Uint64
+	failed    atomic.Uint64 // events dead-lettered after every attempt failed
+	dropped   atomic.Uint64
+}
+
+// newStatusChangePublisherFromEnv returns a running publisher when
+// STATUS_CHANGE_TOPIC is set, and nil otherwise. DAPR_HTTP_PORT and
+// DAPR_PUBSUB_NAME select the sidecar and pub/sub component;
+// PUBLISH_MAX_ATTEMPTS and PUBLISH_RETRY_BACKOFF_MS tune retries.
+func newStatusChangePublisherFromEnv() *statusChangePublisher {
+	topic := envString("STATUS_CHANGE_TOPIC", "")
+	if topic == "" {
+		return nil
+	}
+	port := envInt("DAPR_HTTP_PORT", defaultDaprHTTPPort)
+	pubsub := envString("DAPR_PUBSUB_NAME", defaultPubSubName)
+	p := newStatusChangePublisher(
+		fmt.Sprintf("http://localhost:%d/v1.0/publish/%s/%s", port, url.PathEscape(pubsub), url.PathEscape(topic)),
+		envInt("PUBLISH_MAX_ATTEMPTS", defaultPublishMaxAttempts),
+		time.Duration(envInt("PUBLISH_RETRY_BACKOFF_MS", int(defaultPublishBackoff/time.Millisecond)))*time.Millisecond,
+	)
+	slog.Info("publishing status changes", "pubsub", pubsub, "topic", topic, "max_attempts", p.maxAttempts)
+	return p
+}
+
+// newStatusChangePublisher starts a publisher posting to endpoint
+func newStatusChangePublisher(endpoint string, maxAttempts int, backoff time.Duration) *statusChangePublisher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	p := &statusChangePublisher{
+		endpoint:    endpoint,
+		client:      &http.Client{Timeout: daprPublishTimeout},
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		events:      make(chan FlightStatusChange, statusChangeQueueSize),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues an event without blocking
+func (p *statusChangePublisher) Publish(event FlightStatusChange) {
+	select {
+	case p.events <- event:
+	default:
+		p.dropped.Add(1)
+		slog.Warn("status change queue full, dropping event", "icao24", event.ICAO24)
+	}
+}
+
+// Stop finishes sending queued events and stops the worker. Events still
+// waiting to be retried get no further attempts.
+func (p *statusChangePublisher) Stop() {
+	close(p.done)
+	<-p.stopped
+}
+
+func (p *statusChangePublisher) run() {
+	defer close(p.stopped)
+	for {
+		select {
+		case event := <-p.events:
+			p.send(event)
+		case <-p.done:
+			for {
+				select {
+				case event := <-p.events:
+					p.send(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send posts event, retrying until it is accepted, maxAttempts is reached
+// or the publisher is stopped
+func (p *statusChangePublisher) send(event FlightStatusChange) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		p.failed.Add(1)
+		slog.Warn("failed to encode status change", "icao24", event.ICAO24, "error", err)
+		return
+	}
+
+	backoff := p.backoff
+	attempt := 1
+	for ; ; attempt++ {
+		err = p.post(payload)
+		if err == nil {
+			p.published.Add(1)
+			return
+		}
+		if attempt >= p.maxAttempts || !p.wait(backoff) {
+			break
+		}
+		p.retried.Add(1)
+		slog.Debug("retrying status change", "icao24", event.ICAO24, "attempt", attempt, "error", err)
+		backoff = min(backoff*2, maxPublishBackoff)
+	}
+	p.failed.Add(1)
+	slog.Error("status change dead-lettered", "icao24", event.ICAO24, "attempts", attempt,
+		"error", err, "event", string(payload))
+}
+
+// post makes one publish call; any non-2xx response is an error
+func (p *statusChangePublisher) post(payload []byte) error {
+	resp, err := p.client.Post(p.endpoint, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New("Dapr rejected status change: " + resp.Status)
+	}
+	return nil
+}
+
+// wait sleeps for d, returning false if the publisher is stopped first
+func (p *statusChangePublisher) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+const (
+	flightUpdateTopic = "flight-update"
+	flightUpdateRoute = "/flight-update"
+)
+
+// daprSubscription is one entry of the GET /dapr/subscribe response
+type daprSubscription struct {
+	PubSubName string `json:"pubsubname"`
+	Topic      string `json:"topic"`
+	Route      string `json:"route"`
+}
+
+// daprSubscriptionsFromEnv returns the programmatic subscriptions to
+// advertise. They are off by default (DAPR_PROGRAMMATIC_SUBSCRIPTION) because
+// the declarative subscription in components/ already routes flight-update
+// here; SUBSCRIPTION_PUBSUB_NAME selects the pub/sub component.
+func daprSubscriptionsFromEnv() []daprSubscription {
+	if !envBool("DAPR_PROGRAMMATIC_SUBSCRIPTION", false) {
+		return []daprSubscription{}
+	}
+	return []daprSubscription{{
+		PubSubName: envString("SUBSCRIPTION_PUBSUB_NAME", defaultPubSubName),
+		Topic:      flightUpdateTopic,
+		Route:      flightUpdateRoute,
+	}}
+}
+
+// GET /dapr/subscribe - Topics the sidecar should deliver to this app
+func handleDaprSubscribe(subscriptions []daprSubscription) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(subscriptions)
+	}
+}
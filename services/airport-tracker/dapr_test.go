@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSidecar records the events published to it
+type fakeSidecar struct {
+	mu     sync.Mutex
+	paths  []string
+	events []FlightStatusChange
+}
+
+func (s *fakeSidecar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var event FlightStatusChange
+	json.NewDecoder(r.Body).Decode(&event)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths = append(s.paths, r.URL.Path)
+	s.events = append(s.events, event)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// waitFor returns the first n events once they have arrived
+func (s *fakeSidecar) waitFor(t *testing.T, n int) []FlightStatusChange {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		s.mu.Lock()
+		events := append([]FlightStatusChange(nil), s.events...)
+		s.mu.Unlock()
+		if len(events) >= n {
+			return events
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sidecar received %d events, want %d", len(events), n)
+		}
+	}
+}
+
+func TestStatusChangesPublishedOnlyOnTransitions(t *testing.T) {
+	sidecar := &fakeSidecar{}
+	server := httptest.NewServer(sidecar)
+	defer server.Close()
+	t.Setenv("STATUS_CHANGE_TOPIC", "flight-status")
+	t.Setenv("DAPR_HTTP_PORT", server.URL[len("http://127.0.0.1:"):])
+	at := newTestTracker(t)
+
+	level := testUpdate("abc123", 40.05, -73)
+	descending := testUpdate("abc123", 40.04, -73)
+	descending.BaroAltitude = ptr(1000.0)
+	descending.VerticalRate = ptr(-5.0)
+	track(t, at, level, level, descending, descending)
+
+	events := sidecar.waitFor(t, 2)
+	time.Sleep(50 * time.Millisecond) // let any spurious publish arrive
+	if events = sidecar.waitFor(t, 2); len(events) != 2 {
+		t.Fatalf("published %d events, want 2: %+v", len(events), events)
+	}
+	if e := events[0]; e.OldStatus != "" || e.NewStatus != StatusNearby || e.AirportCode != "KTST" {
+		t.Errorf("first event = %+v, want a new flight nearby KTST", e)
+	}
+	if e := events[1]; e.OldStatus != StatusNearby || e.NewStatus != StatusArriving || e.Timestamp.IsZero() {
+		t.Errorf("second event = %+v, want nearby to arriving", e)
+	}
+	if sidecar.paths[0] != "/v1.0/publish/pubsub/flight-status" {
+		t.Errorf("published to %s", sidecar.paths[0])
+	}
+}
+
+func TestStatusChangeCooldownSuppressesFlapping(t *testing.T) {
+	sidecar := &fakeSidecar{}
+	server := httptest.NewServer(sidecar)
+	defer server.Close()
+	t.Setenv("STATUS_CHANGE_TOPIC", "flight-status")
+	t.Setenv("DAPR_HTTP_PORT", server.URL[len("http://127.0.0.1:"):])
+	at := newTestTracker(t)
+	notifier := make(channelNotifier, 10)
+	at.notifier = notifier
+
+	level := testUpdate("abc123", 40.05, -73)
+	descending := testUpdate("abc123", 40.04, -73)
+	descending.BaroAltitude = ptr(1000.0)
+	descending.VerticalRate = ptr(-5.0)
+	track(t, at, level, descending, level, descending, level)
+
+	// Within the cooldown each status is published once, and the webhook's
+	// own de-duplication does not hold back the topic
+	sidecar.waitFor(t, 2)
+	time.Sleep(50 * time.Millisecond) // let any repeated publish arrive
+	if events := sidecar.waitFor(t, 2); len(events) != 2 {
+		t.Errorf("published %d events, want nearby and arriving once each: %+v", len(events), events)
+	}
+	notifier.received(t, 2)
+}
+
+func TestStatusChangePublishToleratesSidecarErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	p := newStatusChangePublisher(server.URL, 1, 0)
+
+	done := make(chan struct{})
+	go func() {
+		p.Publish(FlightStatusChange{ICAO24: "abc123", NewStatus: StatusNearby})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked")
+	}
+	p.Stop()
+	if p.failed.Load() != 1 || p.published.Load() != 0 {
+		t.Errorf("failed = %d, published = %d; want the event dead-lettered", p.failed.Load(), p.published.Load())
+	}
+}
+
+func TestDaprSubscribe(t *testing.T) {
+	for _, tc := range []struct {
+		programmatic, pubsub string
+		want                 string
+	}{
+		{"", "", `[]`},
+		{"true", "", `[{"pubsubname":"pubsub","topic":"flight-update","route":"/flight-update"}]`},
+		{"true", "flights-kafka", `[{"pubsubname":"flights-kafka","topic":"flight-update","route":"/flight-update"}]`},
+	} {
+		t.Setenv("DAPR_PROGRAMMATIC_SUBSCRIPTION", tc.programmatic)
+		t.Setenv("SUBSCRIPTION_PUBSUB_NAME", tc.pubsub)
+		rec := call(handleDaprSubscribe(daprSubscriptionsFromEnv()), http.MethodGet, "/dapr/subscribe", nil)
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		// Dapr expects an array of objects with exactly these keys
+		var got, want []map[string]string
+		decodeBody(t, rec, &got)
+		json.Unmarshal([]byte(tc.want), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DAPR_PROGRAMMATIC_SUBSCRIPTION=%q SUBSCRIPTION_PUBSUB_NAME=%q: %s, want %s", tc.programmatic, tc.pubsub, rec.Body, tc.want)
+		}
+	}
+}
+
+func TestStatusChangePublishRetriesWithBackoff(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		if len(attempts) <= 2 {
+			http.Error(w, "sidecar starting", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	p := newStatusChangePublisher(server.URL, 5, 20*time.Millisecond)
+	defer p.Stop()
+
+	p.Publish(FlightStatusChange{ICAO24: "abc123", NewStatus: StatusArriving})
+	for deadline := time.Now().Add(2 * time.Second); p.published.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("event never published")
+		}
+	}
+	if p.retried.Load() != 2 || p.failed.Load() != 0 {
+		t.Errorf("retried = %d, failed = %d; want 2 retries and no dead letter", p.retried.Load(), p.failed.Load())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 {
+		t.Fatalf("%d attempts, want 3", len(attempts))
+	}
+	// The wait doubles after each failure: 20ms, then 40ms
+	if first, second := attempts[1].Sub(attempts[0]), attempts[2].Sub(attempts[1]); first < 20*time.Millisecond || second < 40*time.Millisecond {
+		t.Errorf("retry gaps %v and %v, want at least 20ms and 40ms", first, second)
+	}
+}
+
+func TestStatusChangeDeadLetteredAfterMaxAttempts(t *testing.T) {
+	logs := captureLogs(t, "info")
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "no pubsub", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	t.Setenv("STATUS_CHANGE_TOPIC", "flight-status")
+	t.Setenv("DAPR_HTTP_PORT", server.URL[len("http://127.0.0.1:"):])
+	t.Setenv("PUBLISH_MAX_ATTEMPTS", "3")
+	t.Setenv("PUBLISH_RETRY_BACKOFF_MS", "1")
+	at := newTestTracker(t)
+
+	// Ingestion is not held up by the failing sidecar
+	start := time.Now()
+	track(t, at, testUpdate("abc123", 40.05, -73))
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("processing took %v", elapsed)
+	}
+	for deadline := time.Now().Add(2 * time.Second); at.statusChanges.failed.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("event never dead-lettered")
+		}
+	}
+	if n := hits.Load(); n != 3 {
+		t.Errorf("%d attempts, want PUBLISH_MAX_ATTEMPTS (3)", n)
+	}
+	if !strings.Contains(logs.String(), "status change dead-lettered") {
+		t.Error("dead letter not logged")
+	}
+	if !strings.Contains(scrape(t, at), "airport_tracker_status_change_publish_failures_total 1") {
+		t.Error("publish failure metric not incremented")
+	}
+}
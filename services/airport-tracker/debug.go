@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GET /api/v1/debug/state - Every locally stored flight by store key, with
+// the loaded airports, for troubleshooting. Unlike the list endpoints it is
+// unfiltered and unpaginated, and flights are returned exactly as stored.
+func (at *AirportTracker) handleDebugState(w http.ResponseWriter, r *http.Request) {
+	flights := map[string]TrackedFlight{}
+	for _, flight := range at.flights.Collect(nil) {
+		flights[at.flightKey(flight.ICAO24, flight.AirportCode)] = flight
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"generated_at":  at.now().UTC(),
+		"tracking_mode": at.trackingMode,
+		"flight_count":  len(flights),
+		"flights":       flights,
+		"airports":      at.airportList(),
+		"config_path":   at.configPath,
+	})
+}
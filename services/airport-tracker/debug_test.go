@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDebugStateDumpsFlightsAndAirports(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	track(t, at, testUpdate("aaa001", 40.05, -73), testUpdate("bbb001", 45.05, -73))
+
+	var dump struct {
+		FlightCount int                      `json:"flight_count"`
+		Flights     map[string]TrackedFlight `json:"flights"`
+		Airports    []AirportConfig          `json:"airports"`
+	}
+	decodeBody(t, call(at.handleDebugState, http.MethodGet, "/api/v1/debug/state", nil), &dump)
+
+	if dump.FlightCount != 2 || len(dump.Flights) != 2 {
+		t.Fatalf("flight_count = %d with %d flights, want 2", dump.FlightCount, len(dump.Flights))
+	}
+	for icao24, airport := range map[string]string{"aaa001": "KAAA", "bbb001": "KBBB"} {
+		flight, ok := dump.Flights[at.flightKey(icao24, airport)]
+		if !ok {
+			t.Errorf("%s missing from dump", icao24)
+			continue
+		}
+		if flight.AirportCode != airport || flight.Status == "" || flight.LastSeen.IsZero() || flight.EnteredAt == nil {
+			t.Errorf("%s dumped as %+v, want it at %s with status, last_seen and entered_at", icao24, flight, airport)
+		}
+	}
+	if len(dump.Airports) != 2 || dump.Airports[0].ICAO != "KAAA" || dump.Airports[1].ICAO != "KBBB" {
+		t.Errorf("airports = %+v, want KAAA and KBBB", dump.Airports)
+	}
+}
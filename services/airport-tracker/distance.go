@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Distance algorithms selectable with DISTANCE_METHOD, which also matches
+// updates against geofences on ingest, or ?distance= on the geofence-check
+// and flights-near utility endpoints
+const (
+	DistanceHaversine = "haversine"
+	DistanceVincenty  = "vincenty"
+	DistanceRhumb     = "rhumb"
+	DistanceEquirect  = "equirect"
+)
+
+// distanceFunc returns the distance between two points in kilometers
+type distanceFunc func(lat1, lon1, lat2, lon2 float64) float64
+
+var distanceFuncs = map[string]distanceFunc{
+	DistanceHaversine: haversineDistance,
+	DistanceVincenty:  vincentyDistance,
+	DistanceRhumb:     rhumbDistance,
+	DistanceEquirect:  equirectDistance,
+}
+
+func toRadians(deg float64) float64 { return deg * math.Pi / 180 }
+
+// vincentyDistance is the ellipsoidal distance on WGS-84 using Vincenty's
+// inverse formula. It falls back to haversine for nearly antipodal points
+// where the iteration does not converge.
+func vincentyDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	const (
+		a = 6378137.0
+		f = 1 / 298.257223563
+		b = a * (1 - f)
+	)
+	L := toRadians(lon2 - lon1)
+	U1 := math.Atan((1 - f) * math.Tan(toRadians(lat1)))
+	U2 := math.Atan((1 - f) * math.Tan(toRadians(lat2)))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cos2Alpha, cos2SigmaM float64
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0 // coincident points
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+		C := f / 16 * cos2Alpha * (4 + f*(4-3*cos2Alpha))
+		prev := lambda
+		lambda = L + (1-C)*f*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			uSq := cos2Alpha * (a*a - b*b) / (b * b)
+			A := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+			B := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+			deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+				B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+			return b * A * (sigma - deltaSigma) / 1000
+		}
+	}
+	return haversineDistance(lat1, lon1, lat2, lon2)
+}
+
+// rhumbDistance is the length of the constant-bearing (loxodrome) path on a
+// sphere, which is never shorter than the great-circle distance
+func rhumbDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	const R = 6371
+	phi1, phi2 := toRadians(lat1), toRadians(lat2)
+	dPhi := phi2 - phi1
+	dLambda := toRadians(lon2 - lon1)
+	if math.Abs(dLambda) > math.Pi {
+		if dLambda > 0 {
+			dLambda -= 2 * math.Pi
+		} else {
+			dLambda += 2 * math.Pi
+		}
+	}
+	dPsi := math.Log(math.Tan(math.Pi/4+phi2/2) / math.Tan(math.Pi/4+phi1/2))
+	q := math.Cos(phi1)
+	if math.Abs(dPsi) > 1e-12 {
+		q = dPhi / dPsi
+	}
+	return R * math.Hypot(dPhi, q*dLambda)
+}
+
+// equirectDistance approximates the great-circle distance on a plane
+// scaled by the cosine of the mean latitude. It skips haversine's
+// trigonometry and stays within about 0.1% of it at geofence ranges (tens
+// of kilometers) away from the poles.
+func equirectDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	const R = 6371
+	dLon := lon2 - lon1
+	if dLon > 180 {
+		dLon -= 360
+	} else if dLon < -180 {
+		dLon += 360
+	}
+	x := toRadians(dLon) * math.Cos(toRadians((lat1+lat2)/2))
+	y := toRadians(lat2 - lat1)
+	return R * math.Hypot(x, y)
+}
+
+// distanceMethodFromEnv reads DISTANCE_METHOD, defaulting to haversine
+func distanceMethodFromEnv() string {
+	method := strings.ToLower(envString("DISTANCE_METHOD", DistanceHaversine))
+	if _, ok := distanceFuncs[method]; !ok {
+		slog.Warn("unknown DISTANCE_METHOD, using haversine", "value", method)
+		return DistanceHaversine
+	}
+	return method
+}
+
+// distanceForRequest returns the algorithm named by ?distance=, or the
+// configured default
+func (at *AirportTracker) distanceForRequest(r *http.Request) (string, distanceFunc, error) {
+	method := strings.ToLower(r.URL.Query().Get("distance"))
+	if method == "" {
+		method = at.distanceMethod
+	}
+	fn, ok := distanceFuncs[method]
+	if !ok {
+		return "", nil, fmt.Errorf("invalid distance %q: expected haversine, vincenty, rhumb or equirect", method)
+	}
+	return method, fn, nil
+}
+
+// queryPoint reads a required ?lat=&lon= pair
+func queryPoint(r *http.Request) (lat, lon float64, err error) {
+	query := r.URL.Query()
+	lat, err = strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("invalid lat %q: expected -90 to 90", query.Get("lat"))
+	}
+	lon, err = strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("invalid lon %q: expected -180 to 180", query.Get("lon"))
+	}
+	return lat, lon, nil
+}
+
+// GeofenceCheckResult is one airport's verdict for a queried point
+type GeofenceCheckResult struct {
+	AirportCode string  `json:"airport_code"`
+	DistanceKm  float64 `json:"distance_km"`
+	Inside      bool    `json:"inside"`
+}
+
+// GET /api/v1/geofence-check?lat=&lon=&distance= - Which airport geofences
+// contain a point
+func (at *AirportTracker) handleGeofenceCheck(w http.ResponseWriter, r *http.Request) {
+	lat, lon, err := queryPoint(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method, distance, err := at.distanceForRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	airports := at.airportList()
+	results := make([]GeofenceCheckResult, 0, len(airports))
+	for _, airport := range airports {
+		d := distance(lat, lon, airport.Latitude, airport.Longitude)
+		results = append(results, GeofenceCheckResult{
+			AirportCode: airport.ICAO,
+			DistanceKm:  d,
+			Inside:      airport.contains(lat, lon, d),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"latitude":  lat,
+		"longitude": lon,
+		"distance":  method,
+		"airports":  results,
+	})
+}
+
+// FlightDistance is a tracked flight and its distance from a queried point.
+// The embedded distance_km stays the distance from the flight's airport.
+type FlightDistance struct {
+	TrackedFlight
+	DistanceFromPointKm float64 `json:"distance_from_point_km"`
+}
+
+// GET /api/v1/flights/near?lat=&lon=&radius_km=&distance= - Tracked flights
+// within radius_km of a point, nearest first
+func (at *AirportTracker) handleFlightsNear(w http.ResponseWriter, r *http.Request) {
+	lat, lon, err := queryPoint(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	radius, err := queryFloat(r, "radius_km", 50)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method, distance, err := at.distanceForRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	near := []FlightDistance{}
+	for _, flight := range at.uniqueAircraft(at.listFlights(nil)) {
+		if d := distance(lat, lon, flight.Latitude, flight.Longitude); d <= radius {
+			near = append(near, FlightDistance{TrackedFlight: flight, DistanceFromPointKm: d})
+		}
+	}
+	sort.Slice(near, func(i, j int) bool { return near[i].DistanceFromPointKm < near[j].DistanceFromPointKm })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"latitude":  lat,
+		"longitude": lon,
+		"radius_km": radius,
+		"distance":  method,
+		"flights":   near,
+		"count":     len(near),
+	})
+}
+
+// GET /api/v1/airports/nearest?lat=&lon= - The configured airport closest to
+// a point by great-circle distance
+func (at *AirportTracker) handleNearestAirport(w http.ResponseWriter, r *http.Request) {
+	lat, lon, err := queryPoint(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	airports := at.airportList()
+	var nearest *AirportConfig
+	var nearestKm float64
+	for i := range airports {
+		d := haversineDistance(lat, lon, airports[i].Latitude, airports[i].Longitude)
+		if nearest == nil || d < nearestKm {
+			nearest, nearestKm = &airports[i], d
+		}
+	}
+	if nearest == nil {
+		http.Error(w, "No airports configured", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"latitude":    lat,
+		"longitude":   lon,
+		"airport":     nearest,
+		"distance_km": nearestKm,
+		"inside":      nearest.contains(lat, lon, nearestKm),
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestEquirectWithinToleranceOfHaversine(t *testing.T) {
+	pairs := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+	}{
+		{"equator east", 0, 0, 0, 0.4},
+		{"mid-latitude diagonal", 40.64, -73.78, 40.90, -73.50},
+		{"southern north-south", -33.95, 151.18, -34.35, 151.18},
+		{"high latitude", 64.13, -21.94, 64.30, -21.30},
+		{"antimeridian", -17.75, 179.9, -17.70, -179.8},
+		{"short hop", 51.47, -0.45, 51.48, -0.44},
+	}
+	for _, p := range pairs {
+		exact := haversineDistance(p.lat1, p.lon1, p.lat2, p.lon2)
+		approx := equirectDistance(p.lat1, p.lon1, p.lat2, p.lon2)
+		if exact > 50 {
+			t.Fatalf("%s: %.1f km is outside the geofence range under test", p.name, exact)
+		}
+		if rel := math.Abs(approx-exact) / exact; rel > 0.001 {
+			t.Errorf("%s: equirect %.4f km vs haversine %.4f km, error %.4f%%", p.name, approx, exact, rel*100)
+		}
+	}
+}
+
+func TestDistanceMethodSelectsGeofenceDistance(t *testing.T) {
+	t.Setenv("DISTANCE_METHOD", "EQUIRECT")
+	at := newTestTracker(t)
+	if at.distanceMethod != DistanceEquirect {
+		t.Fatalf("distanceMethod = %q, want %q", at.distanceMethod, DistanceEquirect)
+	}
+	if got, want := at.geofenceDistance(40, -73, 40.2, -73.1), equirectDistance(40, -73, 40.2, -73.1); got != want {
+		t.Errorf("geofenceDistance = %v, want equirect's %v", got, want)
+	}
+}
+
+var distanceSink float64
+
+func BenchmarkGeofenceDistance(b *testing.B) {
+	for _, method := range []string{DistanceHaversine, DistanceEquirect} {
+		fn := distanceFuncs[method]
+		b.Run(method, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				distanceSink = fn(40.64, -73.78, 40.90+float64(i%8)*0.01, -73.50)
+			}
+		})
+	}
+}
+
+func TestDistanceMethodsRelativeResults(t *testing.T) {
+	// JFK to Heathrow: the great circle bows north, so the constant-bearing
+	// rhumb line is longer; the ellipsoid changes the result only slightly
+	jfk, lhr := [2]float64{40.6413, -73.7781}, [2]float64{51.4700, -0.4543}
+	haversine := haversineDistance(jfk[0], jfk[1], lhr[0], lhr[1])
+	vincenty := vincentyDistance(jfk[0], jfk[1], lhr[0], lhr[1])
+	rhumb := rhumbDistance(jfk[0], jfk[1], lhr[0], lhr[1])
+	if math.Abs(haversine-5540) > 5 {
+		t.Errorf("haversine = %.1f km, want about 5540", haversine)
+	}
+	if math.Abs(vincenty-5555) > 1 {
+		t.Errorf("vincenty = %.1f km, want the WGS-84 geodesic of about 5555", vincenty)
+	}
+	if rel := math.Abs(vincenty-haversine) / haversine; rel > 0.005 {
+		t.Errorf("vincenty = %.1f km, want within 0.5%% of haversine %.1f km", vincenty, haversine)
+	}
+	if rhumb < haversine*1.01 {
+		t.Errorf("rhumb = %.1f km, want clearly longer than haversine %.1f km", rhumb, haversine)
+	}
+
+	// Along a meridian the rhumb line is the great circle
+	if a, b := haversineDistance(40, -73, 45, -73), rhumbDistance(40, -73, 45, -73); math.Abs(a-b) > 1e-6 {
+		t.Errorf("meridian: rhumb %.6f km, haversine %.6f km", b, a)
+	}
+}
+
+func TestDistanceSelectedPerRequest(t *testing.T) {
+	at := newTestTracker(t, testAirport("EGLL", 51.4700, -0.4543))
+	distances := map[string]float64{}
+	for _, method := range []string{"", "haversine", "VINCENTY", "rhumb"} {
+		rec := call(at.handleGeofenceCheck, http.MethodGet, "/api/v1/geofence-check?lat=40.6413&lon=-73.7781&distance="+method, nil)
+		var body struct {
+			Distance string                `json:"distance"`
+			Airports []GeofenceCheckResult `json:"airports"`
+		}
+		decodeBody(t, rec, &body)
+		distances[body.Distance] = body.Airports[0].DistanceKm
+	}
+	if len(distances) != 3 {
+		t.Fatalf("methods used = %v, want haversine (the default), vincenty and rhumb", distances)
+	}
+	if distances[DistanceHaversine] != haversineDistance(40.6413, -73.7781, 51.4700, -0.4543) ||
+		distances[DistanceRhumb] <= distances[DistanceHaversine] || distances[DistanceVincenty] == distances[DistanceHaversine] {
+		t.Errorf("distances = %v", distances)
+	}
+	if rec := call(at.handleGeofenceCheck, http.MethodGet, "/api/v1/geofence-check?lat=40&lon=-73&distance=manhattan", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown distance code = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestNearestAirport(t *testing.T) {
+	at := newTestTracker(t,
+		testAirport("KJFK", 40.6413, -73.7781),
+		testAirport("KBOS", 42.3656, -71.0096),
+		testAirport("KORD", 41.9742, -87.9073),
+	)
+	for _, tc := range []struct {
+		lat, lon float64
+		want     string
+		inside   bool
+	}{
+		{42.30, -71.10, "KBOS", true},
+		{41.50, -87.00, "KORD", false}, // about 90 km out
+		{40.70, -73.90, "KJFK", true},
+	} {
+		rec := call(at.handleNearestAirport, http.MethodGet, fmt.Sprintf("/api/v1/airports/nearest?lat=%v&lon=%v", tc.lat, tc.lon), nil)
+		var body struct {
+			Airport    AirportConfig `json:"airport"`
+			DistanceKm float64       `json:"distance_km"`
+			Inside     bool          `json:"inside"`
+		}
+		decodeBody(t, rec, &body)
+		want := haversineDistance(tc.lat, tc.lon, body.Airport.Latitude, body.Airport.Longitude)
+		if body.Airport.ICAO != tc.want || body.DistanceKm != want || body.Inside != tc.inside {
+			t.Errorf("(%v, %v): %s at %.1f km inside=%v, want %s inside=%v", tc.lat, tc.lon, body.Airport.ICAO, body.DistanceKm, body.Inside, tc.want, tc.inside)
+		}
+	}
+
+	for _, query := range []string{"", "?lat=40", "?lat=91&lon=0", "?lat=40&lon=-181", "?lat=north&lon=0"} {
+		if rec := call(at.handleNearestAirport, http.MethodGet, "/api/v1/airports/nearest"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: code = %d, want 400", query, rec.Code)
+		}
+	}
+
+	at.airports = nil
+	if rec := call(at.handleNearestAirport, http.MethodGet, "/api/v1/airports/nearest?lat=40&lon=-73", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("no airports: code = %d, want 404", rec.Code)
+	}
+}
+
+func TestFlightsNearReportsBothDistances(t *testing.T) {
+	at := newTestTracker(t)
+	track(t, at, testUpdate("abc123", 40.05, -73), testUpdate("def456", 40.2, -73))
+
+	var body struct {
+		Flights []map[string]interface{} `json:"flights"`
+		Count   int                      `json:"count"`
+	}
+	decodeBody(t, call(at.handleFlightsNear, http.MethodGet, "/api/v1/flights/near?lat=40.06&lon=-73&radius_km=5&distance=haversine", nil), &body)
+	if body.Count != 1 || len(body.Flights) != 1 {
+		t.Fatalf("near flights = %v, want abc123 only", body.Flights)
+	}
+	flight := body.Flights[0]
+	fromAirport, _ := flight["distance_km"].(float64)
+	fromPoint, _ := flight["distance_from_point_km"].(float64)
+	if math.Abs(fromAirport-haversineDistance(40.05, -73, 40, -73)) > 1e-6 {
+		t.Errorf("distance_km = %v, want the distance from KTST", flight["distance_km"])
+	}
+	if math.Abs(fromPoint-haversineDistance(40.06, -73, 40.05, -73)) > 1e-6 {
+		t.Errorf("distance_from_point_km = %v, want the distance from the queried point", flight["distance_from_point_km"])
+	}
+}
+
+func TestFlightsNearListAircraftOnceInAllMode(t *testing.T) {
+	at := overlappingTracker(t)
+	track(t, at, testUpdate("abc123", 40.05, -73))
+
+	var body struct {
+		Flights []FlightDistance `json:"flights"`
+		Count   int              `json:"count"`
+	}
+	decodeBody(t, call(at.handleFlightsNear, http.MethodGet, "/api/v1/flights/near?lat=40.05&lon=-73&radius_km=5", nil), &body)
+	if body.Count != 1 || len(body.Flights) != 1 || body.Flights[0].AirportCode != "KAAA" {
+		t.Errorf("near flights = %+v, want abc123 once at the nearest airport KAAA", body.Flights)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDwellTimeAcrossUpdatesAndReentry(t *testing.T) {
+	at := newTestTracker(t)
+	now := time.Unix(1_700_000_000, 0)
+	at.clock = func() time.Time { return now }
+	process := func(lat float64) {
+		t.Helper()
+		update := testUpdate("abc123", lat, -73)
+		update.LastContact, update.TimePosition = now.Unix(), now.Unix()
+		if _, err := at.processFlightUpdate(context.Background(), update); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	process(40.1)
+	entered := now
+	now = now.Add(90 * time.Second)
+	process(40.05)
+	flight, _ := at.flights.Get("abc123")
+	if flight.EnteredAt == nil || !flight.EnteredAt.Equal(entered) {
+		t.Fatalf("entered_at = %v, want %v", flight.EnteredAt, entered)
+	}
+	if flight.DwellSeconds != 90 {
+		t.Errorf("dwell_seconds = %v, want 90", flight.DwellSeconds)
+	}
+
+	// Leaving every geofence clears the timer
+	now = now.Add(30 * time.Second)
+	process(39)
+	flight, _ = at.flights.Get("abc123")
+	if flight.EnteredAt != nil || flight.DwellSeconds != 0 {
+		t.Errorf("after leaving: entered_at = %v, dwell_seconds = %v, want both cleared", flight.EnteredAt, flight.DwellSeconds)
+	}
+
+	// Re-entering starts a fresh timer
+	now = now.Add(time.Minute)
+	process(40.05)
+	flight, _ = at.flights.Get("abc123")
+	if flight.EnteredAt == nil || !flight.EnteredAt.Equal(now) || flight.DwellSeconds != 0 {
+		t.Errorf("after re-entry: entered_at = %v, dwell_seconds = %v, want %v and 0", flight.EnteredAt, flight.DwellSeconds, now)
+	}
+}
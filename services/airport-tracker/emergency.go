@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Emergency types reported for the reserved squawk codes
+const (
+	EmergencyHijack       = "hijack"
+	EmergencyRadioFailure = "radio_failure"
+	EmergencyGeneral      = "general_emergency"
+)
+
+var emergencySquawks = map[string]string{
+	"7500": EmergencyHijack,
+	"7600": EmergencyRadioFailure,
+	"7700": EmergencyGeneral,
+}
+
+// emergencyType returns the emergency signalled by a squawk code, if any
+func emergencyType(squawk string) (string, bool) {
+	kind, ok := emergencySquawks[strings.TrimSpace(squawk)]
+	return kind, ok
+}
+
+// GET /api/v1/flights/emergencies - Get flights currently squawking an emergency code
+func (at *AirportTracker) handleEmergencies(w http.ResponseWriter, r *http.Request) {
+	opts, err := at.parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	emergencies := at.uniqueAircraft(at.listFlights(func(flight *TrackedFlight) bool {
+		return flight.Emergency
+	}))
+
+	emergencies, truncation := capFlights(emergencies, opts.limit)
+	at.decorateFlights(emergencies, opts)
+
+	response := map[string]interface{}{
+		"flights": emergencies,
+		"count":   len(emergencies),
+		"units":   opts.units,
+	}
+	truncation.annotate(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
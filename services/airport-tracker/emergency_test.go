@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEmergencySquawks(t *testing.T) {
+	at := newTestTracker(t)
+	logs := captureLogs(t, "error")
+	for _, tc := range []struct {
+		icao24, squawk, kind string
+	}{
+		{"aaa001", "7500", EmergencyHijack},
+		{"aaa002", "7600", EmergencyRadioFailure},
+		{"aaa003", " 7700 ", EmergencyGeneral},
+		{"aaa004", "1200", ""},
+	} {
+		update := testUpdate(tc.icao24, 40.05, -73)
+		update.Squawk = tc.squawk
+		track(t, at, update)
+		flight, _ := at.flights.Get(tc.icao24)
+		if flight.Emergency != (tc.kind != "") || flight.EmergencyType != tc.kind {
+			t.Errorf("squawk %q: emergency %v %q, want %q", tc.squawk, flight.Emergency, flight.EmergencyType, tc.kind)
+		}
+	}
+	if n := strings.Count(logs.String(), `"msg":"emergency squawk"`); n != 3 {
+		t.Errorf("logged %d emergency squawks at error level, want 3", n)
+	}
+
+	var body struct {
+		Flights []TrackedFlight `json:"flights"`
+		Count   int             `json:"count"`
+	}
+	decodeBody(t, call(at.handleEmergencies, http.MethodGet, "/api/v1/flights/emergencies", nil), &body)
+	if body.Count != 3 {
+		t.Errorf("emergencies endpoint returned %d flights, want 3", body.Count)
+	}
+	for _, flight := range body.Flights {
+		if flight.ICAO24 == "aaa004" {
+			t.Error("normal squawk listed as an emergency")
+		}
+	}
+}
+
+func TestEmergenciesListAircraftOnceInAllMode(t *testing.T) {
+	at := overlappingTracker(t)
+	update := testUpdate("abc123", 40.05, -73)
+	update.Squawk = "7700"
+	track(t, at, update)
+
+	var body struct {
+		Flights []TrackedFlight `json:"flights"`
+		Count   int             `json:"count"`
+	}
+	decodeBody(t, call(at.handleEmergencies, http.MethodGet, "/api/v1/flights/emergencies", nil), &body)
+	if body.Count != 1 || len(body.Flights) != 1 || body.Flights[0].AirportCode != "KAAA" {
+		t.Errorf("emergencies = %+v, want abc123 once at the nearest airport KAAA", body.Flights)
+	}
+}
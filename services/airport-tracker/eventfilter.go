@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// eventFilter holds the CloudEvent attributes /flight-update expects, so a
+// message routed to the wrong subscription is refused rather than tracked.
+// Empty fields are not checked; with none set every event is accepted.
+type eventFilter struct {
+	eventType  string
+	pubsubName string
+	topic      string
+}
+
+// eventFilterFromEnv reads EXPECTED_EVENT_TYPE, EXPECTED_PUBSUB_NAME and
+// EXPECTED_TOPIC. All are unset by default.
+func eventFilterFromEnv() eventFilter {
+	return eventFilter{
+		eventType:  envString("EXPECTED_EVENT_TYPE", ""),
+		pubsubName: envString("EXPECTED_PUBSUB_NAME", ""),
+		topic:      envString("EXPECTED_TOPIC", ""),
+	}
+}
+
+func (f eventFilter) enabled() bool {
+	return f.eventType != "" || f.pubsubName != "" || f.topic != ""
+}
+
+// mismatch explains why body is not an expected CloudEvent, or returns ""
+// when it is or the filter is disabled. A body that is not a CloudEvent at
+// all carries none of the attributes and so fails any enabled check.
+func (f eventFilter) mismatch(body []byte) string {
+	if !f.enabled() {
+		return ""
+	}
+	var event CloudEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return "" // left for extractFlightUpdates to report
+	}
+	for _, check := range []struct{ name, want, got string }{
+		{"type", f.eventType, event.Type},
+		{"pubsubname", f.pubsubName, event.PubSubName},
+		{"topic", f.topic, event.Topic},
+	} {
+		if check.want != "" && check.got != check.want {
+			return fmt.Sprintf("unexpected CloudEvent %s %q: expected %q", check.name, check.got, check.want)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flightEvent is a CloudEvent carrying one trackable flight
+func flightEvent(eventType, pubsubName, topic string) string {
+	now := time.Now().Unix()
+	return fmt.Sprintf(`{"specversion":"1.0","id":"1","source":"feeder","type":%q,"pubsubname":%q,"topic":%q,`+
+		`"data":{"icao24":"abc123","latitude":40.05,"longitude":-73,"time_position":%d,"last_contact":%d}}`,
+		eventType, pubsubName, topic, now, now)
+}
+
+func postFlightEvent(at *AirportTracker, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	at.handleFlightUpdate(rec, httptest.NewRequest(http.MethodPost, flightUpdateRoute, strings.NewReader(body)))
+	return rec
+}
+
+func TestEventFilterDisabledByDefault(t *testing.T) {
+	at := newTestTracker(t)
+	if rec := postFlightEvent(at, flightEvent("anything", "other", "elsewhere")); rec.Code != http.StatusOK {
+		t.Fatalf("code = %d, want 200 without a filter", rec.Code)
+	}
+	if _, ok := at.flights.Get("abc123"); !ok {
+		t.Error("flight not tracked")
+	}
+}
+
+func TestEventFilterMatchesTypeAndTopic(t *testing.T) {
+	t.Setenv("EXPECTED_EVENT_TYPE", "com.opensky.flight.update")
+	t.Setenv("EXPECTED_TOPIC", "flights")
+	for _, tc := range []struct {
+		name, eventType, topic string
+		code                   int
+		reason                 string
+	}{
+		{"matching", "com.opensky.flight.update", "flights", http.StatusOK, ""},
+		{"wrong type", "com.example.weather", "flights", http.StatusBadRequest, `unexpected CloudEvent type "com.example.weather"`},
+		{"wrong topic", "com.opensky.flight.update", "weather", http.StatusBadRequest, `unexpected CloudEvent topic "weather"`},
+	} {
+		at := newTestTracker(t)
+		rec := postFlightEvent(at, flightEvent(tc.eventType, "pubsub", tc.topic))
+		ack := decodeAck(t, rec)
+		if rec.Code != tc.code || !strings.Contains(ack["reason"], tc.reason) {
+			t.Errorf("%s: %d %v, want %d %q", tc.name, rec.Code, ack, tc.code, tc.reason)
+		}
+		if _, tracked := at.flights.Get("abc123"); tracked != (tc.code == http.StatusOK) {
+			t.Errorf("%s: tracked = %v", tc.name, tracked)
+		}
+		if tc.code != http.StatusOK && ack["status"] != DaprDrop {
+			t.Errorf("%s: status %q, want DROP so Dapr does not redeliver", tc.name, ack["status"])
+		}
+	}
+
+	// A bare flight carries none of the attributes
+	at := newTestTracker(t)
+	if rec := postFlightEvent(at, `{"icao24":"abc123","latitude":40.05,"longitude":-73}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("bare flight: code = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSSEKeepAlive = 15 * time.Second
+
+// statusEventHub fans status changes out to Server-Sent Events clients. Like
+// flightHub, Publish never blocks and slow clients are disconnected.
+type statusEventHub struct {
+	mu      sync.Mutex
+	clients map[*statusEventClient]struct{}
+}
+
+// statusEventClient is one subscriber; airport filters changes when non-empty
+type statusEventClient struct {
+	send    chan FlightStatusChange
+	airport string
+}
+
+func newStatusEventHub() *statusEventHub {
+	return &statusEventHub{clients: make(map[*statusEventClient]struct{})}
+}
+
+func (h *statusEventHub) subscribe(airport string) *statusEventClient {
+	client := &statusEventClient{send: make(chan FlightStatusChange, streamClientBuffer), airport: airport}
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+	return client
+}
+
+// unsubscribe removes client and closes its channel; it is safe to call
+// more than once
+func (h *statusEventHub) unsubscribe(client *statusEventClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+	}
+}
+
+// Publish queues change for every interested client, dropping slow ones.
+// A client filtering on an airport sees flights both entering and leaving it.
+func (h *statusEventHub) Publish(change FlightStatusChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if client.airport != "" && !strings.EqualFold(client.airport, change.AirportCode) &&
+			!strings.EqualFold(client.airport, change.OldAirport) {
+			continue
+		}
+		select {
+		case client.send <- change:
+		default:
+			slog.Warn("disconnecting slow event client", "airport", client.airport)
+			delete(h.clients, client)
+			close(client.send)
+		}
+	}
+}
+
+// GET /api/v1/flights/events?airport= - Server-Sent Events feed of flight
+// status changes, with a comment line every SSE_KEEPALIVE_SECONDS so
+// proxies keep the connection open
+func (at *AirportTracker) handleStatusEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	client := at.events.subscribe(r.URL.Query().Get("airport"))
+	defer at.events.unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(at.sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case change, ok := <-client.send:
+			if !ok {
+				return // dropped as too slow
+			}
+			data, err := json.Marshal(change)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: status_change\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusEventsStreamTransitions(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	at.sseKeepAlive = 10 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(at.handleStatusEvents))
+	defer server.Close()
+
+	// The handler subscribes before writing the headers
+	resp, err := http.Get(server.URL + "?airport=kaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", cc)
+	}
+
+	descending := testUpdate("aaa001", 40.05, -73)
+	descending.BaroAltitude, descending.VerticalRate = ptr(1000.0), ptr(-5.0)
+	track(t, at, testUpdate("bbb001", 45.05, -73), testUpdate("aaa001", 40.05, -73), descending)
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	var changes []FlightStatusChange
+	keepAlive := false
+	timeout := time.After(2 * time.Second)
+	for len(changes) < 2 || !keepAlive {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream ended")
+			}
+			switch {
+			case line == ": keep-alive":
+				keepAlive = true
+			case strings.HasPrefix(line, "data: "):
+				var change FlightStatusChange
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &change); err != nil {
+					t.Fatalf("event data %q: %v", line, err)
+				}
+				changes = append(changes, change)
+			}
+		case <-timeout:
+			t.Fatalf("after 2s: %d changes, keep-alive %v", len(changes), keepAlive)
+		}
+	}
+
+	// bbb001 at KBBB is filtered out; aaa001 enters, then starts arriving
+	for i, want := range [][2]string{{"", StatusNearby}, {StatusNearby, StatusArriving}} {
+		c := changes[i]
+		if c.ICAO24 != "aaa001" || c.AirportCode != "KAAA" || c.OldStatus != want[0] || c.NewStatus != want[1] {
+			t.Errorf("change %d = %+v, want aaa001 at KAAA from %q to %s", i, c, want[0], want[1])
+		}
+	}
+}
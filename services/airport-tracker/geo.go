@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// longitudeDelta returns the absolute longitude difference in degrees,
+// wrapped so points either side of the antimeridian (179.9 and -179.9) are
+// 0.2° apart rather than 359.8°.
+func longitudeDelta(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+	if d > 180 {
+		d = 360 - d
+	}
+	return d
+}
+
+// kmPerDegreeHaversine is the length of a degree of latitude on the sphere
+// haversineDistance uses (R = 6371 km)
+const kmPerDegreeHaversine = 6371 * math.Pi / 180
+
+// radiusBoundsMargin widens the prefilter box so distance methods that
+// differ from haversine by under 1% (vincenty, equirect) are covered too
+const radiusBoundsMargin = 1.01
+
+// withinRadiusBounds is a cheap prefilter for a circular geofence: it
+// rejects points outside the radius's lat/lon bounding box before the more
+// expensive haversine check. It is conservative, never rejecting a point
+// that is actually inside the radius, including across the antimeridian
+// and near the poles.
+func withinRadiusBounds(lat, lon, centerLat, centerLon, radiusKm float64) bool {
+	latSpan := radiusKm * radiusBoundsMargin / kmPerDegreeHaversine
+	if math.Abs(lat-centerLat) > latSpan {
+		return false
+	}
+	// Use the latitude closest to the pole within the box, where a degree of
+	// longitude is shortest, so the longitude span is never underestimated
+	poleward := math.Min(math.Abs(centerLat)+latSpan, 90)
+	cos := math.Cos(poleward * math.Pi / 180)
+	if cos <= 0 {
+		return true
+	}
+	lonSpan := latSpan / cos
+	if lonSpan >= 180 {
+		return true
+	}
+	return longitudeDelta(lon, centerLon) <= lonSpan
+}
+
+// boundingBox is a lat/lon rectangle. When MinLon > MaxLon the box crosses
+// the antimeridian, e.g. 170,-20,-170,-10 spans Fiji from 170°E to 170°W.
+type boundingBox struct {
+	MinLon, MinLat, MaxLon, MaxLat float64
+}
+
+// parseBoundingBox parses "minLon,minLat,maxLon,maxLat"
+func parseBoundingBox(raw string) (boundingBox, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return boundingBox{}, fmt.Errorf("invalid bbox %q: expected minLon,minLat,maxLon,maxLat", raw)
+	}
+	var v [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return boundingBox{}, fmt.Errorf("invalid bbox %q: %v", raw, err)
+		}
+		v[i] = f
+	}
+	box := boundingBox{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if box.MinLat > box.MaxLat || box.MinLat < -90 || box.MaxLat > 90 ||
+		box.MinLon < -180 || box.MinLon > 180 || box.MaxLon < -180 || box.MaxLon > 180 {
+		return boundingBox{}, fmt.Errorf("invalid bbox %q: coordinates out of range", raw)
+	}
+	return box, nil
+}
+
+// Contains reports whether the point lies in the box, handling boxes that
+// cross the antimeridian
+func (b boundingBox) Contains(lat, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return lon >= b.MinLon && lon <= b.MaxLon
+	}
+	return lon >= b.MinLon || lon <= b.MaxLon
+}
+
+// initialBearing returns the initial great-circle bearing from the first
+// point to the second in degrees clockwise from true north, in [0, 360)
+func initialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := toRadians(lat1), toRadians(lat2)
+	dLambda := toRadians(lon2 - lon1)
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+const (
+	// defaultHeadingToleranceDeg is how far a track may deviate from the
+	// bearing to (or away from) an airport and still count as inbound (or
+	// outbound); HEADING_TOLERANCE_DEG overrides it and 0 disables the check
+	defaultHeadingToleranceDeg = 45.0
+
+	// headingMinDistanceKm is the distance within which the bearing to the
+	// airport is too sensitive to position noise to judge a heading
+	headingMinDistanceKm = 1.0
+)
+
+// angularDifference returns the smallest angle between two bearings, in
+// degrees in [0, 180]
+func angularDifference(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+	if d > 180 {
+		d = 360 - d
+	}
+	return d
+}
+
+// refineStatusByHeading corrects a vertical-rate based status using the
+// track relative to the airport. Below the departure threshold, a flight
+// tracking away from the airport is departing unless it is clearly
+// descending; below the arrival threshold, one tracking toward it is
+// arriving unless it is clearly climbing. "Clearly" means beyond
+// slightRateMS. Flights on the ground, very close to the reference point or
+// missing a track, vertical rate or altitude keep their status.
+func refineStatusByHeading(status string, update FlightUpdate, altitude float64, hasAltitude bool,
+	airport AirportConfig, distanceKm, toleranceDeg, slightRateMS float64) string {
+	if toleranceDeg <= 0 || update.OnGround || !hasAltitude || update.TrueTrack == nil ||
+		update.VerticalRate == nil || distanceKm < headingMinDistanceKm {
+		return status
+	}
+	toleranceDeg = math.Min(toleranceDeg, 90)
+	rate := *update.VerticalRate
+	offset := angularDifference(*update.TrueTrack,
+		initialBearing(update.Latitude, update.Longitude, airport.Latitude, airport.Longitude))
+	switch {
+	case offset >= 180-toleranceDeg && rate >= -slightRateMS && altitude < airport.DepartureThresholdM:
+		return StatusDeparting
+	case offset <= toleranceDeg && rate <= slightRateMS && altitude < airport.ArrivalThresholdM:
+		return StatusArriving
+	}
+	return status
+}
+
+// destinationPoint returns the point reached by travelling distanceKm along
+// a great circle from lat, lon with the given initial bearing in degrees
+func destinationPoint(lat, lon, bearingDeg, distanceKm float64) (float64, float64) {
+	const R = 6371 // Earth radius in km
+	delta := distanceKm / R
+	theta := toRadians(bearingDeg)
+	phi1, lambda1 := toRadians(lat), toRadians(lon)
+
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1),
+		math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2))
+
+	lon2 := math.Mod(lambda2*180/math.Pi+540, 360) - 180
+	return phi2 * 180 / math.Pi, lon2
+}
+
+// greatCircleMidpoint returns the point halfway between two points along
+// the great circle joining them
+func greatCircleMidpoint(lat1, lon1, lat2, lon2 float64) (float64, float64) {
+	phi1, phi2 := toRadians(lat1), toRadians(lat2)
+	lambda1 := toRadians(lon1)
+	dLambda := toRadians(lon2 - lon1)
+
+	bx := math.Cos(phi2) * math.Cos(dLambda)
+	by := math.Cos(phi2) * math.Sin(dLambda)
+	phi := math.Atan2(math.Sin(phi1)+math.Sin(phi2), math.Sqrt((math.Cos(phi1)+bx)*(math.Cos(phi1)+bx)+by*by))
+	lambda := lambda1 + math.Atan2(by, math.Cos(phi1)+bx)
+
+	return phi * 180 / math.Pi, math.Mod(lambda*180/math.Pi+540, 360) - 180
+}
+
+// minClosingSpeedMS is the closing speed below which a flight is treated as
+// not approaching, avoiding huge ETAs for tangential passes
+const minClosingSpeedMS = 1.0
+
+// estimateETA returns the seconds until an aircraft at lat, lon reaches the
+// airport at distanceKm, projecting its ground speed along its track onto
+// the direction of the airport. It is nil when velocity or track is missing
+// or the aircraft is not closing on the airport.
+func estimateETA(update FlightUpdate, airport AirportConfig, distanceKm float64) *float64 {
+	if update.Velocity == nil || update.TrueTrack == nil {
+		return nil
+	}
+	toAirport := initialBearing(update.Latitude, update.Longitude, airport.Latitude, airport.Longitude)
+	closing := *update.Velocity * math.Cos(toRadians(*update.TrueTrack-toAirport))
+	if closing < minClosingSpeedMS {
+		return nil
+	}
+	eta := distanceKm * 1000 / closing
+	return &eta
+}
+
+// positionProblem explains why an update has no usable position fix, or
+// returns "" when it does. Feeds report aircraft without a fix with a zero
+// time_position and often a (0, 0) position.
+func positionProblem(update FlightUpdate) string {
+	switch {
+	case update.TimePosition == 0:
+		return "no position fix (time_position is 0)"
+	case math.IsNaN(update.Latitude) || update.Latitude < -90 || update.Latitude > 90:
+		return "latitude out of range"
+	case math.IsNaN(update.Longitude) || update.Longitude < -180 || update.Longitude > 180:
+		return "longitude out of range"
+	case update.Latitude == 0 && update.Longitude == 0:
+		return "no position fix (0, 0)"
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestWithinRadiusBoundsNeverRejectsInsidePoints(t *testing.T) {
+	const radiusKm = 50
+	for _, center := range [][2]float64{{0, 0}, {45, 10}, {-60, 179.9}, {89.5, 0}} {
+		for bearing := 0.0; bearing < 360; bearing += 15 {
+			// Just inside the radius, where a too-narrow box would cut it off
+			lat, lon := destinationPoint(center[0], center[1], bearing, radiusKm*0.9999)
+			if haversineDistance(lat, lon, center[0], center[1]) > radiusKm {
+				continue
+			}
+			if !withinRadiusBounds(lat, lon, center[0], center[1], radiusKm) {
+				t.Errorf("center %v bearing %g: point (%g, %g) inside the radius was rejected", center, bearing, lat, lon)
+			}
+		}
+	}
+}
+
+func TestWithinRadiusBoundsRejectsDistantPoints(t *testing.T) {
+	if withinRadiusBounds(41, -73, 40, -73, 50) {
+		t.Error("point 111 km north passed a 50 km prefilter")
+	}
+	if withinRadiusBounds(40, -71.5, 40, -73, 50) {
+		t.Error("point 128 km east passed a 50 km prefilter")
+	}
+}
+
+func TestProximityWindowMatchesHaversine(t *testing.T) {
+	// 1.999 km apart along a meridian, just inside a 2 km threshold
+	dLat := 1.999 / (6371 * math.Pi / 180)
+	flights := []TrackedFlight{
+		{FlightUpdate: FlightUpdate{ICAO24: "aaa111", Latitude: 10, Longitude: 20, BaroAltitude: ptr(1000.0)}},
+		{FlightUpdate: FlightUpdate{ICAO24: "bbb222", Latitude: 10 + dLat, Longitude: 20, BaroAltitude: ptr(1000.0)}},
+	}
+	if pairs := findProximityPairs(flights, 2, 300); len(pairs) != 1 {
+		t.Fatalf("pairs = %d, want 1", len(pairs))
+	}
+}
+
+func TestTrackedFlightDistanceAndBearing(t *testing.T) {
+	at := newTestTracker(t, testAirport("KJFK", 40.6413, -73.7781))
+	track(t, at, testUpdate("abc123", 40.75, -73.60))
+
+	rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/KJFK/nearby", map[string]string{"code": "KJFK"})
+	var body struct {
+		Flights []map[string]interface{} `json:"flights"`
+	}
+	decodeBody(t, rec, &body)
+	if len(body.Flights) != 1 {
+		t.Fatalf("%d flights, want 1", len(body.Flights))
+	}
+	distance, _ := body.Flights[0]["distance_km"].(float64)
+	bearing, _ := body.Flights[0]["bearing_deg"].(float64)
+	if want := haversineDistance(40.6413, -73.7781, 40.75, -73.60); distance != want {
+		t.Errorf("distance_km = %v, want %v", distance, want)
+	}
+	if math.Abs(distance-19.3) > 0.1 {
+		t.Errorf("distance_km = %.2f, want about 19.3", distance)
+	}
+	// North-east of the airport
+	if bearing < 45 || bearing > 55 {
+		t.Errorf("bearing_deg = %.1f, want about 50", bearing)
+	}
+}
+
+func TestInitialBearingCardinalDirections(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		lat2, lon2 float64
+		want       float64
+	}{
+		{"north", 1, 0, 0},
+		{"east", 0, 1, 90},
+		{"south", -1, 0, 180},
+		{"west", 0, -1, 270},
+	} {
+		if got := initialBearing(0, 0, tc.lat2, tc.lon2); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: bearing %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestEstimateETA(t *testing.T) {
+	airport := testAirport("KTST", 40, -73)
+	// 0.1 degrees due north of the airport, about 11.1 km out
+	update := testUpdate("abc123", 40.1, -73)
+	distance := haversineDistance(40.1, -73, 40, -73)
+	for _, tc := range []struct {
+		name      string
+		velocity  *float64
+		track     *float64
+		wantSpeed float64 // closing speed in m/s, 0 for no ETA
+	}{
+		{"straight in", ptr(100.0), ptr(180.0), 100},
+		{"oblique", ptr(100.0), ptr(120.0), 50},
+		{"tangential", ptr(100.0), ptr(90.0), 0},
+		{"moving away", ptr(100.0), ptr(0.0), 0},
+		{"no velocity", nil, ptr(180.0), 0},
+		{"no track", ptr(100.0), nil, 0},
+	} {
+		update.Velocity, update.TrueTrack = tc.velocity, tc.track
+		eta := estimateETA(update, airport, distance)
+		if tc.wantSpeed == 0 {
+			if eta != nil {
+				t.Errorf("%s: eta = %v s, want none", tc.name, *eta)
+			}
+			continue
+		}
+		if want := distance * 1000 / tc.wantSpeed; eta == nil || math.Abs(*eta-want) > 0.5 {
+			t.Errorf("%s: eta = %v, want about %.0f s", tc.name, eta, want)
+		}
+	}
+}
+
+func TestArrivalsIncludeETA(t *testing.T) {
+	at := newTestTracker(t)
+	update := testUpdate("abc123", 40.1, -73)
+	update.BaroAltitude, update.VerticalRate = ptr(1000.0), ptr(-5.0)
+	update.Velocity, update.TrueTrack = ptr(100.0), ptr(180.0)
+	track(t, at, update)
+
+	rec := call(at.handleArrivals, http.MethodGet, "/api/v1/airports/KTST/arrivals", map[string]string{"code": "KTST"})
+	var body struct {
+		Arrivals []TrackedFlight `json:"arrivals"`
+	}
+	decodeBody(t, rec, &body)
+	if len(body.Arrivals) != 1 || body.Arrivals[0].ETASeconds == nil {
+		t.Fatalf("arrivals = %+v, want one flight with an ETA", body.Arrivals)
+	}
+	if eta := *body.Arrivals[0].ETASeconds; math.Abs(eta-111) > 1 {
+		t.Errorf("eta_seconds = %.1f, want about 111", eta)
+	}
+}
+
+func TestRefineStatusByHeading(t *testing.T) {
+	airport := testAirport("KTST", 40, -73)
+	// About 11 km north of the airport, which bears 180
+	for _, tc := range []struct {
+		name      string
+		track     float64
+		rate      float64
+		altitude  float64
+		lat       float64
+		tolerance float64
+		want      string
+	}{
+		{"inbound, slightly climbing", 180, 0.5, 1000, 40.1, 45, StatusArriving},
+		{"outbound, slightly descending", 0, -1, 1000, 40.1, 45, StatusDeparting},
+		{"outbound, clearly descending", 0, -5, 1000, 40.1, 45, StatusArriving},
+		{"inbound, clearly climbing", 180, 5, 1000, 40.1, 45, StatusDeparting},
+		{"crossing", 90, 0.5, 1000, 40.1, 45, StatusNearby},
+		{"inbound within tolerance", 220, 0.5, 1000, 40.1, 45, StatusArriving},
+		{"inbound beyond tolerance", 220, 0.5, 1000, 40.1, 30, StatusNearby},
+		{"check disabled", 180, 0.5, 1000, 40.1, 0, StatusNearby},
+		{"above arrival threshold", 180, 0.5, 3500, 40.1, 45, StatusNearby},
+		{"outbound above departure threshold", 0, -0.5, 2500, 40.1, 45, StatusNearby},
+		{"over the airport", 180, 0.5, 1000, 40.005, 45, StatusNearby},
+	} {
+		update := testUpdate("abc123", tc.lat, -73)
+		update.TrueTrack, update.VerticalRate = ptr(tc.track), ptr(tc.rate)
+		status := classifyStatus(update, tc.altitude, true, airport)
+		distance := haversineDistance(tc.lat, -73, 40, -73)
+		if got := refineStatusByHeading(status, update, tc.altitude, true, airport, distance, tc.tolerance, defaultVerticalDeadBandMS); got != tc.want {
+			t.Errorf("%s: %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHeadingToleranceConfigured(t *testing.T) {
+	inbound := testUpdate("abc123", 40.1, -73)
+	inbound.BaroAltitude, inbound.VerticalRate, inbound.TrueTrack = ptr(1000.0), ptr(0.5), ptr(200.0)
+	for tolerance, want := range map[string]string{"": StatusArriving, "10": StatusNearby, "0": StatusNearby} {
+		t.Setenv("HEADING_TOLERANCE_DEG", tolerance)
+		at := newTestTracker(t)
+		track(t, at, inbound)
+		if flight, _ := at.flights.Get("abc123"); flight.Status != want {
+			t.Errorf("HEADING_TOLERANCE_DEG=%q: status %s, want %s", tolerance, flight.Status, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGeocodePrecision = 2 // decimal places, roughly 1 km
+	defaultGeocodeCacheSize = 10000
+)
+
+// ReverseGeocoder resolves a position to a human-readable place name
+type ReverseGeocoder interface {
+	ReverseGeocode(lat, lon float64) (string, error)
+}
+
+// noopGeocoder is the default provider and never resolves anything
+type noopGeocoder struct{}
+
+func (noopGeocoder) ReverseGeocode(lat, lon float64) (string, error) { return "", nil }
+
+// nominatimGeocoder queries a Nominatim-compatible /reverse endpoint
+type nominatimGeocoder struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (g *nominatimGeocoder) ReverseGeocode(lat, lon float64) (string, error) {
+	query := url.Values{}
+	query.Set("format", "jsonv2")
+	query.Set("zoom", "10")
+	query.Set("lat", strconv.FormatFloat(lat, 'f', 5, 64))
+	query.Set("lon", strconv.FormatFloat(lon, 'f', 5, 64))
+
+	resp, err := g.client.Get(g.baseURL + "?" + query.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("geocoder returned %s", resp.Status)
+	}
+
+	var result struct {
+		DisplayName string `json:"display_name"`
+		Address     struct {
+			City    string `json:"city"`
+			Town    string `json:"town"`
+			Village string `json:"village"`
+		} `json:"address"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	for _, name := range []string{result.Address.City, result.Address.Town, result.Address.Village} {
+		if name != "" {
+			return name, nil
+		}
+	}
+	return result.DisplayName, nil
+}
+
+// geocodeCache wraps a provider with a cache keyed by quantized coordinates
+// so nearby positions of the same flight share one lookup.
+type geocodeCache struct {
+	provider  ReverseGeocoder
+	precision int
+	maxSize   int
+
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+func newGeocodeCache(provider ReverseGeocoder, precision, maxSize int) *geocodeCache {
+	return &geocodeCache{
+		provider:  provider,
+		precision: precision,
+		maxSize:   maxSize,
+		entries:   make(map[string]string),
+	}
+}
+
+// newGeocoderFromEnv enables the Nominatim provider when GEOCODER_URL is set.
+// GEOCODE_PRECISION controls the quantization (decimal places).
+func newGeocoderFromEnv() *geocodeCache {
+	var provider ReverseGeocoder = noopGeocoder{}
+	if baseURL := envString("GEOCODER_URL", ""); baseURL != "" {
+		provider = &nominatimGeocoder{baseURL: baseURL, client: &http.Client{Timeout: 2 * time.Second}}
+		slog.Info("reverse geocoding enabled", "url", baseURL)
+	}
+
+	precision := envInt("GEOCODE_PRECISION", defaultGeocodePrecision)
+	if precision < 0 {
+		precision = defaultGeocodePrecision
+	}
+	return newGeocodeCache(provider, precision, defaultGeocodeCacheSize)
+}
+
+// Lookup returns the cached or freshly resolved place name. Provider errors
+// are logged and yield an empty label; they are not cached so a later
+// update can retry.
+func (c *geocodeCache) Lookup(lat, lon float64) string {
+	if _, ok := c.provider.(noopGeocoder); ok {
+		return ""
+	}
+
+	scale := math.Pow(10, float64(c.precision))
+	key := fmt.Sprintf("%.*f,%.*f", c.precision, math.Round(lat*scale)/scale, c.precision, math.Round(lon*scale)/scale)
+
+	c.mu.Lock()
+	label, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return label
+	}
+
+	label, err := c.provider.ReverseGeocode(lat, lon)
+	if err != nil {
+		slog.Warn("reverse geocoding failed", "key", key, "error", err)
+		return ""
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= c.maxSize {
+		c.entries = make(map[string]string)
+	}
+	c.entries[key] = label
+	c.mu.Unlock()
+	return label
+}
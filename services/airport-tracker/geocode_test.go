@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// stubGeocoder names every position after city and counts lookups
+type stubGeocoder struct {
+	city  string
+	err   error
+	calls atomic.Int32
+}
+
+func (g *stubGeocoder) ReverseGeocode(lat, lon float64) (string, error) {
+	g.calls.Add(1)
+	return g.city, g.err
+}
+
+func TestGeocodeCacheHitsForNearbyPositions(t *testing.T) {
+	stub := &stubGeocoder{city: "Springfield"}
+	cache := newGeocodeCache(stub, 2, 100)
+
+	if got := cache.Lookup(40.0012, -73.0021); got != "Springfield" {
+		t.Fatalf("label = %q, want Springfield", got)
+	}
+	// Same cell at two decimal places
+	cache.Lookup(40.0049, -73.0001)
+	if calls := stub.calls.Load(); calls != 1 {
+		t.Errorf("provider called %d times for one cell, want 1", calls)
+	}
+	cache.Lookup(40.02, -73)
+	if calls := stub.calls.Load(); calls != 2 {
+		t.Errorf("provider called %d times for two cells, want 2", calls)
+	}
+}
+
+func TestGeocodeFailuresAreSoftAndNotCached(t *testing.T) {
+	stub := &stubGeocoder{err: errors.New("unavailable")}
+	cache := newGeocodeCache(stub, 2, 100)
+	if got := cache.Lookup(40, -73); got != "" {
+		t.Errorf("label = %q after a failure, want empty", got)
+	}
+	stub.err, stub.city = nil, "Springfield"
+	if got := cache.Lookup(40, -73); got != "Springfield" {
+		t.Errorf("label = %q after recovery, want Springfield", got)
+	}
+}
+
+func TestTrackedFlightsAreGeocoded(t *testing.T) {
+	at := newTestTracker(t)
+	at.geocoder = newGeocodeCache(&stubGeocoder{city: "Springfield"}, 2, 100)
+	track(t, at, testUpdate("abc123", 40.05, -73))
+	if flight, _ := at.flights.Get("abc123"); flight.Location != "Springfield" {
+		t.Errorf("location = %q, want Springfield", flight.Location)
+	}
+}
+
+func TestNominatimGeocoderPrefersCity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "jsonv2" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"display_name": "Somewhere, County", "address": {"town": "Shelbyville"}}`))
+	}))
+	defer server.Close()
+
+	g := &nominatimGeocoder{baseURL: server.URL, client: server.Client()}
+	if got, err := g.ReverseGeocode(40, -73); err != nil || got != "Shelbyville" {
+		t.Errorf("ReverseGeocode = %q, %v; want Shelbyville", got, err)
+	}
+}
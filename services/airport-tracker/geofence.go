@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Geofence types (AirportConfig.GeofenceType)
+const (
+	GeofenceCircle   = "circle"
+	GeofencePolygon  = "polygon"
+	GeofenceCorridor = "corridor"
+)
+
+// geofenceType returns the airport's geofence type. Without an explicit
+// geofence_type an airport with polygon vertices is a polygon, and anything
+// else is a circle of RadiusKm around the airport reference point.
+func (a AirportConfig) geofenceType() string {
+	if a.GeofenceType == "" {
+		if len(a.Polygon) > 0 {
+			return GeofencePolygon
+		}
+		return GeofenceCircle
+	}
+	return strings.ToLower(a.GeofenceType)
+}
+
+// contains reports whether a position is inside the airport's geofence.
+// distanceKm is the position's precomputed distance to the airport
+// reference point, used by the circle test.
+func (a AirportConfig) contains(lat, lon, distanceKm float64) bool {
+	switch a.geofenceType() {
+	case GeofencePolygon:
+		return pointInPolygon(lat, lon, a.Polygon)
+	case GeofenceCorridor:
+		return distanceToPath(lat, lon, a.Corridor) <= a.CorridorWidthKm/2
+	default:
+		return distanceKm <= a.RadiusKm
+	}
+}
+
+// validateGeofence checks the parameters required by the geofence type
+func (a AirportConfig) validateGeofence() error {
+	switch a.geofenceType() {
+	case GeofenceCircle:
+		return nil
+	case GeofencePolygon:
+		if len(a.Polygon) < 3 {
+			return fmt.Errorf("%s: polygon geofence needs at least 3 vertices", a.ICAO)
+		}
+		return validateVertices(a.ICAO, "polygon", a.Polygon)
+	case GeofenceCorridor:
+		if len(a.Corridor) < 2 {
+			return fmt.Errorf("%s: corridor geofence needs at least 2 points", a.ICAO)
+		}
+		if a.CorridorWidthKm <= 0 {
+			return fmt.Errorf("%s: corridor geofence needs a positive corridor_width_km", a.ICAO)
+		}
+		return validateVertices(a.ICAO, "corridor", a.Corridor)
+	default:
+		return fmt.Errorf("%s: unknown geofence_type %q", a.ICAO, a.GeofenceType)
+	}
+}
+
+func validateVertices(icao, field string, vertices [][]float64) error {
+	for i, v := range vertices {
+		if len(v) != 2 {
+			return fmt.Errorf("%s: %s point %d must be [lat, lon]", icao, field, i)
+		}
+		if v[0] < -90 || v[0] > 90 || v[1] < -180 || v[1] > 180 {
+			return fmt.Errorf("%s: %s point %d out of range", icao, field, i)
+		}
+	}
+	return nil
+}
+
+// unwrapLongitude shifts lon by whole turns so it is within 180° of ref,
+// letting shapes that straddle the antimeridian be treated as planar
+func unwrapLongitude(lon, ref float64) float64 {
+	for lon-ref > 180 {
+		lon -= 360
+	}
+	for lon-ref < -180 {
+		lon += 360
+	}
+	return lon
+}
+
+// pointInPolygon is an even-odd ray-casting test over [lat, lon] vertices.
+// It works for concave polygons; longitudes are unwrapped around the test
+// point so polygons crossing the antimeridian are handled.
+func pointInPolygon(lat, lon float64, polygon [][]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		latI, lonI := polygon[i][0], unwrapLongitude(polygon[i][1], lon)
+		latJ, lonJ := polygon[j][0], unwrapLongitude(polygon[j][1], lon)
+		// Half-open comparison counts a vertex on the ray exactly once
+		if (latI > lat) != (latJ > lat) {
+			crossLon := lonI + (lat-latI)/(latJ-latI)*(lonJ-lonI)
+			if lon < crossLon {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// distanceToPath returns the distance in km from a position to the nearest
+// segment of a [lat, lon] polyline, using a local equirectangular projection
+// around the position (accurate for corridor widths of tens of km)
+func distanceToPath(lat, lon float64, path [][]float64) float64 {
+	kmPerDegLon := kmPerDegreeLatitude * math.Cos(lat*math.Pi/180)
+	project := func(p []float64) (x, y float64) {
+		return (unwrapLongitude(p[1], lon) - lon) * kmPerDegLon, (p[0] - lat) * kmPerDegreeLatitude
+	}
+
+	best := math.Inf(1)
+	for i := 1; i < len(path); i++ {
+		ax, ay := project(path[i-1])
+		bx, by := project(path[i])
+		// Closest point on segment AB to the origin (the position)
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSq))
+		}
+		best = math.Min(best, math.Hypot(ax+t*dx, ay+t*dy))
+	}
+	return best
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeofenceTypesDispatch(t *testing.T) {
+	circle := testAirport("KCIR", 40, -73)
+	circle.RadiusKm = 10
+	polygon := testAirport("KPOL", 41.1, -72.9)
+	polygon.GeofenceType = GeofencePolygon
+	polygon.Polygon = [][]float64{{41, -73}, {41.2, -73}, {41.2, -72.8}, {41, -72.8}}
+	corridor := testAirport("KCOR", 42, -72.5)
+	corridor.GeofenceType = GeofenceCorridor
+	corridor.Corridor = [][]float64{{42, -73}, {42, -72}}
+	corridor.CorridorWidthKm = 4
+	at := newTestTracker(t, circle, polygon, corridor)
+
+	for _, tc := range []struct {
+		icao24   string
+		lat, lon float64
+		airport  string // empty when outside every geofence
+	}{
+		{"aaa001", 40.05, -73, "KCIR"},
+		{"aaa002", 40.2, -73, ""},         // 22 km out, beyond the radius
+		{"bbb001", 41.19, -72.81, "KPOL"}, // near a corner, far from the reference point
+		{"bbb002", 41.25, -72.9, ""},
+		{"ccc001", 42.01, -72.1, "KCOR"}, // 1.1 km off the centerline, 33 km from the reference point
+		{"ccc002", 42.03, -72.5, ""},     // 3.3 km off, beyond half the width
+	} {
+		track(t, at, testUpdate(tc.icao24, tc.lat, tc.lon))
+		flight, ok := at.flights.Get(tc.icao24)
+		switch {
+		case tc.airport == "" && ok:
+			t.Errorf("%s tracked at %s, want untracked", tc.icao24, flight.AirportCode)
+		case tc.airport != "" && flight.AirportCode != tc.airport:
+			t.Errorf("%s tracked at %q, want %s", tc.icao24, flight.AirportCode, tc.airport)
+		}
+	}
+}
+
+func TestGeofenceValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*AirportConfig)
+		want   string
+	}{
+		{"unknown type", func(a *AirportConfig) { a.GeofenceType = "hexagon" }, "unknown geofence_type"},
+		{"short polygon", func(a *AirportConfig) {
+			a.GeofenceType = GeofencePolygon
+			a.Polygon = [][]float64{{40, -73}, {40.1, -73}}
+		}, "at least 3 vertices"},
+		{"bad vertex", func(a *AirportConfig) { a.Polygon = [][]float64{{40, -73}, {40.1, -73}, {95, -73}} }, "out of range"},
+		{"corridor width", func(a *AirportConfig) {
+			a.GeofenceType = GeofenceCorridor
+			a.Corridor = [][]float64{{40, -73}, {40, -72}}
+		}, "corridor_width_km"},
+	} {
+		airport := testAirport("KBAD", 40, -73)
+		tc.modify(&airport)
+		if err := airport.validateGeofence(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+	if err := testAirport("KTST", 40, -73).validateGeofence(); err != nil {
+		t.Errorf("circle: %v", err)
+	}
+}
+
+func TestPointInConcavePolygon(t *testing.T) {
+	// A U shape: the notch between the arms is outside
+	u := [][]float64{{0, 0}, {0, 3}, {3, 3}, {3, 2}, {1, 2}, {1, 1}, {3, 1}, {3, 0}}
+	for _, tc := range []struct {
+		name     string
+		lat, lon float64
+		inside   bool
+	}{
+		{"base", 0.5, 1.5, true},
+		{"left arm", 2, 0.5, true},
+		{"right arm", 2, 2.5, true},
+		{"notch", 2, 1.5, false},
+		{"above the notch", 3.5, 1.5, false},
+		{"east of both arms", 2, 3.5, false},
+		// The ray from these points passes through the notch's vertices
+		{"level with the notch floor", 1, 0.5, true},
+		{"level with the arm tops", 3 - 1e-9, 0.5, true},
+	} {
+		if got := pointInPolygon(tc.lat, tc.lon, u); got != tc.inside {
+			t.Errorf("%s (%g, %g): inside = %v, want %v", tc.name, tc.lat, tc.lon, got, tc.inside)
+		}
+	}
+}
+
+func TestConcavePolygonAirportSkipsNotch(t *testing.T) {
+	airport := testAirport("KUUU", 40.15, -73.15)
+	airport.Polygon = [][]float64{{40, -73.3}, {40, -73}, {40.3, -73}, {40.3, -73.1}, {40.1, -73.1}, {40.1, -73.2}, {40.3, -73.2}, {40.3, -73.3}}
+	at := newTestTracker(t, airport)
+	track(t, at, testUpdate("aaa001", 40.2, -73.25), testUpdate("bbb002", 40.2, -73.15))
+
+	if _, ok := at.flights.Get("aaa001"); !ok {
+		t.Error("flight over the arm not tracked")
+	}
+	if flight, ok := at.flights.Get("bbb002"); ok {
+		t.Errorf("flight in the notch tracked at %s, though it is near the reference point", flight.AirportCode)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Ground filter modes (GROUND_FILTER) for taxiing and low-altitude clutter
+const (
+	GroundFilterOff     = "off"     // classify grounded flights like any other
+	GroundFilterStatus  = "status"  // track them with status on_ground
+	GroundFilterExclude = "exclude" // track them but leave them out of arrivals and departures
+)
+
+// groundFilterFromEnv reads GROUND_FILTER, defaulting to off
+func groundFilterFromEnv() string {
+	mode := strings.ToLower(envString("GROUND_FILTER", GroundFilterOff))
+	switch mode {
+	case GroundFilterOff, GroundFilterStatus, GroundFilterExclude:
+		return mode
+	}
+	slog.Warn("unknown GROUND_FILTER, using off", "value", mode)
+	return GroundFilterOff
+}
+
+// isGrounded reports whether a flight counts as on the ground: it says so,
+// or its altitude is below floorM (GROUND_ALTITUDE_FLOOR_M, in meters like
+// the airport thresholds). A flight without an altitude is only grounded if
+// it reports on_ground.
+func isGrounded(update FlightUpdate, altitude float64, hasAltitude bool, floorM float64) bool {
+	return update.OnGround || (hasAltitude && altitude < floorM)
+}
+
+// excludedAsGrounded reports whether GROUND_FILTER=exclude leaves flight out
+// of the arrivals and departures lists. Grounded flights are still tracked,
+// so they stay visible everywhere else.
+func (at *AirportTracker) excludedAsGrounded(flight *TrackedFlight) bool {
+	if at.groundFilter != GroundFilterExclude {
+		return false
+	}
+	altitude, hasAltitude := effectiveAltitude(flight.FlightUpdate)
+	return isGrounded(flight.FlightUpdate, altitude, hasAltitude, at.groundFloorM)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// groundUpdates are a taxiing flight, a low-altitude airborne one below a
+// 100 m floor, and an arrival above it
+func groundUpdates() []FlightUpdate {
+	taxiing := testUpdate("aaa001", 40.05, -73)
+	taxiing.OnGround, taxiing.Velocity = true, ptr(8.0)
+	low := testUpdate("bbb002", 40.05, -73)
+	low.BaroAltitude, low.VerticalRate = ptr(60.0), ptr(-3.0)
+	arriving := testUpdate("ccc003", 40.05, -73)
+	arriving.BaroAltitude, arriving.VerticalRate = ptr(600.0), ptr(-3.0)
+	return []FlightUpdate{taxiing, low, arriving}
+}
+
+func TestGroundFilterStatus(t *testing.T) {
+	t.Setenv("GROUND_FILTER", "STATUS")
+	t.Setenv("GROUND_ALTITUDE_FLOOR_M", "100")
+	at := newTestTracker(t)
+	track(t, at, groundUpdates()...)
+
+	for icao24, want := range map[string]string{"aaa001": StatusOnGround, "bbb002": StatusOnGround, "ccc003": StatusArriving} {
+		if flight, _ := at.flights.Get(icao24); flight.Status != want {
+			t.Errorf("%s: status %s, want %s", icao24, flight.Status, want)
+		}
+	}
+	var body struct {
+		Arrivals []TrackedFlight `json:"arrivals"`
+	}
+	decodeBody(t, call(at.handleArrivals, http.MethodGet, "/", map[string]string{"code": "KTST"}), &body)
+	if len(body.Arrivals) != 1 || body.Arrivals[0].ICAO24 != "ccc003" {
+		t.Errorf("arrivals = %+v, want ccc003 only", body.Arrivals)
+	}
+}
+
+func TestGroundFilterExclude(t *testing.T) {
+	t.Setenv("GROUND_FILTER", GroundFilterExclude)
+	t.Setenv("GROUND_ALTITUDE_FLOOR_M", "100")
+	at := newTestTracker(t)
+	track(t, at, groundUpdates()...)
+
+	// Grounded flights are still tracked, only left out of the lists
+	if n := at.flights.Len(); n != 3 {
+		t.Errorf("tracked %d flights, want all 3", n)
+	}
+	var body struct {
+		Arrivals []TrackedFlight `json:"arrivals"`
+	}
+	decodeBody(t, call(at.handleArrivals, http.MethodGet, "/", map[string]string{"code": "KTST"}), &body)
+	if len(body.Arrivals) != 1 || body.Arrivals[0].ICAO24 != "ccc003" {
+		t.Errorf("arrivals = %+v, want ccc003 only", body.Arrivals)
+	}
+
+	for i := 0; i < 3; i++ {
+		climbing := testUpdate("ddd004", 40.01+float64(i)*0.01, -73)
+		climbing.BaroAltitude, climbing.VerticalRate = ptr(50.0+float64(i)*10), ptr(5.0)
+		track(t, at, climbing)
+	}
+	if flight, _ := at.flights.Get("ddd004"); flight.Status != StatusDeparting {
+		t.Fatalf("ddd004 status %s, want it tracked as departing", flight.Status)
+	}
+	var departures struct {
+		Departures []TrackedFlight `json:"departures"`
+	}
+	decodeBody(t, call(at.handleDepartures, http.MethodGet, "/", map[string]string{"code": "KTST"}), &departures)
+	if len(departures.Departures) != 0 {
+		t.Errorf("departures = %+v, want the flight below the floor left out", departures.Departures)
+	}
+}
+
+func TestGroundFilterOffByDefault(t *testing.T) {
+	at := newTestTracker(t)
+	track(t, at, groundUpdates()...)
+	for icao24, want := range map[string]string{"aaa001": StatusNearby, "bbb002": StatusArriving} {
+		if flight, _ := at.flights.Get(icao24); flight.Status != want {
+			t.Errorf("%s: status %s, want %s", icao24, flight.Status, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMain(m *testing.M) {
+	// Keep test output readable; tests that assert on logs install their own
+	slog.SetDefault(newLogger(io.Discard, "error"))
+	os.Exit(m.Run())
+}
+
+// testAirport is a circular airport with the default thresholds
+func testAirport(icao string, lat, lon float64) AirportConfig {
+	return AirportConfig{
+		ICAO:                icao,
+		Name:                icao + " Test",
+		Latitude:            lat,
+		Longitude:           lon,
+		RadiusKm:            50,
+		ArrivalThresholdM:   3000,
+		DepartureThresholdM: 2000,
+	}
+}
+
+// writeAirports writes airports to a config file in a temporary directory
+// and returns its path
+func writeAirports(t testing.TB, airports ...AirportConfig) string {
+	t.Helper()
+	data, err := json.Marshal(airports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "airports.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestTracker starts a tracker over airports (a single KTST airport when
+// none are given) and closes it when the test ends
+func newTestTracker(t testing.TB, airports ...AirportConfig) *AirportTracker {
+	t.Helper()
+	if len(airports) == 0 {
+		airports = []AirportConfig{testAirport("KTST", 40, -73)}
+	}
+	at, err := NewAirportTracker(writeAirports(t, airports...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(at.Close)
+	return at
+}
+
+// testUpdate is a position report with a current position fix
+func testUpdate(icao24 string, lat, lon float64) FlightUpdate {
+	now := time.Now().Unix()
+	return FlightUpdate{ICAO24: icao24, Latitude: lat, Longitude: lon, TimePosition: now, LastContact: now}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// call calls handler with a request for target, setting the route
+// variables a mux router would have extracted
+func call(handler http.HandlerFunc, method, target string, vars map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if vars != nil {
+		req = mux.SetURLVars(req, vars)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// decodeBody decodes a JSON response into v, failing the test otherwise
+func decodeBody(t testing.TB, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %d response %q: %v", rec.Code, rec.Body.String(), err)
+	}
+}
+
+// track processes updates, failing the test on any error
+func track(t testing.TB, at *AirportTracker, updates ...FlightUpdate) {
+	t.Helper()
+	for _, update := range updates {
+		if _, err := at.processFlightUpdate(context.Background(), update); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// logBuffer collects log output from any goroutine
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogs sends logs at level and above to the returned buffer until
+// the test ends
+func captureLogs(t testing.TB, level string) *logBuffer {
+	prev := slog.Default()
+	logs := &logBuffer{}
+	slog.SetDefault(newLogger(logs, level))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return logs
+}
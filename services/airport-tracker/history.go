@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultHistoryLength           = 50
+	defaultDepartureConfirmSamples = 3
+	defaultAltitudeSmoothingWindow = 1
+
+	// Each sample of a confirmed departure must be at least this much
+	// further from the airport than the last, so position noise on an
+	// orbit does not count as moving away
+	minDepartureGainKm = 0.05
+
+	// Bounds on the gap between samples used to derive an implied speed;
+	// shorter gaps amplify position noise, longer ones hide turns
+	minSpeedSampleGap = 2 * time.Second
+	maxSpeedSampleGap = 2 * time.Minute
+)
+
+// PositionSample is one recorded position of a tracked flight
+type PositionSample struct {
+	Latitude     float64   `json:"lat"`
+	Longitude    float64   `json:"lon"`
+	Altitude     *float64  `json:"altitude,omitempty"`
+	VerticalRate *float64  `json:"vertical_rate,omitempty"`
+	Velocity     *float64  `json:"velocity,omitempty"`
+	PositionTime int64     `json:"time_position,omitempty"` // feed position timestamp, unix seconds
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+func sampleFromUpdate(update FlightUpdate, seen time.Time) PositionSample {
+	sample := PositionSample{
+		Latitude:     update.Latitude,
+		Longitude:    update.Longitude,
+		VerticalRate: update.VerticalRate,
+		Velocity:     update.Velocity,
+		PositionTime: update.TimePosition,
+		Timestamp:    seen,
+	}
+	if altitude, ok := effectiveAltitude(update); ok {
+		sample.Altitude = &altitude
+	}
+	return sample
+}
+
+// appendSample returns a new history with sample appended, dropping the
+// oldest entries beyond limit. The input slice is never modified so
+// previously returned flights keep a consistent view.
+func appendSample(history []PositionSample, sample PositionSample, limit int) []PositionSample {
+	if limit < 1 {
+		limit = 1
+	}
+	start := 0
+	if len(history)+1 > limit {
+		start = len(history) + 1 - limit
+	}
+	next := make([]PositionSample, 0, len(history)-start+1)
+	next = append(next, history[start:]...)
+	return append(next, sample)
+}
+
+// smoothedAltitude averages the altitudes reported in the last window
+// samples, skipping samples without one. ok is false when none of them has
+// an altitude. A window of 1 is the latest sample's altitude.
+func smoothedAltitude(history []PositionSample, window int) (altitude float64, ok bool) {
+	if window < 1 {
+		window = 1
+	}
+	if len(history) > window {
+		history = history[len(history)-window:]
+	}
+	sum, n := 0.0, 0
+	for _, sample := range history {
+		if sample.Altitude != nil {
+			sum += *sample.Altitude
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// confirmDeparture reports whether the last n samples show a sustained
+// climb-out: every sample climbing (positive vertical rate, or rising
+// altitude when the rate is missing) and each one further from the airport
+// than the last by minDepartureGainKm. A low orbit fails the distance check
+// even while climbing. Without requireClimb only the distance is checked,
+// for departures decided by an outbound track while level or slightly
+// descending.
+func confirmDeparture(history []PositionSample, airport AirportConfig, n int, requireClimb bool) bool {
+	if n <= 1 {
+		return true
+	}
+	if len(history) < n {
+		return false
+	}
+	recent := history[len(history)-n:]
+	for i, sample := range recent {
+		if requireClimb {
+			climbing := false
+			switch {
+			case sample.VerticalRate != nil:
+				climbing = *sample.VerticalRate > 0
+			case i == 0:
+				// Nothing earlier to compare the altitude against
+				climbing = true
+			case sample.Altitude != nil && recent[i-1].Altitude != nil:
+				climbing = *sample.Altitude > *recent[i-1].Altitude
+			}
+			if !climbing {
+				return false
+			}
+		}
+		if i == 0 {
+			continue
+		}
+		prev := recent[i-1]
+		if haversineDistance(sample.Latitude, sample.Longitude, airport.Latitude, airport.Longitude) <
+			haversineDistance(prev.Latitude, prev.Longitude, airport.Latitude, airport.Longitude)+minDepartureGainKm {
+			return false
+		}
+	}
+	return true
+}
+
+// estimateSpeedDiscrepancy derives the ground speed implied by the last two
+// positions and compares it with the reported velocity (averaged over both
+// samples when available). The difference approximates the wind component
+// along the track. Both results are nil when the history is too short, the
+// sample gap is out of bounds, or no velocity was reported.
+func estimateSpeedDiscrepancy(history []PositionSample) (implied, diff *float64) {
+	if len(history) < 2 {
+		return nil, nil
+	}
+	prev, last := history[len(history)-2], history[len(history)-1]
+	if last.Velocity == nil {
+		return nil, nil
+	}
+
+	// Prefer the feed's position timestamps over our receive times, which
+	// include publish and delivery jitter
+	gap := last.Timestamp.Sub(prev.Timestamp)
+	if prev.PositionTime > 0 && last.PositionTime > 0 {
+		gap = time.Duration(last.PositionTime-prev.PositionTime) * time.Second
+	}
+	if gap < minSpeedSampleGap || gap > maxSpeedSampleGap {
+		return nil, nil
+	}
+
+	distanceM := haversineDistance(prev.Latitude, prev.Longitude, last.Latitude, last.Longitude) * 1000
+	speed := distanceM / gap.Seconds()
+	reported := *last.Velocity
+	if prev.Velocity != nil {
+		reported = (reported + *prev.Velocity) / 2
+	}
+	delta := speed - reported
+	if math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return nil, nil
+	}
+	return &speed, &delta
+}
+
+// InterarrivalStats summarises the time between successive updates of one
+// flight, from the LastSeen of each update
+type InterarrivalStats struct {
+	LastSeconds float64 `json:"last_seconds"`
+	MeanSeconds float64 `json:"mean_seconds"`
+	Intervals   int64   `json:"intervals"`
+}
+
+// nextInterarrival folds the gap between prevSeen and seen into a running
+// mean. prev may be nil for the second update of a flight.
+func nextInterarrival(prev *InterarrivalStats, prevSeen, seen time.Time) *InterarrivalStats {
+	gap := seen.Sub(prevSeen).Seconds()
+	if gap < 0 {
+		return prev
+	}
+	next := InterarrivalStats{LastSeconds: gap, MeanSeconds: gap, Intervals: 1}
+	if prev != nil {
+		next.Intervals = prev.Intervals + 1
+		next.MeanSeconds = prev.MeanSeconds + (gap-prev.MeanSeconds)/float64(next.Intervals)
+	}
+	return &next
+}
+
+// GET /api/v1/flights/{icao24}/track - Recent positions of a flight, oldest
+// first. History is kept per replica, so this reads the local store.
+func (at *AirportTracker) handleFlightTrack(w http.ResponseWriter, r *http.Request) {
+	icao24 := mux.Vars(r)["icao24"]
+	flight, ok := at.localFlight(normalizeICAO24(icao24))
+	if !ok {
+		http.Error(w, fmt.Sprintf("Flight %s is not tracked", icao24), http.StatusNotFound)
+		return
+	}
+
+	track := flight.History
+	if track == nil {
+		track = []PositionSample{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"icao24":   flight.ICAO24,
+		"callsign": flight.Callsign,
+		"track":    track,
+		"count":    len(track),
+	})
+}
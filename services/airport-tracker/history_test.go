@@ -0,0 +1,252 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// climbing is an update at lat, lon climbing through altitude
+func climbing(lat, lon, altitude float64) FlightUpdate {
+	update := testUpdate("abc123", lat, lon)
+	update.BaroAltitude = ptr(altitude)
+	update.VerticalRate = ptr(5.0)
+	return update
+}
+
+func TestDepartureNeedsSustainedClimbOut(t *testing.T) {
+	at := newTestTracker(t)
+	statuses := []string{}
+	for i := 0; i < 4; i++ {
+		track(t, at, climbing(40.01+float64(i)*0.01, -73, 300+float64(i)*150))
+		flight, _ := at.flights.Get("abc123")
+		statuses = append(statuses, flight.Status)
+	}
+	// Confirmed once DEPARTURE_CONFIRM_SAMPLES (3) samples climb away
+	want := []string{StatusNearby, StatusNearby, StatusDeparting, StatusDeparting}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", statuses, want)
+		}
+	}
+}
+
+func TestLowOrbitIsNotDeparting(t *testing.T) {
+	at := newTestTracker(t)
+	// Circling 5 km out while slowly climbing
+	for i := 0; i < 8; i++ {
+		angle := float64(i) * math.Pi / 4
+		lat, lon := destinationPoint(40, -73, angle*180/math.Pi, 5)
+		track(t, at, climbing(lat, lon, 400+float64(i)*10))
+		if flight, _ := at.flights.Get("abc123"); flight.Status == StatusDeparting {
+			t.Fatalf("sample %d: low orbit classified as departing", i)
+		}
+	}
+}
+
+func TestOutboundSlightlyDescendingIsDeparting(t *testing.T) {
+	at := newTestTracker(t)
+	statuses := []string{}
+	for i := 0; i < 4; i++ {
+		update := testUpdate("abc123", 40.02+float64(i)*0.01, -73)
+		update.BaroAltitude = ptr(1000 - float64(i)*5)
+		update.VerticalRate, update.TrueTrack = ptr(-1.0), ptr(0.0)
+		track(t, at, update)
+		flight, _ := at.flights.Get("abc123")
+		statuses = append(statuses, flight.Status)
+	}
+	// The outbound track decides the status, so only the distance gain is confirmed
+	want := []string{StatusNearby, StatusNearby, StatusDeparting, StatusDeparting}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", statuses, want)
+		}
+	}
+}
+
+func TestConfirmDepartureChecksClimbAndDistance(t *testing.T) {
+	airport := testAirport("KTST", 40, -73)
+	sample := func(lat float64, altitude float64, rate *float64) PositionSample {
+		return PositionSample{Latitude: lat, Longitude: -73, Altitude: ptr(altitude), VerticalRate: rate}
+	}
+	climbOut := []PositionSample{sample(40.01, 300, nil), sample(40.02, 400, nil), sample(40.03, 500, nil)}
+	if !confirmDeparture(climbOut, airport, 3, true) {
+		t.Error("climb-out inferred from rising altitude not confirmed")
+	}
+	descending := []PositionSample{sample(40.01, 300, ptr(5.0)), sample(40.02, 400, ptr(-2.0)), sample(40.03, 500, ptr(5.0))}
+	if confirmDeparture(descending, airport, 3, true) {
+		t.Error("confirmed with a descending sample")
+	}
+	closing := []PositionSample{sample(40.03, 300, ptr(5.0)), sample(40.02, 400, ptr(5.0)), sample(40.01, 500, ptr(5.0))}
+	if confirmDeparture(closing, airport, 3, true) {
+		t.Error("confirmed while closing on the airport")
+	}
+	if confirmDeparture(climbOut[:2], airport, 3, true) {
+		t.Error("confirmed with too few samples")
+	}
+	if !confirmDeparture(descending, airport, 3, false) {
+		t.Error("moving away without a climb not confirmed when the climb is not required")
+	}
+	if confirmDeparture(closing, airport, 3, false) {
+		t.Error("confirmed while closing on the airport without a climb required")
+	}
+}
+
+func TestSpeedDiscrepancyEstimatesTailwind(t *testing.T) {
+	at := newTestTracker(t)
+	// 250 m/s over the ground for 60 s while reporting 230 m/s: a 20 m/s tailwind
+	start := time.Now().Unix() - 60
+	first := testUpdate("abc123", 40, -73)
+	first.TimePosition, first.LastContact = start, start
+	first.Velocity = ptr(230.0)
+	second := testUpdate("abc123", 40+15/kmPerDegreeHaversine, -73)
+	second.TimePosition, second.LastContact = start+60, start+60
+	second.Velocity = ptr(230.0)
+	track(t, at, first, second)
+
+	flight, _ := at.flights.Get("abc123")
+	if flight.ImpliedSpeedMS == nil || flight.SpeedDiscrepancyMS == nil {
+		t.Fatal("no speed estimate after two samples")
+	}
+	if math.Abs(*flight.ImpliedSpeedMS-250) > 0.1 || math.Abs(*flight.SpeedDiscrepancyMS-20) > 0.1 {
+		t.Errorf("implied %.2f m/s, discrepancy %.2f m/s; want 250 and 20", *flight.ImpliedSpeedMS, *flight.SpeedDiscrepancyMS)
+	}
+}
+
+func TestSpeedDiscrepancyNeedsUsableHistory(t *testing.T) {
+	now := time.Now()
+	sample := func(lat float64, at time.Time, velocity *float64) PositionSample {
+		return PositionSample{Latitude: lat, Longitude: -73, Timestamp: at, Velocity: velocity}
+	}
+	for name, history := range map[string][]PositionSample{
+		"single sample": {sample(40, now, ptr(230.0))},
+		"no velocity":   {sample(40, now, nil), sample(40.1, now.Add(time.Minute), nil)},
+		"gap too short": {sample(40, now, ptr(230.0)), sample(40.001, now.Add(time.Second), ptr(230.0))},
+		"gap too long":  {sample(40, now, ptr(230.0)), sample(41, now.Add(time.Hour), ptr(230.0))},
+	} {
+		if implied, diff := estimateSpeedDiscrepancy(history); implied != nil || diff != nil {
+			t.Errorf("%s: got an estimate", name)
+		}
+	}
+}
+
+func TestAltitudeSmoothingStabilizesStatus(t *testing.T) {
+	// Descending around the 3000 m arrival threshold with noisy altitudes
+	altitudes := []float64{2700, 3150, 2650, 3100, 2700}
+	statuses := func(window int) []string {
+		at := newTestTracker(t)
+		at.altitudeSmoothingWindow = window
+		var seen []string
+		for _, altitude := range altitudes {
+			update := testUpdate("abc123", 40.05, -73)
+			update.BaroAltitude = ptr(altitude)
+			update.VerticalRate = ptr(-5.0)
+			track(t, at, update)
+			flight, _ := at.flights.Get("abc123")
+			seen = append(seen, flight.Status)
+		}
+		return seen
+	}
+
+	for i, status := range statuses(4) {
+		if status != StatusArriving {
+			t.Errorf("smoothed sample %d: status %s, want arriving throughout", i, status)
+		}
+	}
+	// Without smoothing the same samples flip the status
+	raw := statuses(1)
+	if raw[1] != StatusNearby || raw[2] != StatusArriving {
+		t.Errorf("unsmoothed statuses = %v, want them to flap", raw)
+	}
+}
+
+func TestSmoothedAltitudeSkipsMissingSamples(t *testing.T) {
+	history := []PositionSample{{Altitude: ptr(1000.0)}, {}, {Altitude: ptr(2000.0)}, {}}
+	if altitude, ok := smoothedAltitude(history, 3); !ok || altitude != 2000 {
+		t.Errorf("window 3 = %g, %v; want 2000", altitude, ok)
+	}
+	if altitude, ok := smoothedAltitude(history, 10); !ok || altitude != 1500 {
+		t.Errorf("window 10 = %g, %v; want 1500", altitude, ok)
+	}
+	if _, ok := smoothedAltitude(history[3:], 1); ok {
+		t.Error("sample without altitude reported one")
+	}
+}
+
+func TestInterarrivalStatsFromKnownIntervals(t *testing.T) {
+	at := newTestTracker(t)
+	clock := time.Now()
+	at.clock = func() time.Time { return clock }
+	for _, gap := range []time.Duration{0, 2 * time.Second, 2 * time.Second, 8 * time.Second} {
+		clock = clock.Add(gap)
+		update := testUpdate("abc123", 40.05, -73)
+		update.TimePosition, update.LastContact = clock.Unix(), clock.Unix()
+		track(t, at, update)
+	}
+
+	var flight TrackedFlight
+	decodeBody(t, call(at.handleFlightDetail, http.MethodGet, "/api/v1/flights/ABC123", map[string]string{"icao24": "ABC123"}), &flight)
+	want := InterarrivalStats{LastSeconds: 8, MeanSeconds: 4, Intervals: 3}
+	if flight.Interarrival == nil || *flight.Interarrival != want {
+		t.Errorf("interarrival = %+v, want %+v", flight.Interarrival, want)
+	}
+}
+
+func TestInterarrivalIgnoresBackwardsTime(t *testing.T) {
+	now := time.Now()
+	prev := &InterarrivalStats{LastSeconds: 5, MeanSeconds: 5, Intervals: 1}
+	if next := nextInterarrival(prev, now, now.Add(-time.Second)); next != prev {
+		t.Errorf("stats changed to %+v for an earlier update", next)
+	}
+	if first := nextInterarrival(nil, now, now.Add(3*time.Second)); *first != (InterarrivalStats{LastSeconds: 3, MeanSeconds: 3, Intervals: 1}) {
+		t.Errorf("first interval = %+v", first)
+	}
+}
+
+func TestAppendSampleKeepsMostRecent(t *testing.T) {
+	var history []PositionSample
+	for i := 0; i < 7; i++ {
+		previous := history
+		history = appendSample(history, PositionSample{Latitude: float64(i)}, 3)
+		if len(previous) == 3 && previous[0].Latitude != float64(i-3) {
+			t.Fatal("appendSample modified the previous history")
+		}
+	}
+	if len(history) != 3 || history[0].Latitude != 4 || history[2].Latitude != 6 {
+		t.Errorf("history = %+v, want samples 4, 5 and 6", history)
+	}
+}
+
+func TestFlightTrackReturnsLastSamplesInOrder(t *testing.T) {
+	t.Setenv("HISTORY_LENGTH", "5")
+	at := newTestTracker(t)
+	for i := 0; i < 8; i++ {
+		update := testUpdate("abc123", 40+float64(i)*0.01, -73)
+		update.BaroAltitude = ptr(1000 + float64(i)*100)
+		track(t, at, update)
+	}
+
+	rec := call(at.handleFlightTrack, http.MethodGet, "/api/v1/flights/ABC123/track", map[string]string{"icao24": "ABC123"})
+	var body struct {
+		Count int              `json:"count"`
+		Track []PositionSample `json:"track"`
+	}
+	decodeBody(t, rec, &body)
+	if body.Count != 5 || len(body.Track) != 5 {
+		t.Fatalf("count = %d with %d samples, want the 5 most recent", body.Count, len(body.Track))
+	}
+	for i, sample := range body.Track {
+		want := 1000 + float64(i+3)*100
+		if sample.Altitude == nil || *sample.Altitude != want || sample.Timestamp.IsZero() {
+			t.Errorf("sample %d = %+v, want altitude %v", i, sample, want)
+		}
+		if math.Abs(sample.Latitude-(40+float64(i+3)*0.01)) > 1e-9 {
+			t.Errorf("sample %d latitude = %v", i, sample.Latitude)
+		}
+	}
+
+	if rec := call(at.handleFlightTrack, http.MethodGet, "/api/v1/flights/fff000/track", map[string]string{"icao24": "fff000"}); rec.Code != http.StatusNotFound {
+		t.Errorf("untracked flight code = %d, want 404", rec.Code)
+	}
+}
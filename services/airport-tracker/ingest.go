@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const defaultBadPayloadPrefixBytes = 256
+
+// ingestQueue decouples handleFlightUpdate from processFlightUpdate. It is
+// bounded so overload shows up as dropped (429) requests rather than
+// unbounded memory growth.
+type ingestQueue struct {
+	updates chan queuedUpdate
+	dropped atomic.Uint64
+	done    chan struct{}
+	stopped chan struct{}
+	// drainCtx bounds the drain on Stop; it is set before done is closed
+	drainCtx context.Context
+
+	// With coalescing, the worker takes up to coalesceBatch queued updates
+	// at a time and applies only the latest per ICAO24, all in one store
+	// write (see processFlightUpdates), and stale flights
+	// are swept between batches when the sweeper signals sweeps.
+	coalesceBatch int
+	coalesced     atomic.Uint64 // updates superseded within a batch
+	sweeps        chan time.Time
+}
+
+// queuedUpdate carries the submitting request's trace with the update
+type queuedUpdate struct {
+	ctx    context.Context
+	update FlightUpdate
+}
+
+// startIngestQueue enables asynchronous ingestion with a single worker
+// draining a queue of the given capacity. A coalesceBatch above 1 enables
+// coalescing; see ingestQueue.
+func (at *AirportTracker) startIngestQueue(size, coalesceBatch int) {
+	q := &ingestQueue{
+		updates:       make(chan queuedUpdate, size),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+		coalesceBatch: coalesceBatch,
+	}
+	if q.coalescing() {
+		q.sweeps = make(chan time.Time, 1)
+	}
+	at.queue = q
+
+	go func() {
+		defer close(q.stopped)
+		for {
+			// Once stopping, queued updates are only taken by the bounded drain
+			select {
+			case <-q.done:
+				at.drainQueue()
+				return
+			default:
+			}
+			select {
+			case queued := <-q.updates:
+				at.processNext(queued)
+			case now := <-q.sweeps:
+				at.sweepStale(now)
+			case <-q.done:
+				at.drainQueue()
+				return
+			}
+		}
+	}()
+	slog.Info("asynchronous ingestion enabled", "queue_size", size, "coalesce_batch", coalesceBatch)
+}
+
+// processNext processes an update taken off the queue, coalescing it with
+// the updates behind it when enabled
+func (at *AirportTracker) processNext(queued queuedUpdate) {
+	if !at.queue.coalescing() {
+		at.processQueued(queued)
+		return
+	}
+	at.processQueued(at.queue.coalesce(queued)...)
+}
+
+// drainQueue processes the updates still queued at shutdown, which were
+// already acknowledged, until the queue is empty or the drain context ends.
+// Whatever is left then is discarded.
+func (at *AirportTracker) drainQueue() {
+	q := at.queue
+	for {
+		if err := q.drainCtx.Err(); err != nil {
+			slog.Warn("shutdown deadline reached, discarding queued updates", "discarded", len(q.updates), "error", err)
+			return
+		}
+		select {
+		case queued := <-q.updates:
+			at.processNext(queued)
+		default:
+			return
+		}
+	}
+}
+
+// processQueued processes updates taken off the queue as one batch, so a
+// coalesced batch is a single store write
+func (at *AirportTracker) processQueued(batch ...queuedUpdate) {
+	for _, p := range at.processFlightUpdates(batch) {
+		if p.err != nil {
+			slog.Warn("queued update failed", "icao24", p.update.ICAO24, "error", p.err)
+		}
+	}
+}
+
+func (q *ingestQueue) coalescing() bool { return q.coalesceBatch > 1 }
+
+// coalesce drains up to coalesceBatch updates starting with first, without
+// waiting for more, and returns the last one received per ICAO24 in the
+// order each flight was first seen
+func (q *ingestQueue) coalesce(first queuedUpdate) []queuedUpdate {
+	batch := []queuedUpdate{first}
+	index := map[string]int{normalizeICAO24(first.update.ICAO24): 0}
+	for received := 1; received < q.coalesceBatch; received++ {
+		var next queuedUpdate
+		select {
+		case next = <-q.updates:
+		default:
+			return batch
+		}
+		key := normalizeICAO24(next.update.ICAO24)
+		if i, ok := index[key]; ok {
+			batch[i] = next
+			q.coalesced.Add(1)
+			continue
+		}
+		index[key] = len(batch)
+		batch = append(batch, next)
+	}
+	return batch
+}
+
+// requestSweep hands a sweep to a coalescing worker, reporting false when
+// the caller should sweep itself. A sweep already pending absorbs this one.
+func (q *ingestQueue) requestSweep(now time.Time) bool {
+	if q == nil || !q.coalescing() {
+		return false
+	}
+	select {
+	case q.sweeps <- now:
+	default:
+	}
+	return true
+}
+
+// Enqueue adds an update without blocking, returning false (and counting a
+// drop) when the queue is full.
+func (q *ingestQueue) Enqueue(ctx context.Context, update FlightUpdate) bool {
+	select {
+	case q.updates <- queuedUpdate{ctx: detachedSpanContext(ctx), update: update}:
+		return true
+	default:
+		q.dropped.Add(1)
+		return false
+	}
+}
+
+// Depth is the number of updates waiting to be processed
+func (q *ingestQueue) Depth() int { return len(q.updates) }
+
+// Capacity is the maximum number of updates the queue holds
+func (q *ingestQueue) Capacity() int { return cap(q.updates) }
+
+// Stop ends the worker once the updates already queued are processed,
+// giving up on the rest when ctx ends, and returns when the worker has exited
+func (q *ingestQueue) Stop(ctx context.Context) {
+	q.drainCtx = ctx
+	close(q.done)
+	<-q.stopped
+}
+
+// badPayloadPrefixFromEnv returns the number of body bytes to log for
+// undecodable updates: DEBUG_BAD_PAYLOADS enables the capture and
+// DEBUG_BAD_PAYLOAD_BYTES overrides the default prefix length.
+func badPayloadPrefixFromEnv() int {
+	if !envBool("DEBUG_BAD_PAYLOADS", false) {
+		return 0
+	}
+	return envInt("DEBUG_BAD_PAYLOAD_BYTES", defaultBadPayloadPrefixBytes)
+}
+
+// logRejectedBody logs a bounded, sanitized prefix of a body that failed to
+// decode, along with the sender, to help identify a misbehaving publisher.
+func (at *AirportTracker) logRejectedBody(r *http.Request, stage string, body []byte, err error) {
+	if at.badPayloadPrefixBytes <= 0 {
+		return
+	}
+	slog.Warn("rejected payload", "remote_addr", r.RemoteAddr, "stage", stage, "bytes", len(body),
+		"error", err, "prefix", sanitizedPrefix(body, at.badPayloadPrefixBytes))
+}
+
+// sanitizedPrefix returns at most limit bytes of body with control and
+// invalid UTF-8 characters replaced, so the log line stays a single,
+// printable line.
+func sanitizedPrefix(body []byte, limit int) string {
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+	var b strings.Builder
+	for len(body) > 0 {
+		r, size := utf8.DecodeRune(body)
+		body = body[size:]
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			b.WriteRune('.')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if truncated {
+		b.WriteString("…")
+	}
+	return b.String()
+}
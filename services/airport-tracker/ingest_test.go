@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForIngest waits until the queue worker has accounted for n updates,
+// either by processing them or by coalescing them away
+func waitForIngest(t testing.TB, at *AirportTracker, n uint64) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for at.stats.updatesProcessed.Load()+at.queue.coalesced.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("ingested %d of %d updates", at.stats.updatesProcessed.Load()+at.queue.coalesced.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Run with -race: writers, the coalescing worker, sweeps and readers all
+// touch the store concurrently
+func TestCoalescedIngestKeepsLastWriterPerFlight(t *testing.T) {
+	const writers, perWriter = 8, 200
+	at := newTestTracker(t)
+	at.startIngestQueue(writers*perWriter, 32)
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				at.flights.Snapshot()
+				at.queue.requestSweep(time.Now())
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			icao24 := fmt.Sprintf("c0ffe%d", w)
+			for i := 0; i < perWriter; i++ {
+				if !at.queue.Enqueue(context.Background(), testUpdate(icao24, 40+float64(i)*0.0001, -73)) {
+					t.Error("queue full")
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	waitForIngest(t, at, writers*perWriter)
+	close(stop)
+	readers.Wait()
+
+	want := 40 + float64(perWriter-1)*0.0001
+	for w := 0; w < writers; w++ {
+		icao24 := fmt.Sprintf("c0ffe%d", w)
+		flight, ok := at.flights.Get(icao24)
+		if !ok {
+			t.Errorf("%s not tracked", icao24)
+			continue
+		}
+		if flight.Latitude != want {
+			t.Errorf("%s latitude = %v, want the last update's %v", icao24, flight.Latitude, want)
+		}
+	}
+}
+
+func TestApplyWritesBatchUnderOneLock(t *testing.T) {
+	store := newFlightStore(4)
+	var order []string
+	writes := []flightWrite{
+		{key: "a", fn: func(*TrackedFlight) *TrackedFlight { return &TrackedFlight{Status: StatusNearby} }},
+		{key: "b", fn: func(*TrackedFlight) *TrackedFlight { return &TrackedFlight{Status: StatusArriving} }},
+		{key: "a", remove: true, done: func(prev, _ *TrackedFlight) {
+			if prev == nil || prev.Status != StatusNearby {
+				t.Errorf("removed %v, want the flight stored earlier in the batch", prev)
+			}
+			order = append(order, "a")
+		}},
+		{key: "c", fn: func(*TrackedFlight) *TrackedFlight { return nil }, done: func(prev, next *TrackedFlight) {
+			if prev != nil || next != nil {
+				t.Errorf("no-op write reported %v -> %v", prev, next)
+			}
+			order = append(order, "c")
+		}},
+	}
+	store.Apply(writes)
+	if store.Len() != 1 {
+		t.Errorf("Len = %d, want 1", store.Len())
+	}
+	if fmt.Sprint(order) != "[a c]" {
+		t.Errorf("done order = %v", order)
+	}
+}
+
+// BenchmarkIngest compares updates processed one at a time by the queue
+// worker with the same updates coalesced into batches
+func BenchmarkIngest(b *testing.B) {
+	const aircraft = 500
+	updates := make([]FlightUpdate, aircraft)
+	for i := range updates {
+		updates[i] = testUpdate(fmt.Sprintf("b%05d", i), 40+float64(i%50)*0.001, -73)
+	}
+	for _, bench := range []struct {
+		name  string
+		batch int
+	}{{"direct", 0}, {"coalesced", 64}} {
+		b.Run(bench.name, func(b *testing.B) {
+			at := newTestTracker(b)
+			at.startIngestQueue(b.N, bench.batch)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				at.queue.Enqueue(context.Background(), updates[i%aircraft])
+			}
+			waitForIngest(b, at, uint64(b.N))
+		})
+	}
+}
+
+func TestFullQueueRefusesWith429(t *testing.T) {
+	at := newTestTracker(t)
+	// A queue with no worker, so nothing drains it and Close has no worker
+	// to wait for
+	stopped := make(chan struct{})
+	close(stopped)
+	at.queue = &ingestQueue{updates: make(chan queuedUpdate, 2), done: make(chan struct{}), stopped: stopped}
+
+	post := func(icao24 string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(testUpdate(icao24, 40.05, -73))
+		rec := httptest.NewRecorder()
+		at.handleFlightUpdate(rec, httptest.NewRequest(http.MethodPost, flightUpdateRoute, bytes.NewReader(body)))
+		return rec
+	}
+	for _, icao24 := range []string{"aaa001", "aaa002"} {
+		if rec := post(icao24); rec.Code != http.StatusOK || decodeAck(t, rec)["outcome"] != OutcomeQueued {
+			t.Fatalf("%s: code %d, body %s; want queued", icao24, rec.Code, rec.Body)
+		}
+	}
+	rec := post("aaa003")
+	if rec.Code != http.StatusTooManyRequests || decodeAck(t, rec)["status"] != DaprRetry {
+		t.Fatalf("full queue: code %d, body %s; want 429 RETRY", rec.Code, rec.Body)
+	}
+
+	var stats struct {
+		Queue struct {
+			Depth    int    `json:"depth"`
+			Capacity int    `json:"capacity"`
+			Dropped  uint64 `json:"dropped"`
+		} `json:"queue"`
+	}
+	decodeBody(t, call(at.handleIngestStats, http.MethodGet, "/api/v1/ingest/stats", nil), &stats)
+	if stats.Queue.Depth != 2 || stats.Queue.Capacity != 2 || stats.Queue.Dropped != 1 {
+		t.Errorf("queue stats = %+v, want depth 2 of 2 with 1 dropped", stats.Queue)
+	}
+
+	metrics := httptest.NewRecorder()
+	at.metrics.Handler().ServeHTTP(metrics, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{"airport_tracker_ingest_queue_depth 2", "airport_tracker_ingest_queue_dropped_total 1"} {
+		if !strings.Contains(metrics.Body.String(), line) {
+			t.Errorf("metrics missing %q", line)
+		}
+	}
+}
+
+// pausedQueueTracker returns a tracker whose queue worker is held inside
+// the first of the given updates, with the rest still queued, and a func to
+// let it continue
+func pausedQueueTracker(t *testing.T, updates ...FlightUpdate) (*AirportTracker, func()) {
+	at := newTestTracker(t)
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	exact := at.geofenceDistance
+	at.geofenceDistance = func(lat1, lon1, lat2, lon2 float64) float64 {
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-release
+		return exact(lat1, lon1, lat2, lon2)
+	}
+	at.startIngestQueue(len(updates), 0)
+	for _, update := range updates {
+		if !at.queue.Enqueue(context.Background(), update) {
+			t.Fatal("queue full")
+		}
+	}
+	<-entered
+	return at, func() { close(release) }
+}
+
+func TestShutdownDrainsQueuedUpdates(t *testing.T) {
+	at, resume := pausedQueueTracker(t, testUpdate("aaa001", 40.05, -73), testUpdate("aaa002", 40.05, -73), testUpdate("aaa003", 40.05, -73))
+	done := make(chan struct{})
+	go func() {
+		at.Shutdown(context.Background())
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond) // let Shutdown signal the worker
+	resume()
+	<-done
+
+	for _, icao24 := range []string{"aaa001", "aaa002", "aaa003"} {
+		if _, ok := at.flights.Get(icao24); !ok {
+			t.Errorf("%s was acknowledged as queued but not processed before shutdown", icao24)
+		}
+	}
+}
+
+func TestShutdownGivesUpDrainingWhenContextEnds(t *testing.T) {
+	at, resume := pausedQueueTracker(t, testUpdate("aaa001", 40.05, -73), testUpdate("aaa002", 40.05, -73))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		at.Shutdown(ctx)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond) // let Shutdown signal the worker
+	resume()
+	<-done
+
+	if _, ok := at.flights.Get("aaa002"); ok {
+		t.Error("queued update processed after the shutdown context ended")
+	}
+}
+
+func TestRejectedBodyPrefixIsBoundedAndLogged(t *testing.T) {
+	body := "{\"icao24\":\n\"abc123\",\"latitude\":40.0"
+	post := func(at *AirportTracker) {
+		rec := httptest.NewRecorder()
+		at.handleFlightUpdate(rec, httptest.NewRequest(http.MethodPost, flightUpdateRoute, strings.NewReader(body)))
+	}
+
+	at := newTestTracker(t)
+	logs := captureLogs(t, "warn")
+	post(at)
+	if strings.Contains(logs.String(), "rejected payload") {
+		t.Fatalf("payload logged without DEBUG_BAD_PAYLOADS: %s", logs)
+	}
+
+	at.badPayloadPrefixBytes = 16
+	post(at)
+	var entry struct {
+		Msg    string `json:"msg"`
+		Bytes  int    `json:"bytes"`
+		Prefix string `json:"prefix"`
+	}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if err := json.Unmarshal([]byte(line), &entry); err == nil && entry.Msg == "rejected payload" {
+			break
+		}
+	}
+	if entry.Msg != "rejected payload" {
+		t.Fatalf("no rejected payload log in %s", logs)
+	}
+	if want := "{\"icao24\":.\"abc1…"; entry.Prefix != want {
+		t.Errorf("prefix = %q, want %q", entry.Prefix, want)
+	}
+	if entry.Bytes != len(body) {
+		t.Errorf("bytes = %d, want %d", entry.Bytes, len(body))
+	}
+}
+
+func TestSanitizedPrefixReplacesInvalidUTF8(t *testing.T) {
+	if got, want := sanitizedPrefix([]byte("ok\xff\x00é"), 10), "ok..é"; got != want {
+		t.Errorf("sanitizedPrefix = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxResponseFlights = 1000
+	defaultPageLimit          = 100
+	maxPageLimit              = 500
+)
+
+// Unit systems accepted by ?units=
+const (
+	UnitsMetric   = "metric"   // meters, m/s
+	UnitsImperial = "imperial" // feet, knots, feet per minute
+)
+
+// Conversion factors from the feed's SI units
+const (
+	metersToFeet   = 3.28084
+	msToKnots      = 1.943844
+	msToFeetPerMin = 196.8504
+)
+
+// Coordinate output formats accepted by ?coord_format=
+const (
+	CoordFormatDecimal = "decimal"
+	CoordFormatDMS     = "dms"
+	CoordFormatString  = "string"
+)
+
+// listOptions are the per-request presentation options shared by the
+// flight list endpoints
+type listOptions struct {
+	expandAirport bool
+	coordFormat   string
+	units         string
+	limit         int
+
+	// Filters from ?country=, ?callsign_prefix= and ?max_age=, combined
+	// with AND. maxAge is zero when unset.
+	country        string
+	callsignPrefix string
+	maxAge         time.Duration
+
+	// now is the request time that ages are computed against
+	now time.Time
+}
+
+// parseListOptions reads the presentation query parameters, rejecting
+// unknown values so client typos are not silently ignored. ?max= lowers the
+// response cap for a request but can never raise it above the configured
+// MAX_RESPONSE_FLIGHTS.
+func (at *AirportTracker) parseListOptions(r *http.Request) (listOptions, error) {
+	query := r.URL.Query()
+	opts := listOptions{
+		expandAirport: wantsExpansion(r, "airport"),
+		coordFormat:   strings.ToLower(query.Get("coord_format")),
+		units:         strings.ToLower(query.Get("units")),
+		limit:         at.maxResponseFlights,
+
+		country:        strings.TrimSpace(query.Get("country")),
+		callsignPrefix: strings.ToUpper(strings.TrimSpace(query.Get("callsign_prefix"))),
+		now:            at.now(),
+	}
+
+	if raw := query.Get("max_age"); raw != "" {
+		seconds, err := strconv.ParseFloat(raw, 64)
+		if err != nil || seconds <= 0 {
+			return opts, fmt.Errorf("invalid max_age %q: expected a positive number of seconds", raw)
+		}
+		opts.maxAge = time.Duration(seconds * float64(time.Second))
+	}
+
+	if raw := query.Get("max"); raw != "" {
+		max, err := strconv.Atoi(raw)
+		if err != nil || max < 1 {
+			return opts, fmt.Errorf("invalid max %q: expected a positive integer", raw)
+		}
+		if opts.limit <= 0 || max < opts.limit {
+			opts.limit = max
+		}
+	}
+
+	switch opts.coordFormat {
+	case "":
+		opts.coordFormat = CoordFormatDecimal
+	case CoordFormatDecimal, CoordFormatDMS, CoordFormatString:
+	default:
+		return opts, fmt.Errorf("invalid coord_format %q: expected decimal, dms or string", opts.coordFormat)
+	}
+
+	switch opts.units {
+	case "":
+		opts.units = UnitsMetric
+	case UnitsMetric, UnitsImperial:
+	default:
+		return opts, fmt.Errorf("invalid units %q: expected metric or imperial", opts.units)
+	}
+	return opts, nil
+}
+
+// matches reports whether flight passes the request's filters. Country is
+// compared case-insensitively, the callsign prefix ignores padding, and a
+// flight exactly max_age old is still included.
+func (opts listOptions) matches(flight *TrackedFlight) bool {
+	if opts.maxAge > 0 && flightAge(flight, opts.now) > opts.maxAge {
+		return false
+	}
+	if opts.country != "" && !strings.EqualFold(strings.TrimSpace(flight.OriginCountry), opts.country) {
+		return false
+	}
+	if opts.callsignPrefix != "" && !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(flight.Callsign)), opts.callsignPrefix) {
+		return false
+	}
+	return true
+}
+
+// decorateFlights applies presentation options to flights about to be
+// encoded. Only the response copies change; stored state is untouched.
+func (at *AirportTracker) decorateFlights(flights []TrackedFlight, opts listOptions) {
+	for i := range flights {
+		age := flightAge(&flights[i], opts.now).Seconds()
+		flights[i].AgeSeconds = &age
+	}
+	if opts.expandAirport {
+		at.expandAirports(flights)
+	}
+	if opts.coordFormat != CoordFormatDecimal {
+		for i := range flights {
+			flights[i].Position = formatPosition(flights[i].Latitude, flights[i].Longitude, opts.coordFormat)
+		}
+	}
+	if opts.units == UnitsImperial {
+		for i := range flights {
+			toImperial(&flights[i])
+		}
+	}
+}
+
+// toImperial converts a response copy's altitudes to feet, velocity to
+// knots and vertical rate to feet per minute. The pointers are replaced,
+// never written through, because they are shared with the stored flight.
+func toImperial(flight *TrackedFlight) {
+	flight.BaroAltitude = scaled(flight.BaroAltitude, metersToFeet)
+	flight.GeoAltitude = scaled(flight.GeoAltitude, metersToFeet)
+	flight.Velocity = scaled(flight.Velocity, msToKnots)
+	flight.VerticalRate = scaled(flight.VerticalRate, msToFeetPerMin)
+}
+
+func scaled(v *float64, factor float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	out := *v * factor
+	return &out
+}
+
+// truncation records whether a list response was capped
+type truncation struct {
+	truncated bool
+	total     int
+}
+
+// annotate adds the truncation indicator, and the number of flights that
+// were available when the cap was hit, to a response envelope
+func (t truncation) annotate(response map[string]interface{}) {
+	response["truncated"] = t.truncated
+	if t.truncated {
+		response["total"] = t.total
+	}
+}
+
+// capFlights returns at most limit flights (all when limit <= 0). When the
+// cap applies the flights are first ordered by ICAO24 so the same subset is
+// returned across requests.
+func capFlights(flights []TrackedFlight, limit int) ([]TrackedFlight, truncation) {
+	if limit <= 0 || len(flights) <= limit {
+		return flights, truncation{total: len(flights)}
+	}
+	sort.Slice(flights, func(i, j int) bool { return flights[i].ICAO24 < flights[j].ICAO24 })
+	return truncateFlights(flights, limit)
+}
+
+// truncateFlights keeps the first limit flights of an already ordered list
+func truncateFlights(flights []TrackedFlight, limit int) ([]TrackedFlight, truncation) {
+	if limit <= 0 || len(flights) <= limit {
+		return flights, truncation{total: len(flights)}
+	}
+	return flights[:limit], truncation{truncated: true, total: len(flights)}
+}
+
+// Sort keys accepted by ?sort= on the arrivals and departures endpoints; a
+// leading "-" on distance reverses the order
+const (
+	SortDistance     = "distance"
+	SortDistanceDesc = "-distance"
+	SortAltitude     = "altitude"
+	SortCallsign     = "callsign"
+)
+
+// parseSortKey reads ?sort=, defaulting to closest first
+func parseSortKey(r *http.Request) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("sort")))
+	switch key {
+	case "":
+		return SortDistance, nil
+	case SortDistance, SortDistanceDesc, SortAltitude, SortCallsign:
+		return key, nil
+	}
+	return "", fmt.Errorf("invalid sort %q: expected distance, -distance, altitude or callsign", key)
+}
+
+// sortFlights orders flights by key, breaking ties by ICAO24. Flights
+// without an altitude sort after those with one.
+func sortFlights(flights []TrackedFlight, key string) {
+	sort.SliceStable(flights, func(i, j int) bool {
+		a, b := &flights[i], &flights[j]
+		switch key {
+		case SortDistance, SortDistanceDesc:
+			if a.DistanceKm != b.DistanceKm {
+				return (a.DistanceKm < b.DistanceKm) == (key == SortDistance)
+			}
+		case SortAltitude:
+			altA, okA := effectiveAltitude(a.FlightUpdate)
+			altB, okB := effectiveAltitude(b.FlightUpdate)
+			if okA != okB {
+				return okA
+			}
+			if altA != altB {
+				return altA < altB
+			}
+		case SortCallsign:
+			ca := strings.ToUpper(strings.TrimSpace(a.Callsign))
+			cb := strings.ToUpper(strings.TrimSpace(b.Callsign))
+			if ca != cb {
+				return ca < cb
+			}
+		}
+		return a.ICAO24 < b.ICAO24
+	})
+}
+
+// page is a ?limit=&offset= window over a list response
+type page struct {
+	limit  int
+	offset int
+}
+
+// parsePage reads ?limit= (default 100, at most 500) and ?offset= (default 0)
+func parsePage(r *http.Request) (page, error) {
+	query := r.URL.Query()
+	p := page{limit: defaultPageLimit}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return p, fmt.Errorf("invalid limit %q: expected 1 to %d", raw, maxPageLimit)
+		}
+		p.limit = limit
+	}
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return p, fmt.Errorf("invalid offset %q: expected a non-negative integer", raw)
+		}
+		p.offset = offset
+	}
+	return p, nil
+}
+
+// paginate orders flights by ICAO24 so pages are stable across requests and
+// returns the window selected by p. An offset past the end yields no flights.
+func paginate(flights []TrackedFlight, p page) []TrackedFlight {
+	sort.Slice(flights, func(i, j int) bool { return flights[i].ICAO24 < flights[j].ICAO24 })
+	if p.offset >= len(flights) {
+		return []TrackedFlight{}
+	}
+	end := p.offset + p.limit
+	if end > len(flights) {
+		end = len(flights)
+	}
+	return flights[p.offset:end]
+}
+
+// wantsExpansion reports whether ?expand= lists the given field (comma-separated)
+func wantsExpansion(r *http.Request, field string) bool {
+	for _, v := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), field) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandAirports embeds the matching airport config in each flight
+func (at *AirportTracker) expandAirports(flights []TrackedFlight) {
+	airports := at.airportList()
+	byCode := make(map[string]*AirportConfig, len(airports))
+	for i := range airports {
+		byCode[airports[i].ICAO] = &airports[i]
+	}
+	for i := range flights {
+		if airport, ok := byCode[flights[i].AirportCode]; ok {
+			embedded := *airport
+			flights[i].Airport = &embedded
+		}
+	}
+}
+
+// formatPosition renders a position as degrees-minutes-seconds
+// (40°38'28.7"N 73°46'41.2"W) or as a combined "lat,lon" string
+func formatPosition(lat, lon float64, format string) string {
+	switch format {
+	case CoordFormatDMS:
+		return formatDMS(lat, "N", "S") + " " + formatDMS(lon, "E", "W")
+	case CoordFormatString:
+		return fmt.Sprintf("%.6f,%.6f", lat, lon)
+	}
+	return ""
+}
+
+func formatDMS(value float64, positive, negative string) string {
+	hemisphere := positive
+	if value < 0 {
+		hemisphere = negative
+	}
+	// Work in tenths of an arc-second so rounding never yields 60 seconds
+	tenths := int64(math.Round(math.Abs(value) * 36000))
+	degrees := tenths / 36000
+	minutes := tenths % 36000 / 600
+	seconds := float64(tenths%600) / 10
+	return fmt.Sprintf("%d°%02d'%04.1f\"%s", degrees, minutes, seconds, hemisphere)
+}
+
+// airportSelection is the set of airports a list request covers
+type airportSelection map[string]bool
+
+// Codes returns the selected airport codes in sorted order
+func (s airportSelection) Codes() []string {
+	codes := make([]string, 0, len(s))
+	for code := range s {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// errUnknownAirport marks a request for an airport that is not configured
+var errUnknownAirport = errors.New("unknown airport codes")
+
+// parseAirportSelection resolves the airports for a per-airport endpoint.
+// The {code} path segment and ?airports= both accept comma-separated codes,
+// and ?exclude= removes codes from the result. Every code must be a
+// configured airport.
+func (at *AirportTracker) parseAirportSelection(r *http.Request, pathCodes string) (airportSelection, error) {
+	airports := at.airportList()
+	known := make(map[string]bool, len(airports))
+	for _, airport := range airports {
+		known[strings.ToUpper(airport.ICAO)] = true
+	}
+
+	var unknown []string
+	split := func(raw string) []string {
+		var codes []string
+		for _, code := range strings.Split(raw, ",") {
+			code = strings.ToUpper(strings.TrimSpace(code))
+			if code == "" {
+				continue
+			}
+			if !known[code] {
+				unknown = append(unknown, code)
+				continue
+			}
+			codes = append(codes, code)
+		}
+		return codes
+	}
+
+	query := r.URL.Query()
+	selection := airportSelection{}
+	for _, code := range split(pathCodes + "," + query.Get("airports")) {
+		selection[code] = true
+	}
+	for _, code := range split(query.Get("exclude")) {
+		delete(selection, code)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", errUnknownAirport, strings.Join(unknown, ", "))
+	}
+	if len(selection) == 0 {
+		return nil, fmt.Errorf("no airports selected")
+	}
+	return selection, nil
+}
+
+// writeSelectionError replies to a failed parseAirportSelection: 404 for
+// codes that are not configured, so typos are not mistaken for quiet
+// airports, and 400 otherwise
+func (at *AirportTracker) writeSelectionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnknownAirport) {
+		at.metrics.unknownAirports.Inc()
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// altitudeBand is an inclusive altitude window in meters from ?min_alt= and
+// ?max_alt=; either bound may be open
+type altitudeBand struct {
+	min, max *float64
+}
+
+// parseAltitudeBand reads ?min_alt= and ?max_alt=
+func parseAltitudeBand(r *http.Request) (altitudeBand, error) {
+	var band altitudeBand
+	for _, bound := range []struct {
+		name string
+		dst  **float64
+	}{{"min_alt", &band.min}, {"max_alt", &band.max}} {
+		raw := r.URL.Query().Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return band, fmt.Errorf("invalid %s %q: expected meters", bound.name, raw)
+		}
+		*bound.dst = &value
+	}
+	if band.min != nil && band.max != nil && *band.min > *band.max {
+		return band, fmt.Errorf("invalid altitude band: min_alt %g is above max_alt %g", *band.min, *band.max)
+	}
+	return band, nil
+}
+
+// contains reports whether flight's effective altitude is within the band.
+// With a bound set, flights reporting no altitude are excluded.
+func (b altitudeBand) contains(flight *TrackedFlight) bool {
+	if b.min == nil && b.max == nil {
+		return true
+	}
+	altitude, ok := effectiveAltitude(flight.FlightUpdate)
+	if !ok {
+		return false
+	}
+	return (b.min == nil || altitude >= *b.min) && (b.max == nil || altitude <= *b.max)
+}
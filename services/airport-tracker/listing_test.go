@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllFlightsPagesReportTruncation(t *testing.T) {
+	at := newTestTracker(t)
+	for i := 0; i < 5; i++ {
+		if _, err := at.processFlightUpdate(context.Background(), testUpdate(fmt.Sprintf("abc%03d", i), 40.05, -73)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct {
+		query     string
+		count     int
+		truncated bool
+	}{
+		{"?limit=2", 2, true},
+		{"?limit=2&offset=2", 2, true},
+		{"?limit=2&offset=4", 1, false},
+		{"", 5, false},
+	} {
+		rec := httptest.NewRecorder()
+		at.handleAllFlights(rec, httptest.NewRequest(http.MethodGet, "/api/v1/flights/all"+tc.query, nil))
+		var body struct {
+			Count     int  `json:"count"`
+			Total     int  `json:"total"`
+			Truncated bool `json:"truncated"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v", tc.query, err)
+		}
+		if body.Count != tc.count || body.Total != 5 || body.Truncated != tc.truncated {
+			t.Errorf("%q: count=%d total=%d truncated=%v, want %d, 5, %v",
+				tc.query, body.Count, body.Total, body.Truncated, tc.count, tc.truncated)
+		}
+	}
+}
+
+func TestExpandAirportEmbedsConfig(t *testing.T) {
+	at := newTestTracker(t)
+	track(t, at, testUpdate("abc123", 40.05, -73))
+
+	for _, tc := range []struct {
+		query    string
+		embedded bool
+	}{{"?expand=airport", true}, {"?expand=history,AIRPORT", true}, {"", false}} {
+		rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/KTST/nearby"+tc.query, map[string]string{"code": "KTST"})
+		var body struct {
+			Flights []TrackedFlight `json:"flights"`
+		}
+		decodeBody(t, rec, &body)
+		if len(body.Flights) != 1 {
+			t.Fatalf("%q: %d flights, want 1", tc.query, len(body.Flights))
+		}
+		airport := body.Flights[0].Airport
+		if !tc.embedded {
+			if airport != nil {
+				t.Errorf("%q: airport embedded without being asked for", tc.query)
+			}
+			continue
+		}
+		if airport == nil || airport.ICAO != "KTST" || airport.Name != "KTST Test" || airport.Latitude != 40 || airport.Longitude != -73 {
+			t.Errorf("%q: embedded airport = %+v", tc.query, airport)
+		}
+	}
+}
+
+func TestCoordFormats(t *testing.T) {
+	at := newTestTracker(t)
+	track(t, at, testUpdate("abc123", 40.2575, -73.25))
+
+	for _, tc := range []struct {
+		format   string
+		position string
+	}{
+		{"", ""},
+		{"decimal", ""},
+		{"DMS", `40°15'27.0"N 73°15'00.0"W`},
+		{"string", "40.257500,-73.250000"},
+	} {
+		rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/KTST/nearby?coord_format="+tc.format, map[string]string{"code": "KTST"})
+		var body struct {
+			Flights []TrackedFlight `json:"flights"`
+		}
+		decodeBody(t, rec, &body)
+		if len(body.Flights) != 1 {
+			t.Fatalf("%q: %d flights, want 1", tc.format, len(body.Flights))
+		}
+		flight := body.Flights[0]
+		if flight.Position != tc.position {
+			t.Errorf("%q: position = %q, want %q", tc.format, flight.Position, tc.position)
+		}
+		if flight.Latitude != 40.2575 || flight.Longitude != -73.25 {
+			t.Errorf("%q: decimal coordinates changed to %g,%g", tc.format, flight.Latitude, flight.Longitude)
+		}
+	}
+
+	if stored, _ := at.flights.Get("abc123"); stored.Position != "" {
+		t.Errorf("stored flight position = %q, want it left unset", stored.Position)
+	}
+	rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/KTST/nearby?coord_format=utm", map[string]string{"code": "KTST"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown coord_format code = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestFormatDMSNeverRoundsToSixtySeconds(t *testing.T) {
+	if got, want := formatDMS(-0.99999999, "N", "S"), `1°00'00.0"S`; got != want {
+		t.Errorf("formatDMS = %s, want %s", got, want)
+	}
+}
+
+func TestResponseCapSignalsTruncation(t *testing.T) {
+	at := newTestTracker(t)
+	at.maxResponseFlights = 3
+	for i := 0; i < 5; i++ {
+		track(t, at, testUpdate(fmt.Sprintf("abc%03d", i), 40.05, -73))
+	}
+	for _, tc := range []struct {
+		query     string
+		count     int
+		truncated bool
+	}{
+		{"", 3, true},
+		{"?max=2", 2, true},
+		{"?max=10", 3, true}, // ?max= cannot raise the configured cap
+	} {
+		rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/KTST/nearby"+tc.query, map[string]string{"code": "KTST"})
+		var body struct {
+			Flights   []TrackedFlight `json:"flights"`
+			Truncated bool            `json:"truncated"`
+			Total     int             `json:"total"`
+		}
+		decodeBody(t, rec, &body)
+		if len(body.Flights) != tc.count || body.Truncated != tc.truncated || body.Total != 5 {
+			t.Errorf("%q: %d flights, truncated=%v, total=%d; want %d, %v, 5",
+				tc.query, len(body.Flights), body.Truncated, body.Total, tc.count, tc.truncated)
+		}
+	}
+
+	at.maxResponseFlights = 0
+	rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/KTST/nearby", map[string]string{"code": "KTST"})
+	var body struct {
+		Flights   []TrackedFlight `json:"flights"`
+		Truncated bool            `json:"truncated"`
+	}
+	decodeBody(t, rec, &body)
+	if len(body.Flights) != 5 || body.Truncated {
+		t.Errorf("uncapped: %d flights, truncated=%v; want all 5", len(body.Flights), body.Truncated)
+	}
+	if rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/KTST/nearby?max=0", map[string]string{"code": "KTST"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("?max=0 code = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAirportSelectionWithExclusion(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73), testAirport("KCCC", 50, -73))
+	track(t, at, testUpdate("aaa001", 40.05, -73), testUpdate("bbb001", 45.05, -73), testUpdate("ccc001", 50.05, -73))
+
+	for _, tc := range []struct {
+		code, query string
+		codes       string
+		count       int
+	}{
+		{"KAAA", "", "[KAAA]", 1},
+		{"kaaa,KBBB", "", "[KAAA KBBB]", 2},
+		{"KAAA", "?airports=KBBB,kccc", "[KAAA KBBB KCCC]", 3},
+		{"KAAA,KBBB,KCCC", "?exclude=KBBB", "[KAAA KCCC]", 2},
+	} {
+		rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/"+tc.code+"/nearby"+tc.query, map[string]string{"code": tc.code})
+		var body struct {
+			AirportCodes []string `json:"airport_codes"`
+			Count        int      `json:"count"`
+		}
+		decodeBody(t, rec, &body)
+		if fmt.Sprint(body.AirportCodes) != tc.codes || body.Count != tc.count {
+			t.Errorf("%s%s: airports %v with %d flights, want %s with %d", tc.code, tc.query, body.AirportCodes, body.Count, tc.codes, tc.count)
+		}
+	}
+
+	for _, tc := range []struct {
+		code, query string
+		status      int
+	}{
+		{"KAAA,KZZZ", "", http.StatusNotFound},
+		{"KAAA", "?exclude=KZZZ", http.StatusNotFound},
+		{"KAAA", "?exclude=KAAA", http.StatusBadRequest},
+	} {
+		rec := call(at.handleNearby, http.MethodGet, "/api/v1/airports/"+tc.code+"/nearby"+tc.query, map[string]string{"code": tc.code})
+		if rec.Code != tc.status {
+			t.Errorf("%s%s: code = %d, want %d", tc.code, tc.query, rec.Code, tc.status)
+		}
+	}
+}
+
+func TestCountryAndCallsignFiltersCombine(t *testing.T) {
+	at := newTestTracker(t)
+	rates := map[string]float64{"arrivals": -5, "departures": 5, "nearby": 0}
+	aircraft := []struct{ country, callsign string }{
+		{"United States", "UAL100  "},
+		{"united states", "DAL200  "},
+		{"Germany", "ual300  "},
+		{"Germany", "DLH400  "},
+	}
+	i := 0
+	for _, kind := range []string{"arrivals", "departures", "nearby"} {
+		for _, a := range aircraft {
+			// Departures are confirmed by a sustained climb away
+			for n := 0.0; n < 3; n++ {
+				update := testUpdate(fmt.Sprintf("%06x", i), 40.01+n*0.01, -73)
+				update.OriginCountry, update.Callsign = a.country, a.callsign
+				update.BaroAltitude, update.VerticalRate = ptr(1000.0+n*rates[kind]*30), ptr(rates[kind])
+				track(t, at, update)
+			}
+			i++
+		}
+	}
+
+	handlers := map[string]http.HandlerFunc{
+		"arrivals":   at.handleArrivals,
+		"departures": at.handleDepartures,
+		"nearby":     at.handleNearby,
+		"all":        at.handleAllFlights,
+	}
+	listKeys := map[string]string{"arrivals": "arrivals", "departures": "departures", "nearby": "flights", "all": "flights"}
+	for kind, handler := range handlers {
+		for _, tc := range []struct {
+			query string
+			want  int // matches per status
+		}{
+			{"", 4},
+			{"?country=UNITED%20STATES", 2},
+			{"?callsign_prefix=ual", 2},
+			{"?country=united+states&callsign_prefix=UAL", 1},
+			{"?country=Germany&callsign_prefix=DAL", 0},
+		} {
+			rec := call(handler, http.MethodGet, "/"+tc.query, map[string]string{"code": "KTST"})
+			var body map[string]json.RawMessage
+			decodeBody(t, rec, &body)
+			var flights []TrackedFlight
+			json.Unmarshal(body[listKeys[kind]], &flights)
+			// nearby lists every flight in the geofence, whatever its status
+			want := tc.want
+			if kind == "nearby" || kind == "all" {
+				want *= 3
+			}
+			if len(flights) != want {
+				t.Errorf("%s%s: %d flights, want %d", kind, tc.query, len(flights), want)
+			}
+			for _, flight := range flights {
+				if tc.want == 1 && (flight.OriginCountry != "United States" || strings.TrimSpace(flight.Callsign) != "UAL100") {
+					t.Errorf("%s%s: returned %s from %s", kind, tc.query, flight.Callsign, flight.OriginCountry)
+				}
+			}
+		}
+	}
+}
+
+func TestImperialUnitsConvertResponseOnly(t *testing.T) {
+	at := newTestTracker(t)
+	update := testUpdate("abc123", 40.05, -73)
+	update.BaroAltitude, update.GeoAltitude = ptr(1000.0), ptr(1050.0)
+	update.Velocity, update.VerticalRate = ptr(100.0), ptr(-5.0)
+	track(t, at, update)
+
+	for _, tc := range []struct {
+		query                             string
+		units                             string
+		baro, geo, velocity, verticalRate float64
+	}{
+		{"", UnitsMetric, 1000, 1050, 100, -5},
+		{"?units=metric", UnitsMetric, 1000, 1050, 100, -5},
+		{"?units=imperial", UnitsImperial, 3280.84, 3444.882, 194.3844, -984.252},
+	} {
+		rec := call(at.handleArrivals, http.MethodGet, "/"+tc.query, map[string]string{"code": "KTST"})
+		var body struct {
+			Units    string          `json:"units"`
+			Arrivals []TrackedFlight `json:"arrivals"`
+		}
+		decodeBody(t, rec, &body)
+		if body.Units != tc.units || len(body.Arrivals) != 1 {
+			t.Fatalf("%q: units %q with %d arrivals, want %q with 1", tc.query, body.Units, len(body.Arrivals), tc.units)
+		}
+		f := body.Arrivals[0]
+		for _, v := range []struct {
+			name      string
+			got, want float64
+		}{
+			{"baro_altitude", *f.BaroAltitude, tc.baro},
+			{"geo_altitude", *f.GeoAltitude, tc.geo},
+			{"velocity", *f.Velocity, tc.velocity},
+			{"vertical_rate", *f.VerticalRate, tc.verticalRate},
+		} {
+			if math.Abs(v.got-v.want) > 0.01 {
+				t.Errorf("%q: %s = %v, want %v", tc.query, v.name, v.got, v.want)
+			}
+		}
+	}
+
+	stored, _ := at.flights.Get("abc123")
+	if *stored.BaroAltitude != 1000 || *stored.Velocity != 100 || *stored.VerticalRate != -5 {
+		t.Error("imperial response modified the stored flight")
+	}
+	if rec := call(at.handleArrivals, http.MethodGet, "/?units=furlongs", map[string]string{"code": "KTST"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown units code = %d, want 400", rec.Code)
+	}
+}
+
+func TestSortFlightsByEachKey(t *testing.T) {
+	flight := func(icao24, callsign string, distance float64, altitude *float64) TrackedFlight {
+		return TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: icao24, Callsign: callsign, BaroAltitude: altitude}, DistanceKm: distance}
+	}
+	flights := []TrackedFlight{
+		flight("ddd004", "dal4    ", 12, ptr(900.0)),
+		flight("ccc003", "UAL3", 5, nil),
+		flight("aaa001", "AAL1", 20, ptr(300.0)),
+		flight("bbb002", "UAL3", 5, ptr(300.0)),
+	}
+	for _, tc := range []struct {
+		key  string
+		want string
+	}{
+		{SortDistance, "bbb002 ccc003 ddd004 aaa001"},
+		{SortDistanceDesc, "aaa001 ddd004 bbb002 ccc003"},
+		{SortAltitude, "aaa001 bbb002 ddd004 ccc003"}, // no altitude sorts last
+		{SortCallsign, "aaa001 ddd004 bbb002 ccc003"},
+	} {
+		sorted := append([]TrackedFlight(nil), flights...)
+		sortFlights(sorted, tc.key)
+		var order []string
+		for _, f := range sorted {
+			order = append(order, f.ICAO24)
+		}
+		if got := strings.Join(order, " "); got != tc.want {
+			t.Errorf("sort %s: %s, want %s", tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestArrivalsSortedClosestFirstByDefault(t *testing.T) {
+	at := newTestTracker(t)
+	for i, lat := range []float64{40.3, 40.1, 40.2} {
+		update := testUpdate(fmt.Sprintf("abc%03d", i), lat, -73)
+		update.BaroAltitude, update.VerticalRate = ptr(1000.0), ptr(-5.0)
+		track(t, at, update)
+	}
+	for query, want := range map[string]string{
+		"":                "abc001 abc002 abc000",
+		"?sort=-distance": "abc000 abc002 abc001",
+	} {
+		rec := call(at.handleArrivals, http.MethodGet, "/"+query, map[string]string{"code": "KTST"})
+		var body struct {
+			Arrivals []TrackedFlight `json:"arrivals"`
+		}
+		decodeBody(t, rec, &body)
+		var order []string
+		for _, f := range body.Arrivals {
+			order = append(order, f.ICAO24)
+		}
+		if got := strings.Join(order, " "); got != want {
+			t.Errorf("%q: %s, want %s", query, got, want)
+		}
+	}
+	if rec := call(at.handleDepartures, http.MethodGet, "/?sort=speed", map[string]string{"code": "KTST"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown sort code = %d, want 400", rec.Code)
+	}
+}
+
+func TestUnknownAirportCodeIsNotFound(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	track(t, at, testUpdate("aaa001", 40.05, -73))
+	handlers := map[string]http.HandlerFunc{
+		"arrivals":   at.handleArrivals,
+		"departures": at.handleDepartures,
+		"nearby":     at.handleNearby,
+	}
+	for name, handler := range handlers {
+		rec := call(handler, http.MethodGet, "/", map[string]string{"code": "KXXX"})
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "KXXX") {
+			t.Errorf("%s for KXXX: %d %q, want 404 naming the code", name, rec.Code, rec.Body)
+		}
+
+		// A configured airport without flights is an empty list
+		rec = call(handler, http.MethodGet, "/", map[string]string{"code": "kbbb"})
+		var body struct {
+			Count int `json:"count"`
+		}
+		decodeBody(t, rec, &body)
+		if rec.Code != http.StatusOK || body.Count != 0 {
+			t.Errorf("%s for KBBB: %d with %d flights, want 200 and none", name, rec.Code, body.Count)
+		}
+	}
+	if metrics := scrape(t, at); !strings.Contains(metrics, "airport_tracker_unknown_airport_requests_total 3") {
+		t.Error("unknown airport requests not counted")
+	}
+}
+
+func TestFlightAgeAndMaxAgeFilter(t *testing.T) {
+	at := newTestTracker(t)
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	at.clock = func() time.Time { return now }
+	for icao24, age := range map[string]time.Duration{"fresh1": 5 * time.Second, "edge30": 30 * time.Second, "stale9": 31 * time.Second} {
+		storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: icao24}, AirportCode: "KTST", Status: StatusNearby, ObservedAt: now.Add(-age)})
+	}
+
+	ages := func(query string) map[string]float64 {
+		rec := call(at.handleAllFlights, http.MethodGet, "/api/v1/flights/all"+query, nil)
+		var body struct {
+			Flights []TrackedFlight `json:"flights"`
+		}
+		decodeBody(t, rec, &body)
+		got := map[string]float64{}
+		for _, flight := range body.Flights {
+			if flight.AgeSeconds == nil {
+				t.Fatalf("%s: age_seconds missing", flight.ICAO24)
+			}
+			got[flight.ICAO24] = *flight.AgeSeconds
+		}
+		return got
+	}
+
+	all := ages("")
+	if all["fresh1"] != 5 || all["edge30"] != 30 || all["stale9"] != 31 {
+		t.Errorf("ages = %v, want 5, 30 and 31 seconds", all)
+	}
+	// A flight exactly max_age old is still fresh
+	if got := ages("?max_age=30"); len(got) != 2 || got["stale9"] != 0 {
+		t.Errorf("?max_age=30 returned %v, want fresh1 and edge30", got)
+	}
+	if got := ages("?max_age=29.5"); len(got) != 1 || got["fresh1"] != 5 {
+		t.Errorf("?max_age=29.5 returned %v, want fresh1 only", got)
+	}
+	for _, bad := range []string{"0", "-1", "soon"} {
+		if rec := call(at.handleAllFlights, http.MethodGet, "/api/v1/flights/all?max_age="+bad, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("max_age=%s: code = %d, want 400", bad, rec.Code)
+		}
+	}
+}
+
+func TestNearbyAltitudeBand(t *testing.T) {
+	at := newTestTracker(t)
+	for icao24, altitude := range map[string]struct{ baro, geo *float64 }{
+		"low500": {ptr(500.0), nil},
+		"mid1k0": {ptr(1000.0), ptr(1100.0)},
+		"geo2k0": {nil, ptr(2000.0)}, // geometric altitude stands in
+		"high3k": {ptr(3000.0), nil},
+		"noalt0": {nil, nil},
+	} {
+		update := testUpdate(icao24, 40.05, -73)
+		update.BaroAltitude, update.GeoAltitude = altitude.baro, altitude.geo
+		track(t, at, update)
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{"", "geo2k0 high3k low500 mid1k0 noalt0"},
+		{"?min_alt=1000&max_alt=2000", "geo2k0 mid1k0"}, // both bounds inclusive
+		{"?min_alt=1000.1", "geo2k0 high3k"},
+		{"?max_alt=500", "low500"},
+		{"?min_alt=-100", "geo2k0 high3k low500 mid1k0"}, // no altitude is excluded by any band
+	} {
+		var body struct {
+			Flights []TrackedFlight `json:"flights"`
+		}
+		decodeBody(t, call(at.handleNearby, http.MethodGet, "/"+tc.query, map[string]string{"code": "KTST"}), &body)
+		var got []string
+		for _, flight := range body.Flights {
+			got = append(got, flight.ICAO24)
+		}
+		sort.Strings(got)
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("%q: %v, want %s", tc.query, got, tc.want)
+		}
+	}
+
+	for _, query := range []string{"?min_alt=high", "?max_alt=NaN", "?min_alt=2000&max_alt=1000"} {
+		if rec := call(at.handleNearby, http.MethodGet, "/"+query, map[string]string{"code": "KTST"}); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: code = %d, want 400", query, rec.Code)
+		}
+	}
+}
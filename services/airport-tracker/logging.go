@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// newLogger returns a JSON logger writing to w at the named level (debug,
+// info, warn or error). Unknown levels fall back to info.
+func newLogger(w io.Writer, level string) *slog.Logger {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		lvl = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl}))
+}
+
+// setupLogging installs the LOG_LEVEL JSON logger as the slog default. The
+// standard log package is routed through it as well.
+func setupLogging() {
+	slog.SetDefault(newLogger(os.Stderr, envString("LOG_LEVEL", "info")))
+}
+
+// fatal logs msg at error level and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// logSampler passes one in every n calls to sample, starting with the
+// first, to thin out high-volume log lines
+type logSampler struct {
+	every uint64
+	calls atomic.Uint64
+}
+
+// newLogSampler returns a sampler keeping one in every n lines; n below 2
+// keeps every line
+func newLogSampler(n int) *logSampler {
+	if n < 1 {
+		n = 1
+	}
+	return &logSampler{every: uint64(n)}
+}
+
+// sample reports whether this line should be logged
+func (s *logSampler) sample() bool {
+	return s.every <= 1 || (s.calls.Add(1)-1)%s.every == 0
+}
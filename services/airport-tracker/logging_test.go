@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProcessedUpdateLogsStructuredJSON(t *testing.T) {
+	at := newTestTracker(t)
+	logs := captureLogs(t, "info")
+	update := testUpdate("abc123", 40.05, -73)
+	update.Callsign = "TST123"
+	update.BaroAltitude = ptr(1000.0)
+	track(t, at, update)
+
+	var entry map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		if entry["msg"] == "flight tracked" {
+			break
+		}
+	}
+	if entry["msg"] != "flight tracked" {
+		t.Fatalf("no flight tracked entry in %s", logs)
+	}
+	if entry["level"] != "INFO" || entry["icao24"] != "abc123" || entry["airport"] != "KTST" || entry["status"] != StatusNearby {
+		t.Errorf("entry = %v", entry)
+	}
+	if _, ok := entry["distance_km"].(float64); !ok {
+		t.Errorf("distance_km = %v, want a number", entry["distance_km"])
+	}
+}
+
+func TestLogLevelFiltersLines(t *testing.T) {
+	logs := &logBuffer{}
+	logger := newLogger(logs, " WARN ")
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if out := logs.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Errorf("warn logger wrote %q", out)
+	}
+
+	logs = &logBuffer{}
+	newLogger(logs, "verbose").Debug("hidden") // unknown levels fall back to info
+	if logs.String() != "" {
+		t.Errorf("unknown level logged debug: %q", logs)
+	}
+}
+
+func TestLogSamplerKeepsOneInN(t *testing.T) {
+	s := newLogSampler(3)
+	var kept []bool
+	for i := 0; i < 6; i++ {
+		kept = append(kept, s.sample())
+	}
+	if want := []bool{true, false, false, true, false, false}; fmt.Sprint(kept) != fmt.Sprint(want) {
+		t.Errorf("sampled %v, want %v", kept, want)
+	}
+	if !newLogSampler(0).sample() || !newLogSampler(0).sample() {
+		t.Error("sampler below 2 dropped a line")
+	}
+}
+
+func TestFlightLogSamplingRatio(t *testing.T) {
+	t.Setenv("FLIGHT_LOG_SAMPLE_RATE", "5")
+	at := newTestTracker(t)
+	logs := captureLogs(t, "info")
+	countTracked := func() int {
+		return strings.Count(logs.String(), `"msg":"flight tracked"`)
+	}
+
+	// The first update is a transition; the other 19 are sampled 1 in 5
+	for i := 0; i < 20; i++ {
+		track(t, at, testUpdate("abc123", 40.05, -73))
+	}
+	if got := countTracked(); got != 5 {
+		t.Errorf("logged %d of 20 updates, want 5", got)
+	}
+
+	// Emergencies are always logged
+	before := countTracked()
+	for i := 0; i < 3; i++ {
+		update := testUpdate("abc123", 40.05, -73)
+		update.Squawk = "7700"
+		track(t, at, update)
+	}
+	if got := countTracked() - before; got != 3 {
+		t.Errorf("logged %d of 3 emergency updates, want 3", got)
+	}
+}
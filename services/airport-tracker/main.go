@@ -1,22 +1,69 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"math"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	_ "time/tzdata" // the runtime image ships without a zoneinfo database
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	Port              = ":3003"
-	DefaultConfigPath = "/config/airports.json"
+	Port                = ":3003"
+	DefaultConfigPath   = "/config/airports.json"
+	DefaultTagRulesPath = "/config/tag_rules.json"
+	
+	// DefaultMaxRadiusKm is the sanity limit for an airport geofence radius
+	DefaultMaxRadiusKm = 500
+)
+
+// Flight statuses relative to the matched airport
+const (
+	StatusArriving  = "arriving"
+	StatusDeparting = "departing"
+	StatusNearby    = "nearby"
+	
+	// StatusLeft only appears as the new status of a FlightStatusChange, for
+	// a flight no longer tracked at AirportCode in TRACKING_MODE=all
+	StatusLeft = "left"
+	
+	// StatusOnGround is used instead of the above for grounded flights when
+	// GROUND_FILTER=status
+	StatusOnGround = "on_ground"
+	
+	// onGroundBucket groups aircraft reporting on_ground in status breakdowns
+	onGroundBucket = StatusOnGround
+)
+
+// Vertical phases derived from the reported vertical rate
+const (
+	PhaseClimbing   = "climbing"
+	PhaseDescending = "descending"
+	PhaseLevel      = "level"
+	
+	// defaultVerticalDeadBandMS is the vertical rate within which a flight
+	// counts as level (VERTICAL_PHASE_DEAD_BAND_MS)
+	defaultVerticalDeadBandMS = 1.5
 )
 
 // FlightUpdate represents a flight update message from Pub/Sub
@@ -47,65 +94,561 @@ type AirportConfig struct {
 	Latitude      float64 `json:"latitude"`
 	Longitude     float64 `json:"longitude"`
 	RadiusKm      float64 `json:"radius_km"`
+	RadiusUnit    string  `json:"radius_unit,omitempty"` // unit of radius_km: "km" (default) or "nm", "km" once loaded
+	
+	// ConfiguredRadius is radius_km before conversion, in
+	// ConfiguredRadiusUnit. Both are set at load for the airports listing
+	// and ignored in the config.
+	ConfiguredRadius     float64 `json:"configured_radius,omitempty"`
+	ConfiguredRadiusUnit string  `json:"configured_radius_unit,omitempty"`
 	ArrivalThresholdM  float64 `json:"arrival_threshold_m"`
 	DepartureThresholdM float64 `json:"departure_threshold_m"`
+	AltitudeUnit  string  `json:"altitude_unit,omitempty"` // unit of the thresholds as configured: "m" (default) or "ft"
+	Timezone      string  `json:"timezone,omitempty"` // IANA name, defaults to UTC
+	
+	// Disabled airports stay configured but match no flights; see isEnabled
+	Enabled *bool `json:"enabled,omitempty"`
+	
+	// Geofence shape: "circle" (default, uses RadiusKm), "polygon" or
+	// "corridor". Points are [lat, lon] pairs.
+	GeofenceType    string      `json:"geofence_type,omitempty"`
+	Polygon         [][]float64 `json:"polygon,omitempty"`
+	Corridor        [][]float64 `json:"corridor,omitempty"`
+	CorridorWidthKm float64     `json:"corridor_width_km,omitempty"`
+	
+	// Overrides NOTIFICATION_COOLDOWN_SECONDS for transitions at this airport
+	NotificationCooldownSeconds *float64 `json:"notification_cooldown_seconds,omitempty"`
+	
+	location *time.Location
+}
+
+// localTime renders t in the airport's configured timezone
+func (a AirportConfig) localTime(t time.Time) time.Time {
+	if a.location == nil {
+		return t.UTC()
+	}
+	return t.In(a.location)
 }
 
 // TrackedFlight represents a flight being tracked near an airport
 type TrackedFlight struct {
 	FlightUpdate
 	AirportCode string    `json:"airport_code"`
-	Status      string    `json:"status"` // "arriving", "departing", "nearby"
+	Status      string    `json:"status"` // "arriving", "departing", "nearby" or "on_ground"
+	VerticalPhase string  `json:"vertical_phase,omitempty"` // "climbing", "descending", "level"; empty without a vertical rate
 	LastSeen    time.Time `json:"last_seen"`
+	LastSeenLocal string  `json:"last_seen_local"` // LastSeen in the airport's timezone
+	ObservedAt  time.Time `json:"observed_at"` // per FLIGHT_TIME_SOURCE; drives age and eviction
+	AgeSeconds  *float64  `json:"age_seconds,omitempty"` // seconds since ObservedAt, set on list and detail responses
+	Tags        []string  `json:"tags,omitempty"`
+	Location    string    `json:"location,omitempty"` // reverse-geocoded label, if enabled
+	
+	// Set while the flight squawks 7500, 7600 or 7700
+	Emergency     bool   `json:"emergency"`
+	EmergencyType string `json:"emergency_type,omitempty"`
+	
+	// Distance from AirportCode's reference point and the initial bearing
+	// from the airport to the aircraft
+	DistanceKm float64 `json:"distance_km"`
+	BearingDeg float64 `json:"bearing_deg"`
+	
+	// When the flight entered AirportCode's geofence, and how long it had been
+	// inside as of LastSeen. Leaving every geofence clears both, and
+	// switching airport restarts the timer.
+	EnteredAt    *time.Time `json:"entered_at,omitempty"`
+	DwellSeconds float64    `json:"dwell_seconds"`
+	
+	// Estimated seconds to reach the airport; only set for arriving flights
+	// that are closing on it
+	ETASeconds *float64 `json:"eta_seconds,omitempty"`
+	
+	// Position is only populated when ?coord_format= is dms or string
+	Position string `json:"position,omitempty"`
+	
+	// Nearest configured airport overall, which may differ from AirportCode
+	// when geofences overlap
+	NearestAirport    string  `json:"nearest_airport"`
+	NearestDistanceKm float64 `json:"nearest_distance_km"`
+	
+	// Ground speed implied by the last two positions and its difference from
+	// the reported velocity, an estimate of the along-track wind component
+	ImpliedSpeedMS     *float64 `json:"implied_speed_ms,omitempty"`
+	SpeedDiscrepancyMS *float64 `json:"speed_discrepancy_ms,omitempty"`
+	
+	// Confidence in the current state from 0 to 1; see flightConfidence
+	Confidence float64 `json:"confidence"`
+	
+	// Time between this flight's updates; nil until a second update arrives
+	Interarrival *InterarrivalStats `json:"interarrival,omitempty"`
+	
+	// History holds the most recent positions, oldest first
+	History []PositionSample `json:"-"`
+	
+	// Airport is only populated on responses requested with ?expand=airport
+	Airport *AirportConfig `json:"airport,omitempty"`
 }
 
 // AirportTracker service
 type AirportTracker struct {
+	airportsMu   sync.RWMutex // guards airports, which reloadConfig replaces
 	airports     []AirportConfig
-	flights      map[string]*TrackedFlight // key: icao24
-	flightsMutex sync.RWMutex
+	enabledOverrides map[string]bool // runtime enable/disable by ICAO code, guarded by airportsMu
+	flights      *flightStore // key: icao24
+	backend      FlightBackend
+	stateStore   *daprStateStore // nil unless STATE_STORE_NAME is set
 	configPath   string
+	tagRules     []TagRule
+	geocoder     *geocodeCache
+	schema       *schemaValidator
+	queue        *ingestQueue // nil when updates are processed synchronously
+	
+	// Flights not seen for flightTTL (or their status's entry in statusTTLs)
+	// are evicted by the background sweeper
+	timeSource   string           // FLIGHT_TIME_SOURCE, see observedAt
+	clock        func() time.Time // nil means time.Now; see now
+	flightTTL    time.Duration
+	statusTTLs   map[string]time.Duration
+	evictionHook EvictionHook
+	stopSweeper  func()
+	shutdown     sync.Once
+	
+	// Status transitions are sent to notifier at most once per cooldown for
+	// each flight, airport and status
+	notifier                    TransitionNotifier
+	notifications               *notificationDeduper
+	notificationCooldownDefault time.Duration
+	
+	// statusChanges publishes every transition to Dapr; nil when disabled
+	statusChanges *statusChangePublisher
+	
+	// Arrival and departure transitions counted per airport and local day
+	dailySummary *dailySummary
+	
+	// badPayloadPrefixBytes bounds the body prefix logged for undecodable
+	// updates; zero disables the diagnostic
+	badPayloadPrefixBytes int
+	
+	// minAirports is how many airports must be loaded for /ready to pass
+	minAirports int
+	
+	// maxResponseFlights caps the flights returned by the list endpoints
+	maxResponseFlights int
+	
+	// departureConfirmSamples is how many consecutive climbing samples moving
+	// away from the airport are needed before a flight is marked departing
+	departureConfirmSamples int
+	
+	// historyLength bounds the position samples kept per flight
+	historyLength int
+	
+	// altitudeSmoothingWindow is how many recent samples are averaged before
+	// comparing altitude against the arrival and departure thresholds
+	altitudeSmoothingWindow int
+	
+	// verticalDeadBandMS is the vertical rate magnitude below which a flight
+	// is in the level phase
+	verticalDeadBandMS float64
+	
+	// headingToleranceDeg bounds the track deviation accepted as heading
+	// toward or away from an airport; see refineStatusByHeading
+	headingToleranceDeg float64
+	
+	// flightLogs samples the per-update "flight tracked" log line
+	flightLogs *logSampler
+	
+	// batchField names the array of updates in a batched event payload
+	batchField string
+	
+	// clockSkew bounds how far feed times may stray from server time
+	clockSkew clockSkewPolicy
+	
+	// groundFilter (GROUND_FILTER) decides what happens to flights on the
+	// ground or below groundFloorM; see isGrounded
+	groundFilter string
+	groundFloorM float64
+	
+	// distanceMethod (DISTANCE_METHOD) is the algorithm geofenceDistance
+	// matches updates with on ingest, and the default for the geofence-check
+	// and flights-near endpoints
+	distanceMethod   string
+	geofenceDistance distanceFunc
+	
+	// Defaults for /api/v1/flights/proximity
+	proximityThresholdKm   float64
+	proximityAltitudeBandM float64
+	
+	stats        trackerStats
+	metrics      *trackerMetrics
+	hub          *flightHub // WebSocket subscribers of flight updates
+	eventFilter  eventFilter // expected CloudEvent attributes on /flight-update
+	trackingMode string      // TRACKING_MODE: nearest or all; see flightKey
+	events       *statusEventHub // Server-Sent Events subscribers of status changes
+	sseKeepAlive time.Duration
+}
+
+// trackerStats holds ingestion counters; fields are updated atomically
+type trackerStats struct {
+	startedAt        time.Time
+	updatesProcessed atomic.Uint64 // updates run through processFlightUpdate
+	processingPanics atomic.Uint64
+	noPositionFix    atomic.Uint64 // updates dropped for a missing or invalid position
 }
 
 // CloudEvent represents Dapr CloudEvents format
 type CloudEvent struct {
+	Type       string      `json:"type,omitempty"`
+	PubSubName string      `json:"pubsubname,omitempty"`
+	Topic      string      `json:"topic,omitempty"`
 	Data      interface{} `json:"data"`
 	DataBase64 string     `json:"data_base64,omitempty"`
 }
 
 func NewAirportTracker(configPath string) (*AirportTracker, error) {
+	flights := newFlightStore(envInt("FLIGHT_STORE_SHARDS", defaultStoreShards))
 	tracker := &AirportTracker{
 		airports:   []AirportConfig{},
-		flights:    make(map[string]*TrackedFlight),
+		enabledOverrides: map[string]bool{},
+		flights:    flights,
+		backend:    newBackendFromEnv(flights),
 		configPath: configPath,
+		geocoder:   newGeocoderFromEnv(),
+		schema:     newSchemaValidatorFromEnv(),
+		hub:        newFlightHub(),
+		eventFilter: eventFilterFromEnv(),
+		trackingMode: trackingModeFromEnv(),
+		events:     newStatusEventHub(),
+		sseKeepAlive: envSeconds("SSE_KEEPALIVE_SECONDS", defaultSSEKeepAlive),
+		
+		timeSource:   timeSourceFromEnv(),
+		flightTTL:    envSeconds("FLIGHT_TTL_SECONDS", defaultFlightTTL),
+		statusTTLs:   statusTTLsFromEnv(),
+		evictionHook: newEvictionHookFromEnv(),
+		
+		notifier:                    newTransitionNotifierFromEnv(),
+		notifications:               newNotificationDeduper(envInt("NOTIFICATION_DEDUP_MAX_ENTRIES", defaultNotificationDedupLimit)),
+		notificationCooldownDefault: envSeconds("NOTIFICATION_COOLDOWN_SECONDS", defaultNotificationCooldown),
+		statusChanges:               newStatusChangePublisherFromEnv(),
+		dailySummary:                newDailySummary(envInt("DAILY_SUMMARY_RESET_HOUR", 0)),
+		
+		badPayloadPrefixBytes:   badPayloadPrefixFromEnv(),
+		minAirports:             envInt("READY_MIN_AIRPORTS", defaultMinAirports),
+		maxResponseFlights:      envInt("MAX_RESPONSE_FLIGHTS", defaultMaxResponseFlights),
+		departureConfirmSamples: envInt("DEPARTURE_CONFIRM_SAMPLES", defaultDepartureConfirmSamples),
+		historyLength:           envInt("HISTORY_LENGTH", defaultHistoryLength),
+		altitudeSmoothingWindow: envInt("ALTITUDE_SMOOTHING_WINDOW", defaultAltitudeSmoothingWindow),
+		verticalDeadBandMS:      envFloat("VERTICAL_PHASE_DEAD_BAND_MS", defaultVerticalDeadBandMS),
+		headingToleranceDeg:     envFloat("HEADING_TOLERANCE_DEG", defaultHeadingToleranceDeg),
+		groundFilter:            groundFilterFromEnv(),
+		clockSkew:               clockSkewPolicyFromEnv(),
+		batchField:              envString("EVENT_BATCH_FIELD", defaultBatchField),
+		flightLogs:              newLogSampler(envInt("FLIGHT_LOG_SAMPLE_RATE", 1)),
+		groundFloorM:            envFloat("GROUND_ALTITUDE_FLOOR_M", 0),
+		distanceMethod:          distanceMethodFromEnv(),
+		proximityThresholdKm:    envFloat("PROXIMITY_THRESHOLD_KM", defaultProximityThresholdKm),
+		proximityAltitudeBandM:  envFloat("PROXIMITY_ALTITUDE_BAND_M", defaultProximityAltitudeM),
 	}
 	
+	tracker.geofenceDistance = distanceFuncs[tracker.distanceMethod]
+	tracker.stats.startedAt = time.Now()
+	tracker.metrics = newTrackerMetrics(tracker)
+	tracker.stateStore = newStateStoreFromEnv(tracker.maxTTL())
+	
 	if err := tracker.loadConfig(); err != nil {
 		return nil, fmt.Errorf("failed to load airport config: %w", err)
 	}
 	
+	if err := tracker.loadTagRules(); err != nil {
+		return nil, fmt.Errorf("failed to load tag rules: %w", err)
+	}
+	
+	if tracker.stateStore != nil {
+		tracker.rehydrate()
+	}
+	
+	if size := envInt("INGEST_QUEUE_SIZE", 0); size > 0 {
+		tracker.startIngestQueue(size, envInt("INGEST_COALESCE_BATCH", 0))
+	}
+	
+	tracker.startSweeper(envSeconds("SWEEP_INTERVAL_SECONDS", defaultSweepInterval), envFloat("SWEEP_JITTER", defaultSweepJitter))
+	
 	return tracker, nil
 }
 
+// Close stops the tracker's background goroutines once every queued update
+// has been processed
+func (at *AirportTracker) Close() {
+	at.Shutdown(context.Background())
+}
+
+// Shutdown stops the tracker's background goroutines. Updates already
+// queued have been acknowledged, so they are processed first for as long as
+// ctx allows. Only the first call has any effect.
+func (at *AirportTracker) Shutdown(ctx context.Context) {
+	at.shutdown.Do(func() {
+		if at.stopSweeper != nil {
+			at.stopSweeper()
+		}
+		if at.queue != nil {
+			at.queue.Stop(ctx)
+		}
+		if at.statusChanges != nil {
+			at.statusChanges.Stop()
+		}
+	})
+}
+
 func (at *AirportTracker) loadConfig() error {
 	configPath := at.configPath
 	if configPath == "" {
-		configPath = os.Getenv("AIRPORT_CONFIG_PATH")
-		if configPath == "" {
-			configPath = DefaultConfigPath
-		}
+		configPath = envString("AIRPORT_CONFIG_PATH", DefaultConfigPath)
+	}
+	
+	airports, err := parseAirportConfig(configPath)
+	if err != nil {
+		return err
+	}
+	
+	at.airportsMu.Lock()
+	at.applyEnabledOverrides(airports)
+	at.airports = airports
+	at.airportsMu.Unlock()
+	
+	recordSetting("airports", len(airports), SourceFile)
+	slog.Info("loaded airports", "count", len(airports), "path", configPath)
+	return nil
+}
+
+// reloadConfig re-reads the airport config and swaps it in atomically. On
+// any error the current airports are kept.
+func (at *AirportTracker) reloadConfig() error {
+	if err := at.loadConfig(); err != nil {
+		slog.Warn("config reload failed, keeping current airports", "airports", len(at.airportList()), "error", err)
+		return err
+	}
+	return nil
+}
+
+// airportList returns the current airports. A reload replaces the slice
+// rather than modifying it, so callers may use the result without locking.
+func (at *AirportTracker) airportList() []AirportConfig {
+	at.airportsMu.RLock()
+	defer at.airportsMu.RUnlock()
+	return at.airports
+}
+
+// parseAirportConfig reads and validates an airports.json file, or every
+// *.json file in a directory; see readAirportDir
+func parseAirportConfig(configPath string) ([]AirportConfig, error) {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
+	}
+	
+	var parsed []AirportConfig
+	if info.IsDir() {
+		parsed, err = readAirportDir(configPath)
+	} else {
+		parsed, err = readAirportFile(configPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	// Every problem is reported at once so a bad config can be fixed in one pass
+	var problems []error
+	for i := range parsed {
+		problems = append(problems, parsed[i].validate(i)...)
+		parsed[i].normalizeAltitudeUnit()
+		parsed[i].normalizeRadiusUnit()
+		parsed[i].normalizeEnabled()
 	}
 	
-	data, err := os.ReadFile(configPath)
+	// DUPLICATE_ICAO_MODE: error (default), first, last or suffix
+	airports, err := resolveDuplicateICAOs(parsed, envString("DUPLICATE_ICAO_MODE", DuplicateICAOError))
 	if err != nil {
-		return fmt.Errorf("failed to read config file %s: %w", configPath, err)
+		problems = append(problems, err)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid airport config: %w", errors.Join(problems...))
+	}
+	
+	// RADIUS_CHECK_MODE=reject turns radius warnings into load errors
+	rejectBadRadius := strings.EqualFold(envString("RADIUS_CHECK_MODE", "warn"), "reject")
+	if err := checkAirportRadii(airports, envFloat("MAX_RADIUS_KM", DefaultMaxRadiusKm), rejectBadRadius); err != nil {
+		return nil, err
+	}
+	return airports, nil
+}
+
+// validate checks one airport entry, loading its timezone on success.
+// index identifies the entry in errors when the ICAO code is missing.
+func (a *AirportConfig) validate(index int) []error {
+	name := a.ICAO
+	if strings.TrimSpace(name) == "" {
+		name = fmt.Sprintf("airport %d", index)
+	}
+	
+	var problems []error
+	if strings.TrimSpace(a.ICAO) == "" {
+		problems = append(problems, fmt.Errorf("%s: icao is required", name))
+	}
+	if a.Latitude < -90 || a.Latitude > 90 {
+		problems = append(problems, fmt.Errorf("%s: latitude %g out of range [-90, 90]", name, a.Latitude))
+	}
+	if a.Longitude < -180 || a.Longitude > 180 {
+		problems = append(problems, fmt.Errorf("%s: longitude %g out of range [-180, 180]", name, a.Longitude))
+	}
+	if a.geofenceType() == GeofenceCircle && a.RadiusKm <= 0 {
+		problems = append(problems, fmt.Errorf("%s: radius_km must be positive, got %g", name, a.RadiusKm))
+	}
+	if err := a.validateGeofence(); err != nil {
+		problems = append(problems, fmt.Errorf("invalid geofence: %w", err))
+	}
+	switch strings.ToLower(a.AltitudeUnit) {
+	case "", AltitudeUnitMeters, AltitudeUnitFeet:
+	default:
+		problems = append(problems, fmt.Errorf("%s: unknown altitude_unit %q: expected m or ft", name, a.AltitudeUnit))
+	}
+	switch strings.ToLower(a.RadiusUnit) {
+	case "", RadiusUnitKm, RadiusUnitNm:
+	default:
+		problems = append(problems, fmt.Errorf("%s: unknown radius_unit %q: expected km or nm", name, a.RadiusUnit))
+	}
+	if a.Timezone != "" {
+		loc, err := time.LoadLocation(a.Timezone)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: invalid timezone: %w", name, err))
+		} else {
+			a.location = loc
+		}
+	}
+	return problems
+}
+
+// Units accepted for an airport's altitude thresholds
+const (
+	AltitudeUnitMeters = "m"
+	AltitudeUnitFeet   = "ft"
+)
+
+// normalizeAltitudeUnit converts thresholds configured in feet to meters, so
+// the rest of the service only ever compares meters
+func (a *AirportConfig) normalizeAltitudeUnit() {
+	switch strings.ToLower(a.AltitudeUnit) {
+	case AltitudeUnitFeet:
+		a.ArrivalThresholdM /= metersToFeet
+		a.DepartureThresholdM /= metersToFeet
+		a.AltitudeUnit = AltitudeUnitMeters
+	case "", AltitudeUnitMeters:
+		a.AltitudeUnit = AltitudeUnitMeters
+	}
+}
+
+// Units accepted for an airport's geofence radius
+const (
+	RadiusUnitKm = "km"
+	RadiusUnitNm = "nm"
+	
+	kmPerNauticalMile = 1.852
+)
+
+// normalizeRadiusUnit converts a radius configured in nautical miles to
+// kilometers, as normalizeAltitudeUnit does for the thresholds. The
+// configured value and unit are kept in ConfiguredRadius and
+// ConfiguredRadiusUnit.
+func (a *AirportConfig) normalizeRadiusUnit() {
+	unit := strings.ToLower(a.RadiusUnit)
+	switch unit {
+	case RadiusUnitNm:
+		a.ConfiguredRadius, a.ConfiguredRadiusUnit = a.RadiusKm, RadiusUnitNm
+		a.RadiusKm *= kmPerNauticalMile
+		a.RadiusUnit = RadiusUnitKm
+	case "", RadiusUnitKm:
+		a.ConfiguredRadius, a.ConfiguredRadiusUnit = a.RadiusKm, RadiusUnitKm
+		a.RadiusUnit = RadiusUnitKm
+	}
+}
+
+// How loadConfig resolves airports that share an ICAO code
+const (
+	DuplicateICAOError  = "error"  // refuse to load the config
+	DuplicateICAOFirst  = "first"  // keep the first entry, drop later ones
+	DuplicateICAOLast   = "last"   // keep the last entry, drop earlier ones
+	DuplicateICAOSuffix = "suffix" // keep all, renaming repeats to ICAO-2, ICAO-3...
+)
+
+// resolveDuplicateICAOs makes airport codes unique so the {code} endpoints
+// are unambiguous. Codes are compared case-insensitively. An unknown mode is
+// an error even when there are no duplicates, so a typo fails at startup
+// rather than on the first config that has one.
+func resolveDuplicateICAOs(airports []AirportConfig, mode string) ([]AirportConfig, error) {
+	switch mode {
+	case DuplicateICAOError, DuplicateICAOFirst, DuplicateICAOLast, DuplicateICAOSuffix:
+	default:
+		return nil, fmt.Errorf("unknown DUPLICATE_ICAO_MODE %q: expected error, first, last or suffix", mode)
 	}
 	
-	if err := json.Unmarshal(data, &at.airports); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+	seen := map[string]int{} // code -> index in resolved
+	counts := map[string]int{}
+	resolved := make([]AirportConfig, 0, len(airports))
+	var duplicates []string
+	
+	for _, airport := range airports {
+		code := strings.ToUpper(strings.TrimSpace(airport.ICAO))
+		counts[code]++
+		idx, dup := seen[code]
+		if !dup {
+			seen[code] = len(resolved)
+			resolved = append(resolved, airport)
+			continue
+		}
+		duplicates = append(duplicates, airport.ICAO)
+		
+		switch mode {
+		case DuplicateICAOFirst:
+		case DuplicateICAOLast:
+			resolved[idx] = airport
+		case DuplicateICAOSuffix:
+			airport.ICAO = fmt.Sprintf("%s-%d", airport.ICAO, counts[code])
+			resolved = append(resolved, airport)
+		case DuplicateICAOError:
+			// Reported below once every duplicate is known
+		}
 	}
 	
-	log.Printf("✓ Loaded %d airports from %s", len(at.airports), configPath)
+	if len(duplicates) == 0 {
+		return airports, nil
+	}
+	if mode == DuplicateICAOError {
+		return nil, fmt.Errorf("duplicate airport ICAO codes: %s", strings.Join(duplicates, ", "))
+	}
+	slog.Warn("duplicate airport ICAO codes resolved", "mode", mode, "codes", duplicates)
+	return resolved, nil
+}
+
+// checkAirportRadii flags radii larger than maxKm (non-positive radii are
+// rejected by validate). A typo such as 5000 instead of 5 would match most
+// of the feed, so these are logged as warnings, or returned as an error when
+// reject is set.
+func checkAirportRadii(airports []AirportConfig, maxKm float64, reject bool) error {
+	var problems []string
+	for _, airport := range airports {
+		if airport.geofenceType() != GeofenceCircle {
+			continue
+		}
+		if airport.RadiusKm > maxKm {
+			problems = append(problems, fmt.Sprintf("%s radius %.1f km exceeds %.1f km", airport.ICAO, airport.RadiusKm, maxKm))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if reject {
+		return fmt.Errorf("suspicious airport radius: %s", strings.Join(problems, "; "))
+	}
+	for _, problem := range problems {
+		slog.Warn("suspicious airport radius", "problem", problem)
+	}
 	return nil
 }
 
@@ -124,109 +667,609 @@ func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	return R * c
 }
 
-func (at *AirportTracker) processFlightUpdate(update FlightUpdate) {
-	at.flightsMutex.Lock()
-	defer at.flightsMutex.Unlock()
+// levelFlightRateMS is the vertical rate below which a flight is treated as
+// level rather than climbing or descending
+const levelFlightRateMS = 1.0
+
+// classifyStatus derives a flight's status at an airport from its vertical
+// rate and altitude: descending below ArrivalThresholdM is arriving,
+// climbing below DepartureThresholdM is departing. Aircraft on the ground,
+// in level flight, above the relevant threshold, or without a vertical rate
+// or altitude are nearby.
+func classifyStatus(update FlightUpdate, altitude float64, hasAltitude bool, airport AirportConfig) string {
+	if update.OnGround || !hasAltitude || update.VerticalRate == nil {
+		return StatusNearby
+	}
+	rate := *update.VerticalRate
+	switch {
+	case rate <= -levelFlightRateMS && altitude < airport.ArrivalThresholdM:
+		return StatusArriving
+	case rate >= levelFlightRateMS && altitude < airport.DepartureThresholdM:
+		return StatusDeparting
+	default:
+		return StatusNearby
+	}
+}
+
+// verticalPhase classifies a vertical rate as climbing, descending or level,
+// treating rates within ±deadBand m/s as level. It returns "" when the rate
+// is not reported.
+func verticalPhase(rate *float64, deadBand float64) string {
+	switch {
+	case rate == nil:
+		return ""
+	case *rate > deadBand:
+		return PhaseClimbing
+	case *rate < -deadBand:
+		return PhaseDescending
+	default:
+		return PhaseLevel
+	}
+}
+
+// airportMatch is an airport whose geofence contains a flight update
+type airportMatch struct {
+	airport    AirportConfig
+	distanceKm float64
+}
+
+// effectiveAltitude returns the barometric altitude, falling back to the
+// geometric altitude. ok is false when neither is reported.
+func effectiveAltitude(update FlightUpdate) (altitude float64, ok bool) {
+	if update.BaroAltitude != nil {
+		return *update.BaroAltitude, true
+	}
+	if update.GeoAltitude != nil {
+		return *update.GeoAltitude, true
+	}
+	return 0, false
+}
+
+// normalizeUpdate canonicalizes identifiers so the same aircraft reported
+// with different case or padding maps to a single tracked flight: ICAO24 is
+// lowercased and the callsign uppercased, both trimmed. An empty callsign
+// stays empty.
+func normalizeUpdate(update FlightUpdate) FlightUpdate {
+	update.ICAO24 = normalizeICAO24(update.ICAO24)
+	update.Callsign = strings.ToUpper(strings.TrimSpace(update.Callsign))
+	return update
+}
+
+// normalizeICAO24 returns the form of an ICAO24 address flights are keyed by
+func normalizeICAO24(icao24 string) string {
+	return strings.ToLower(strings.TrimSpace(icao24))
+}
+
+// processFlightUpdate matches an update against the configured airports
+// and tracks it. A panic while processing is recovered, counted and returned
+// as a *processingPanicError so one malformed message cannot take down
+// ingestion.
+func (at *AirportTracker) processFlightUpdate(ctx context.Context, update FlightUpdate) (processOutcome, error) {
+	p := at.processFlightUpdates([]queuedUpdate{{ctx: ctx, update: update}})[0]
+	return p.outcome, p.err
+}
+
+// pendingUpdate is an update on its way through processFlightUpdates. Its
+// span and processing timer stay open until finishUpdate.
+type pendingUpdate struct {
+	update  FlightUpdate
+	start   time.Time
+	span    trace.Span
+	writes  int
+	outcome processOutcome
+	err     error
+}
+
+// processFlightUpdates processes a batch of updates. Matching and enrichment
+// run per update without any lock held; the store writes of the whole batch
+// are then applied together under one acquisition of the store locks.
+func (at *AirportTracker) processFlightUpdates(batch []queuedUpdate) []*pendingUpdate {
+	pending := make([]*pendingUpdate, len(batch))
+	var writes []flightWrite
+	for i, queued := range batch {
+		update := normalizeUpdate(queued.update)
+		p := &pendingUpdate{update: update, start: time.Now()}
+		_, p.span = tracer.Start(queued.ctx, "processFlightUpdate", trace.WithAttributes(attribute.String("icao24", update.ICAO24)))
+		
+		var planned []flightWrite
+		p.outcome, planned, p.err = at.planUpdate(p)
+		p.writes = len(planned)
+		writes = append(writes, planned...)
+		pending[i] = p
+	}
+	at.applyWrites(writes, pending)
+	for _, p := range pending {
+		at.finishUpdate(p)
+	}
+	return pending
+}
+
+// applyWrites applies a batch's store writes. A panic while applying them
+// fails every update in the batch that had writes, as the culprit is unknown.
+func (at *AirportTracker) applyWrites(writes []flightWrite, pending []*pendingUpdate) {
+	defer func() {
+		if r := recover(); r != nil {
+			at.stats.processingPanics.Add(1)
+			slog.Error("recovered from panic storing flights", "updates", len(pending), "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+			for _, p := range pending {
+				if p.err == nil && p.writes > 0 {
+					p.err = &processingPanicError{icao24: p.update.ICAO24, value: r}
+				}
+			}
+		}
+	}()
+	at.flights.Apply(writes)
+}
+
+// finishUpdate records an update's processing metrics and ends its span
+func (at *AirportTracker) finishUpdate(p *pendingUpdate) {
+	at.stats.updatesProcessed.Add(1)
+	at.metrics.updatesProcessed.Inc()
+	at.metrics.processingDuration.Observe(time.Since(p.start).Seconds())
+	
+	p.span.SetAttributes(attribute.String("outcome", p.outcome.result))
+	if p.err != nil {
+		p.span.RecordError(p.err)
+		p.span.SetStatus(codes.Error, p.err.Error())
+	}
+	p.span.End()
+}
+
+// planUpdate decides what an update does to the store without touching it,
+// returning the writes to apply
+func (at *AirportTracker) planUpdate(p *pendingUpdate) (outcome processOutcome, writes []flightWrite, err error) {
+	update, span := p.update, p.span
+	now := at.now()
+	defer func() {
+		if r := recover(); r != nil {
+			at.stats.processingPanics.Add(1)
+			slog.Error("recovered from panic processing flight", "icao24", update.ICAO24, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+			outcome, writes, err = processOutcome{}, nil, &processingPanicError{icao24: update.ICAO24, value: r}
+		}
+	}()
+	
+	if problem := positionProblem(update); problem != "" {
+		at.stats.noPositionFix.Add(1)
+		slog.Debug("dropping update without a position fix", "icao24", update.ICAO24, "reason", problem)
+		return skipped(problem), nil, nil
+	}
 	
-	for _, airport := range at.airports {
-		distance := haversineDistance(
+	if corrected, skew := at.clockSkew.check(update, now); skew != "" {
+		at.metrics.clockSkew.WithLabelValues(skew).Inc()
+		if at.clockSkew.action == ClockSkewReject {
+			slog.Warn("rejecting update with skewed clock", "icao24", update.ICAO24, "skew", skew,
+				"timestamp", update.Timestamp, "last_contact", update.LastContact, "time_position", update.TimePosition)
+			return processOutcome{result: OutcomeRejected, reason: skew + " clock skew beyond window"}, nil, nil
+		}
+		slog.Warn("clamping skewed update times", "icao24", update.ICAO24, "skew", skew,
+			"timestamp", update.Timestamp, "last_contact", update.LastContact, "time_position", update.TimePosition)
+		update = corrected
+	}
+	
+	// Match against airports before taking the lock so enrichment that may
+	// block (reverse geocoding) never holds up readers. The nearest airport is
+	// tracked across all airports, not just those whose geofence matched.
+	var matches []airportMatch
+	var nearest *airportMatch
+	for _, airport := range at.airportList() {
+		if !airport.isEnabled() {
+			continue
+		}
+		if airport.geofenceType() == GeofenceCircle && nearest != nil &&
+			!withinRadiusBounds(update.Latitude, update.Longitude, airport.Latitude, airport.Longitude, math.Max(airport.RadiusKm, nearest.distanceKm)) {
+			// Neither inside this geofence nor closer than the nearest so far
+			continue
+		}
+		
+		distance := at.geofenceDistance(
 			update.Latitude,
 			update.Longitude,
 			airport.Latitude,
 			airport.Longitude,
 		)
 		
-		if distance <= airport.RadiusKm {
-			altitude := 0.0
-			if update.BaroAltitude != nil {
-				altitude = *update.BaroAltitude
-			} else if update.GeoAltitude != nil {
-				altitude = *update.GeoAltitude
+		if nearest == nil || distance < nearest.distanceKm {
+			nearest = &airportMatch{airport: airport, distanceKm: distance}
+		}
+		
+		if airport.contains(update.Latitude, update.Longitude, distance) {
+			matches = append(matches, airportMatch{airport: airport, distanceKm: distance})
+		}
+	}
+	if len(matches) == 0 {
+		return skipped("outside all airport geofences"), at.leftGeofenceWrites(update.ICAO24), nil
+	}
+	
+	// Overlapping geofences: the nearest containing airport is primary, and
+	// config order breaks exact ties. TRACKING_MODE=all also tracks the
+	// flight at every other airport containing it.
+	primary := matches[0]
+	for _, m := range matches[1:] {
+		if m.distanceKm < primary.distanceKm {
+			primary = m
+		}
+	}
+	span.SetAttributes(attribute.String("airport", primary.airport.ICAO))
+	targets := []airportMatch{primary}
+	if at.trackingMode == TrackingAll {
+		targets = matches
+		writes = at.leftAirportWrites(update.ICAO24, matches, now)
+	}
+	
+	altitude, _ := effectiveAltitude(update)
+	emergency, isEmergency := emergencyType(update.Squawk)
+	if isEmergency {
+		slog.Error("emergency squawk", "icao24", update.ICAO24, "callsign", update.Callsign,
+			"squawk", update.Squawk, "emergency_type", emergency, "lat", update.Latitude, "lon", update.Longitude)
+	}
+	enrichment := flightEnrichment{
+		tags:          evaluateTags(at.tagRules, update),
+		location:      at.geocoder.Lookup(update.Latitude, update.Longitude),
+		altitude:      altitude,
+		emergency:     isEmergency,
+		emergencyType: emergency,
+		nearest:       *nearest,
+	}
+	
+	for _, match := range targets {
+		var onTracked func(TrackedFlight)
+		if match.airport.ICAO == primary.airport.ICAO {
+			onTracked = func(tracked TrackedFlight) {
+				span.SetAttributes(attribute.String("status", tracked.Status))
 			}
+		}
+		writes = append(writes, at.trackWrite(update, match, enrichment, now, onTracked))
+	}
+	return processed, writes, nil
+}
+
+// flightEnrichment is what processFlightUpdate derives from an update
+// regardless of the airport it is tracked at
+type flightEnrichment struct {
+	tags          []string
+	location      string
+	altitude      float64
+	emergency     bool
+	emergencyType string
+	nearest       airportMatch // nearest configured airport overall
+}
+
+// trackWrite stores update as a flight at match's airport. Once stored, the
+// result is mirrored, persisted and broadcast, any status transition is
+// reported, and onTracked, when set, is called with it.
+func (at *AirportTracker) trackWrite(update FlightUpdate, match airportMatch, e flightEnrichment, now time.Time, onTracked func(TrackedFlight)) flightWrite {
+	airport := match.airport
+	store := func(prev *TrackedFlight) *TrackedFlight {
+		var history []PositionSample
+		var interarrival *InterarrivalStats
+		if prev != nil {
+			history = prev.History
+			interarrival = nextInterarrival(prev.Interarrival, prev.LastSeen, now)
+		}
+		history = appendSample(history, sampleFromUpdate(update, now), at.historyLength)
+		impliedSpeed, speedDiscrepancy := estimateSpeedDiscrepancy(history)
+		observed := observedAt(update, now, at.timeSource)
+		confidence := flightConfidence(update, now, observed, speedDiscrepancy)
+		
+		// Compare thresholds against a moving average so a single noisy
+		// sample does not flip the status
+		statusAltitude, hasAltitude := smoothedAltitude(history, at.altitudeSmoothingWindow)
+		
+		rateStatus := classifyStatus(update, statusAltitude, hasAltitude, airport)
+		status := refineStatusByHeading(rateStatus, update, statusAltitude, hasAltitude, airport,
+			match.distanceKm, at.headingToleranceDeg, at.verticalDeadBandMS)
+		
+		// A departure the heading decided need not be climbing, only moving away
+		byHeading := rateStatus != StatusDeparting
+		if status == StatusDeparting && !confirmDeparture(history, airport, at.departureConfirmSamples, !byHeading) {
+			status = StatusNearby
+		}
+		if at.groundFilter == GroundFilterStatus && isGrounded(update, statusAltitude, hasAltitude, at.groundFloorM) {
+			status = StatusOnGround
+		}
+		
+		enteredAt := now
+		if prev != nil && prev.AirportCode == airport.ICAO && prev.EnteredAt != nil {
+			enteredAt = *prev.EnteredAt
+		}
+		
+		var eta *float64
+		if status == StatusArriving {
+			eta = estimateETA(update, airport, match.distanceKm)
+		}
+		
+		return &TrackedFlight{
+			FlightUpdate: update,
+			AirportCode:  airport.ICAO,
+			Status:       status,
+			VerticalPhase: verticalPhase(update.VerticalRate, at.verticalDeadBandMS),
+			LastSeen:     now,
+			LastSeenLocal: airport.localTime(now).Format(time.RFC3339),
+			ObservedAt:   observed,
+			Tags:         e.tags,
+			Location:     e.location,
+			Emergency:     e.emergency,
+			EmergencyType: e.emergencyType,
+			History:      history,
 			
-			status := "nearby"
-			if altitude > 0 && altitude < airport.ArrivalThresholdM {
-				status = "arriving"
-			} else if altitude > 0 && altitude < airport.DepartureThresholdM {
-				status = "departing"
-			}
+			DistanceKm: match.distanceKm,
+			BearingDeg: initialBearing(airport.Latitude, airport.Longitude, update.Latitude, update.Longitude),
+			ETASeconds: eta,
 			
-			at.flights[update.ICAO24] = &TrackedFlight{
-				FlightUpdate: update,
-				AirportCode:  airport.ICAO,
-				Status:       status,
-				LastSeen:     time.Now(),
-			}
+			EnteredAt:    &enteredAt,
+			DwellSeconds: now.Sub(enteredAt).Seconds(),
+			
+			NearestAirport:    e.nearest.airport.ICAO,
+			NearestDistanceKm: e.nearest.distanceKm,
 			
-			log.Printf("📍 Flight %s (%s) near %s - Status: %s (distance: %.2f km, altitude: %.0f m)",
-				update.ICAO24, update.Callsign, airport.ICAO, status, distance, altitude)
+			ImpliedSpeedMS:     impliedSpeed,
+			SpeedDiscrepancyMS: speedDiscrepancy,
+			Confidence:         confidence,
+			Interarrival:       interarrival,
 		}
 	}
+	return flightWrite{key: at.flightKey(update.ICAO24, airport.ICAO), fn: store, done: func(prev, next *TrackedFlight) {
+		var prevAirport, prevStatus string
+		if prev != nil {
+			prevAirport, prevStatus = prev.AirportCode, prev.Status
+		}
+		tracked := *next
+		
+		// Per-update logs are sampled (FLIGHT_LOG_SAMPLE_RATE); transitions and
+		// emergencies are always logged
+		transition := tracked.AirportCode != prevAirport || tracked.Status != prevStatus
+		if transition || tracked.Emergency || at.flightLogs.sample() {
+			slog.Info("flight tracked", "icao24", update.ICAO24, "callsign", update.Callsign,
+				"airport", airport.ICAO, "status", tracked.Status, "distance_km", match.distanceKm, "altitude_m", e.altitude,
+				"transition", transition)
+		}
+		at.mirrorFlight(tracked)
+		at.persistFlight(tracked)
+		at.hub.Publish(tracked)
+		if transition {
+			at.publishStatusChange(FlightStatusChange{
+				ICAO24:      tracked.ICAO24,
+				Callsign:    tracked.Callsign,
+				AirportCode: tracked.AirportCode,
+				OldAirport:  prevAirport,
+				OldStatus:   prevStatus,
+				NewStatus:   tracked.Status,
+				Timestamp:   now,
+			})
+			at.notifyTransition(prevStatus, tracked)
+			at.dailySummary.Record(airport, tracked.Status, now)
+		}
+		if onTracked != nil {
+			onTracked(tracked)
+		}
+	}}
+}
+
+// publishStatusChange sends change to the Dapr topic, when enabled, and to
+// Server-Sent Events subscribers. The topic gets the same cooldown as the
+// transition webhook, so a flapping flight is not republished.
+func (at *AirportTracker) publishStatusChange(change FlightStatusChange) {
+	if at.statusChanges != nil &&
+		at.allowTransition("status_change", change.ICAO24, change.AirportCode, change.NewStatus, change.Timestamp) {
+		at.statusChanges.Publish(change)
+	}
+	at.events.Publish(change)
+}
+
+// leftGeofenceWrites clear the dwell timer of a tracked flight reported
+// outside every geofence, so re-entering starts a fresh timer
+func (at *AirportTracker) leftGeofenceWrites(icao24 string) []flightWrite {
+	keys := at.flightKeys(icao24)
+	writes := make([]flightWrite, 0, len(keys))
+	for _, key := range keys {
+		writes = append(writes, flightWrite{key: key, fn: func(prev *TrackedFlight) *TrackedFlight {
+			if prev == nil || prev.EnteredAt == nil {
+				return nil
+			}
+			next := *prev
+			next.EnteredAt = nil
+			next.DwellSeconds = 0
+			return &next
+		}})
+	}
+	return writes
+}
+
+// Dapr pub/sub response statuses
+const (
+	DaprSuccess = "SUCCESS"
+	DaprRetry   = "RETRY"
+	DaprDrop    = "DROP"
+)
+
+// Outcomes reported back to the publisher alongside the Dapr status
+const (
+	OutcomeProcessed = "processed"
+	OutcomeSkipped   = "skipped"
+	OutcomeQueued    = "queued"
+	OutcomeRejected  = "rejected"
+	OutcomeRetry     = "retry"
+)
+
+// processOutcome says whether processFlightUpdate tracked the flight or
+// acknowledged and skipped it, and why
+type processOutcome struct {
+	result string
+	reason string
+}
+
+var processed = processOutcome{result: OutcomeProcessed}
+
+func skipped(reason string) processOutcome {
+	return processOutcome{result: OutcomeSkipped, reason: reason}
+}
+
+// writeAck writes a Dapr-compatible acknowledgement. Dapr retries on RETRY
+// or any non-2xx code and discards the message on DROP.
+func writeAck(w http.ResponseWriter, code int, daprStatus, outcome, reason string) {
+	response := map[string]string{"status": daprStatus, "outcome": outcome}
+	if reason != "" {
+		response["reason"] = reason
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
 }
 
 // POST /flight-update - Dapr Pub/Sub subscription endpoint
 func (at *AirportTracker) handleFlightUpdate(w http.ResponseWriter, r *http.Request) {
-	// Dapr sends CloudEvents format - decode the raw body first
-	var rawBody map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&rawBody); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to decode request: %v", err), http.StatusBadRequest)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		// The body may have been cut off in transit, so a redelivery can succeed
+		writeAck(w, http.StatusInternalServerError, DaprRetry, OutcomeRetry, fmt.Sprintf("failed to read request: %v", err))
+		return
+	}
+	
+	if reason := at.eventFilter.mismatch(body); reason != "" {
+		slog.Warn("rejected misrouted event", "remote_addr", r.RemoteAddr, "reason", reason)
+		writeAck(w, http.StatusBadRequest, DaprDrop, OutcomeRejected, reason)
+		return
+	}
+	
+	flights, err := at.extractFlightUpdates(r, body)
+	if err == nil && len(flights) == 0 {
+		err = errors.New("event carries no flight updates")
+	}
+	if err != nil {
+		at.metrics.decodeErrors.Inc()
+		// Malformed messages will never succeed, so tell Dapr to drop them
+		writeAck(w, http.StatusOK, DaprDrop, OutcomeRejected, err.Error())
 		return
 	}
 	
-	var flight FlightUpdate
-	var dataBytes []byte
-	var err error
+	// An event carrying several updates is redelivered as a whole if any
+	// fails with a retryable error; reprocessing an update is harmless. An
+	// update that panics would panic again on every redelivery, so it is
+	// counted as rejected and the rest of the event is still processed.
+	ctx := eventTraceContext(r, body)
+	outcomes := make([]processOutcome, 0, len(flights))
+	for _, flight := range flights {
+		outcome, ingestErr := at.ingest(ctx, flight)
+		var panicErr *processingPanicError
+		if errors.As(ingestErr, &panicErr) {
+			outcome = processOutcome{result: OutcomeRejected, reason: ingestErr.Error()}
+		} else if ingestErr != nil {
+			err = ingestErr
+			break
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	outcome := processOutcome{}
+	if err == nil {
+		outcome = summarizeOutcomes(outcomes)
+	}
+	switch {
+	case errors.Is(err, errQueueFull):
+		writeAck(w, http.StatusTooManyRequests, DaprRetry, OutcomeRetry, err.Error())
+	case err != nil:
+		writeAck(w, http.StatusInternalServerError, DaprRetry, OutcomeRetry, err.Error())
+	case outcome.result == OutcomeRejected:
+		writeAck(w, http.StatusOK, DaprDrop, OutcomeRejected, outcome.reason)
+	default:
+		writeAck(w, http.StatusOK, DaprSuccess, outcome.result, outcome.reason)
+	}
+}
+
+// errQueueFull is returned by ingest when the ingestion queue has no room
+var errQueueFull = errors.New("ingestion queue is full")
+
+// processingPanicError is returned by processFlightUpdate when processing an
+// update panicked. Retrying the update would panic again.
+type processingPanicError struct {
+	icao24 string
+	value  interface{}
+}
+
+func (e *processingPanicError) Error() string {
+	return fmt.Sprintf("panic processing flight %s: %v", e.icao24, e.value)
+}
+
+// ingest checks a decoded update against the schema and then queues or
+// processes it. Updates refused by schema validation come back as rejected.
+func (at *AirportTracker) ingest(ctx context.Context, flight FlightUpdate) (processOutcome, error) {
+	if violations := at.schema.Validate(flight); len(violations) > 0 {
+		if at.schema.mode == SchemaValidationReject {
+			return processOutcome{result: OutcomeRejected, reason: "schema violations: " + strings.Join(violations, ", ")}, nil
+		}
+		slog.Warn("flight violates schema", "icao24", flight.ICAO24, "violations", violations)
+	}
+	
+	if at.queue != nil {
+		if !at.queue.Enqueue(ctx, flight) {
+			return processOutcome{}, errQueueFull
+		}
+		return processOutcome{result: OutcomeQueued}, nil
+	}
+	return at.processFlightUpdate(ctx, flight)
+}
+
+// extractEventData returns the JSON payload of a CloudEvent body. The data
+// field can be a JSON string, an object or an array; data_base64 is decoded,
+// and a body with neither is treated as the payload itself.
+func (at *AirportTracker) extractEventData(r *http.Request, body []byte) ([]byte, error) {
+	// A bare array cannot be a CloudEvent, so it is the payload
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		return trimmed, nil
+	}
+	
+	// Dapr sends CloudEvents format - decode the raw body first
+	var rawBody map[string]interface{}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&rawBody); err != nil {
+		at.logRejectedBody(r, "decode", body, err)
+		return nil, fmt.Errorf("failed to decode request: %v", err)
+	}
 	
-	// Extract flight data from CloudEvents format
-	// The data field can be a string (JSON) or an object
 	if dataVal, ok := rawBody["data"]; ok {
 		switch v := dataVal.(type) {
 		case string:
 			// Data is a JSON string
-			dataBytes = []byte(v)
-		case map[string]interface{}:
-			// Data is already an object
-			dataBytes, err = json.Marshal(v)
+			return []byte(v), nil
+		case map[string]interface{}, []interface{}:
+			// Data is already an object or array
+			dataBytes, err := json.Marshal(v)
 			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to marshal data: %v", err), http.StatusBadRequest)
-				return
+				return nil, fmt.Errorf("failed to marshal data: %v", err)
 			}
+			return dataBytes, nil
 		default:
-			http.Error(w, fmt.Sprintf("Unexpected data type: %T", v), http.StatusBadRequest)
-			return
+			return nil, fmt.Errorf("unexpected data type: %T", v)
 		}
-		
-		if err := json.Unmarshal(dataBytes, &flight); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to unmarshal flight data: %v", err), http.StatusBadRequest)
-			return
-		}
-	} else if dataBase64, ok := rawBody["data_base64"].(string); ok {
+	}
+	if dataBase64, ok := rawBody["data_base64"].(string); ok {
 		// Handle base64 encoded data (unlikely but possible)
 		decoded, err := base64.StdEncoding.DecodeString(dataBase64)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to decode base64 data: %v", err), http.StatusBadRequest)
-			return
+			at.logRejectedBody(r, "decode data_base64", body, err)
+			return nil, fmt.Errorf("failed to decode base64 data: %v", err)
 		}
-		if err := json.Unmarshal(decoded, &flight); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to unmarshal flight data: %v", err), http.StatusBadRequest)
-			return
-		}
-	} else {
-		// Try to decode the entire body as flight data (fallback)
-		bodyBytes, _ := json.Marshal(rawBody)
-		if err := json.Unmarshal(bodyBytes, &flight); err != nil {
-			http.Error(w, "No data field in CloudEvent and body is not flight data", http.StatusBadRequest)
-			return
+		// Some Dapr configurations wrap the original CloudEvent again
+		if hasEventData(decoded) {
+			return at.extractEventData(r, decoded)
 		}
+		return decoded, nil
 	}
-	
-	at.processFlightUpdate(flight)
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	// No data field: treat the entire body as the payload (fallback)
+	return body, nil
+}
+
+// hasEventData reports whether payload is a JSON object carrying a data or
+// data_base64 field, i.e. a CloudEvent rather than a flight
+func hasEventData(payload []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return false
+	}
+	_, hasData := fields["data"]
+	_, hasBase64 := fields["data_base64"]
+	return hasData || hasBase64
 }
 
-// GET /health - Health check endpoint
+// GET /health/live (and /health) - Liveness probe; healthy while the process serves requests
 func (at *AirportTracker) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -235,133 +1278,492 @@ func (at *AirportTracker) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GET /api/v1/ingest/stats - Ingestion counters and queue backlog
+func (at *AirportTracker) handleIngestStats(w http.ResponseWriter, r *http.Request) {
+	queue := map[string]interface{}{"enabled": at.queue != nil}
+	if at.queue != nil {
+		queue["depth"] = at.queue.Depth()
+		queue["capacity"] = at.queue.Capacity()
+		queue["dropped"] = at.queue.dropped.Load()
+		queue["coalesced"] = at.queue.coalesced.Load()
+	}
+	
+	statusChanges := map[string]interface{}{"enabled": at.statusChanges != nil}
+	if at.statusChanges != nil {
+		statusChanges["published"] = at.statusChanges.published.Load()
+		statusChanges["retried"] = at.statusChanges.retried.Load()
+		statusChanges["failed"] = at.statusChanges.failed.Load()
+		statusChanges["dropped"] = at.statusChanges.dropped.Load()
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"processing_panics": at.stats.processingPanics.Load(),
+		"no_position_fix":   at.stats.noPositionFix.Load(),
+		"schema_violations": at.schema.ViolationCounts(),
+		"queue":             queue,
+		"status_changes":    statusChanges,
+	})
+}
+
+// GET /api/v1/stats - Tracked flights by status, configured airports,
+// updates processed and uptime, for quick checks without Prometheus
+func (at *AirportTracker) handleStats(w http.ResponseWriter, r *http.Request) {
+	byStatus := map[string]int{StatusArriving: 0, StatusDeparting: 0, StatusNearby: 0, StatusOnGround: 0}
+	aircraft := at.uniqueAircraft(at.listFlights(nil))
+	for _, flight := range aircraft {
+		byStatus[flight.Status]++
+	}
+	total := len(aircraft)
+	uptime := time.Since(at.stats.startedAt)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tracked_flights":   total,
+		"by_status":         byStatus,
+		"airports":          len(at.airportList()),
+		"updates_processed": at.stats.updatesProcessed.Load(),
+		"started_at":        at.stats.startedAt,
+		"uptime_seconds":    uptime.Seconds(),
+	})
+}
+
+// POST /api/v1/config/reload - Re-read the airport config
+func (at *AirportTracker) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if err := at.reloadConfig(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "reloaded",
+		"airports": len(at.airportList()),
+	})
+}
+
 // GET /api/v1/airports - List all monitored airports
 func (at *AirportTracker) handleListAirports(w http.ResponseWriter, r *http.Request) {
-	at.flightsMutex.RLock()
-	defer at.flightsMutex.RUnlock()
-	
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(at.airports)
+	json.NewEncoder(w).Encode(at.airportList())
 }
 
-// GET /api/v1/airports/{code}/arrivals - Get flights arriving at airport
+// GET /api/v1/airports/{code} - Get one airport's config; the code is
+// matched case-insensitively
+func (at *AirportTracker) handleGetAirport(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	airport, ok := at.airportByCode(strings.TrimSpace(code))
+	if !ok {
+		at.metrics.unknownAirports.Inc()
+		http.Error(w, fmt.Sprintf("Unknown airport %q", code), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(airport)
+}
+
+// GET /api/v1/airports/{code}/arrivals - Get flights arriving at airport,
+// closest first unless ?sort= says otherwise. {code} may list several
+// airports; see parseAirportSelection
 func (at *AirportTracker) handleArrivals(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	airportCode := vars["code"]
 	
-	at.flightsMutex.RLock()
-	defer at.flightsMutex.RUnlock()
+	opts, err := at.parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	
-	arrivals := []TrackedFlight{}
-	for _, flight := range at.flights {
-		if flight.AirportCode == airportCode && flight.Status == "arriving" {
-			arrivals = append(arrivals, *flight)
-		}
+	selected, err := at.parseAirportSelection(r, airportCode)
+	if err != nil {
+		at.writeSelectionError(w, err)
+		return
+	}
+	sortKey, err := parseSortKey(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"airport_code": airportCode,
-		"arrivals":     arrivals,
-		"count":        len(arrivals),
+	arrivals := at.listFlights(func(flight *TrackedFlight) bool {
+		return opts.matches(flight) && selected[strings.ToUpper(flight.AirportCode)] && flight.Status == StatusArriving &&
+			!at.excludedAsGrounded(flight)
 	})
+	
+	sortFlights(arrivals, sortKey)
+	arrivals, truncation := truncateFlights(arrivals, opts.limit)
+	at.decorateFlights(arrivals, opts)
+	
+	response := map[string]interface{}{
+		"airport_code":  airportCode,
+		"airport_codes": selected.Codes(),
+		"arrivals":      arrivals,
+		"count":         len(arrivals),
+		"units":         opts.units,
+		"sort":          sortKey,
+	}
+	truncation.annotate(response)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-// GET /api/v1/airports/{code}/departures - Get flights departing from airport
+// GET /api/v1/airports/{code}/departures - Get flights departing from
+// airport, closest first unless ?sort= says otherwise
 func (at *AirportTracker) handleDepartures(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	airportCode := vars["code"]
 	
-	at.flightsMutex.RLock()
-	defer at.flightsMutex.RUnlock()
+	opts, err := at.parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	
-	departures := []TrackedFlight{}
-	for _, flight := range at.flights {
-		if flight.AirportCode == airportCode && flight.Status == "departing" {
-			departures = append(departures, *flight)
-		}
+	selected, err := at.parseAirportSelection(r, airportCode)
+	if err != nil {
+		at.writeSelectionError(w, err)
+		return
+	}
+	sortKey, err := parseSortKey(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"airport_code": airportCode,
-		"departures":   departures,
-		"count":        len(departures),
+	departures := at.listFlights(func(flight *TrackedFlight) bool {
+		return opts.matches(flight) && selected[strings.ToUpper(flight.AirportCode)] && flight.Status == StatusDeparting &&
+			!at.excludedAsGrounded(flight)
 	})
+	
+	sortFlights(departures, sortKey)
+	departures, truncation := truncateFlights(departures, opts.limit)
+	at.decorateFlights(departures, opts)
+	
+	response := map[string]interface{}{
+		"airport_code":  airportCode,
+		"airport_codes": selected.Codes(),
+		"departures":    departures,
+		"count":         len(departures),
+		"units":         opts.units,
+		"sort":          sortKey,
+	}
+	truncation.annotate(response)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-// GET /api/v1/airports/{code}/nearby - Get all flights near airport
+// GET /api/v1/airports/{code}/nearby - Get all flights near airport,
+// optionally within ?min_alt=&max_alt= meters
 func (at *AirportTracker) handleNearby(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	airportCode := vars["code"]
 	
-	at.flightsMutex.RLock()
-	defer at.flightsMutex.RUnlock()
+	opts, err := at.parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	
-	nearby := []TrackedFlight{}
-	for _, flight := range at.flights {
-		if flight.AirportCode == airportCode {
-			nearby = append(nearby, *flight)
-		}
+	selected, err := at.parseAirportSelection(r, airportCode)
+	if err != nil {
+		at.writeSelectionError(w, err)
+		return
 	}
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"airport_code": airportCode,
-		"flights":      nearby,
-		"count":        len(nearby),
+	band, err := parseAltitudeBand(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	nearby := at.listFlights(func(flight *TrackedFlight) bool {
+		return opts.matches(flight) && selected[strings.ToUpper(flight.AirportCode)] && band.contains(flight)
 	})
+	
+	nearby, truncation := capFlights(nearby, opts.limit)
+	at.decorateFlights(nearby, opts)
+	
+	response := map[string]interface{}{
+		"airport_code":  airportCode,
+		"airport_codes": selected.Codes(),
+		"flights":       nearby,
+		"count":         len(nearby),
+		"units":         opts.units,
+	}
+	truncation.annotate(response)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-// GET /api/v1/flights/all - Get all tracked flights from all airports
+// GET /api/v1/flights/all?limit=&offset= - Get a page of tracked flights
+// from all airports, ordered by ICAO24
 func (at *AirportTracker) handleAllFlights(w http.ResponseWriter, r *http.Request) {
-	at.flightsMutex.RLock()
-	defer at.flightsMutex.RUnlock()
+	opts, err := at.parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pg, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	// Optional ?bbox=minLon,minLat,maxLon,maxLat; minLon > maxLon crosses the antimeridian
+	match := opts.matches
+	if raw := r.URL.Query().Get("bbox"); raw != "" {
+		box, err := parseBoundingBox(raw)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		match = func(flight *TrackedFlight) bool {
+			return opts.matches(flight) && box.Contains(flight.Latitude, flight.Longitude)
+		}
+	}
+	
+	allFlights := at.uniqueAircraft(at.listFlights(match))
+	total := len(allFlights)
 	
-	allFlights := []TrackedFlight{}
-	for _, flight := range at.flights {
-		allFlights = append(allFlights, *flight)
+	// ?max= and MAX_RESPONSE_FLIGHTS still bound the page size
+	if opts.limit > 0 && pg.limit > opts.limit {
+		pg.limit = opts.limit
 	}
+	allFlights = paginate(allFlights, pg)
+	at.decorateFlights(allFlights, opts)
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	// As on the other list endpoints, truncated says flights were left out:
+	// here, that pages follow this one
+	response := map[string]interface{}{
 		"flights": allFlights,
 		"count":   len(allFlights),
-	})
+		"total":   total,
+		"limit":   pg.limit,
+		"offset":  pg.offset,
+		"units":   opts.units,
+	}
+	truncation{truncated: pg.offset+len(allFlights) < total, total: total}.annotate(response)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /api/v1/flights/by-status - Get tracked flights bucketed by status
+func (at *AirportTracker) handleFlightsByStatus(w http.ResponseWriter, r *http.Request) {
+	airportCode := strings.TrimSpace(r.URL.Query().Get("airport"))
+	if airportCode != "" {
+		airport, ok := at.airportByCode(airportCode)
+		if !ok {
+			at.metrics.unknownAirports.Inc()
+			http.Error(w, fmt.Sprintf("Unknown airport %q", airportCode), http.StatusNotFound)
+			return
+		}
+		airportCode = airport.ICAO
+	}
+	
+	// Aircraft on the ground get their own bucket regardless of status
+	buckets := map[string][]TrackedFlight{
+		StatusArriving:  {},
+		StatusDeparting: {},
+		StatusNearby:    {},
+		onGroundBucket:  {},
+	}
+	// Buckets come from one consistent snapshot, and across airports each
+	// aircraft is counted once; see uniqueAircraft
+	flights := at.snapshotFlights()
+	if airportCode == "" {
+		flights = at.uniqueAircraft(flights)
+	}
+	for _, flight := range flights {
+		if airportCode != "" && !strings.EqualFold(flight.AirportCode, airportCode) {
+			continue
+		}
+		bucket := flight.Status
+		if flight.OnGround {
+			bucket = onGroundBucket
+		}
+		buckets[bucket] = append(buckets[bucket], flight)
+	}
+	
+	counts := make(map[string]int, len(buckets))
+	total := 0
+	for bucket, flights := range buckets {
+		counts[bucket] = len(flights)
+		total += len(flights)
+	}
+	
+	response := map[string]interface{}{
+		"counts": counts,
+		"count":  total,
+	}
+	for bucket, flights := range buckets {
+		response[bucket] = flights
+	}
+	if airportCode != "" {
+		response["airport_code"] = airportCode
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /api/v1/flights/{icao24} - Get a single tracked flight
+func (at *AirportTracker) handleFlightDetail(w http.ResponseWriter, r *http.Request) {
+	icao24 := normalizeICAO24(mux.Vars(r)["icao24"])
+	
+	opts, err := at.parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	flight, ok := at.lookupFlight(icao24)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Flight %s is not tracked", icao24), http.StatusNotFound)
+		return
+	}
+	flights := []TrackedFlight{flight}
+	at.decorateFlights(flights, opts)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flights[0])
+}
+
+// DELETE /api/v1/flights/{icao24} - Stop tracking a flight at every airport
+func (at *AirportTracker) handleDeleteFlight(w http.ResponseWriter, r *http.Request) {
+	icao24 := normalizeICAO24(mux.Vars(r)["icao24"])
+	if !at.deleteFlight(icao24) {
+		http.Error(w, fmt.Sprintf("Flight %s is not tracked", icao24), http.StatusNotFound)
+		return
+	}
+	slog.Info("flight deleted", "icao24", icao24, "remote_addr", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func main() {
-	configPath := os.Getenv("AIRPORT_CONFIG_PATH")
-	if configPath == "" {
-		configPath = DefaultConfigPath
+	setupLogging()
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		fatal("failed to set up tracing", "error", err)
 	}
 	
+	configPath := envString("AIRPORT_CONFIG_PATH", DefaultConfigPath)
+	
 	tracker, err := NewAirportTracker(configPath)
 	if err != nil {
-		log.Fatalf("Failed to initialize airport tracker: %v", err)
+		fatal("failed to initialize airport tracker", "error", err)
 	}
 	
 	router := mux.NewRouter()
+	router.Use(routeSpanNames)
+	router.Use(compressResponses(envInt("GZIP_MIN_BYTES", defaultGzipMinBytes)))
+	
+	// Write and admin endpoints require API_KEY when it is set
+	apiKey := envString("API_KEY", "")
+	protected := func(h http.HandlerFunc) http.Handler { return requireAPIKey(apiKey, h) }
+	
+	// Ingestion endpoints share INGEST_RATE_LIMIT. The limit applies after
+	// the API key check so unauthenticated requests cannot use up the budget.
+	ingestLimiter := newIngestLimiterFromEnv()
+	limited := func(h http.HandlerFunc) http.HandlerFunc { return limitIngest(ingestLimiter, h).ServeHTTP }
 	
-	// Dapr Pub/Sub subscription endpoint
-	router.HandleFunc("/flight-update", tracker.handleFlightUpdate).Methods("POST")
+	// Dapr Pub/Sub subscription endpoints
+	router.HandleFunc("/dapr/subscribe", handleDaprSubscribe(daprSubscriptionsFromEnv())).Methods("GET")
+	router.Handle(flightUpdateRoute, protected(limited(tracker.handleFlightUpdate))).Methods("POST")
 	
-	// Health check
+	// Health checks; /health and /ready are kept as aliases
+	router.HandleFunc("/health/live", tracker.handleHealth).Methods("GET")
+	router.HandleFunc("/health/ready", tracker.handleReady).Methods("GET")
 	router.HandleFunc("/health", tracker.handleHealth).Methods("GET")
+	router.HandleFunc("/ready", tracker.handleReady).Methods("GET")
+	router.Handle("/metrics", tracker.metrics.Handler()).Methods("GET")
 	
 	// REST API endpoints
 	router.HandleFunc("/api/v1/airports", tracker.handleListAirports).Methods("GET")
+	router.HandleFunc("/api/v1/airports/nearest", tracker.handleNearestAirport).Methods("GET")
+	router.HandleFunc("/api/v1/airports/{code}", tracker.handleGetAirport).Methods("GET") // after /airports/nearest
 	router.HandleFunc("/api/v1/airports/{code}/arrivals", tracker.handleArrivals).Methods("GET")
 	router.HandleFunc("/api/v1/airports/{code}/departures", tracker.handleDepartures).Methods("GET")
 	router.HandleFunc("/api/v1/airports/{code}/nearby", tracker.handleNearby).Methods("GET")
+	router.HandleFunc("/api/v1/airports/{code}/daily-summary", tracker.handleDailySummary).Methods("GET")
+	router.HandleFunc("/api/v1/airports/{code}/summary", tracker.handleAirportSummary).Methods("GET")
 	router.HandleFunc("/api/v1/flights/all", tracker.handleAllFlights).Methods("GET")
+	router.HandleFunc("/api/v1/map", tracker.handleMap).Methods("GET")
+	router.HandleFunc("/api/v1/flights/by-status", tracker.handleFlightsByStatus).Methods("GET")
+	router.HandleFunc("/api/v1/flights/proximity", tracker.handleProximity).Methods("GET")
+	router.HandleFunc("/api/v1/flights/near", tracker.handleFlightsNear).Methods("GET")
+	router.HandleFunc("/api/v1/flights/emergencies", tracker.handleEmergencies).Methods("GET")
+	router.HandleFunc("/api/v1/flights/stream", tracker.handleFlightStream).Methods("GET")
+	router.HandleFunc("/api/v1/flights/events", tracker.handleStatusEvents).Methods("GET")
+	router.HandleFunc("/api/v1/geofence-check", tracker.handleGeofenceCheck).Methods("GET")
+	router.HandleFunc("/api/v1/flights/altitude-histogram", tracker.handleAltitudeHistogram).Methods("GET")
+	router.HandleFunc("/api/v1/flights/countries", tracker.handleFlightCountries).Methods("GET")
+	router.Handle("/api/v1/flights/batch", protected(limited(tracker.handleFlightBatch))).Methods("POST")
+	router.HandleFunc("/api/v1/flights/{icao24}", tracker.handleFlightDetail).Methods("GET") // after the fixed /flights/ routes
+	router.Handle("/api/v1/flights/{icao24}", protected(tracker.handleDeleteFlight)).Methods("DELETE")
+	router.HandleFunc("/api/v1/flights/{icao24}/track", tracker.handleFlightTrack).Methods("GET")
+	router.HandleFunc("/api/v1/flights/{icao24}/predict", tracker.handleFlightPredict).Methods("GET")
+	router.HandleFunc("/api/v1/ingest/stats", tracker.handleIngestStats).Methods("GET")
+	router.HandleFunc("/api/v1/stats", tracker.handleStats).Methods("GET")
+	router.HandleFunc("/api/v1/schema/{type}", handleSchema).Methods("GET")
 	
-	log.Printf("🚀 Airport Tracker service listening on port %s", Port)
-	log.Printf("📡 Subscribing to flight-update topic via Dapr Pub/Sub")
-	log.Printf("📍 Tracking %d airports", len(tracker.airports))
+	// Effective configuration is opt-in as it reveals deployment details
+	if envBool("CONFIG_ENDPOINT_ENABLED", false) {
+		router.HandleFunc("/api/v1/config/effective", handleEffectiveConfig).Methods("GET")
+	}
+	router.Handle("/api/v1/config/reload", protected(tracker.handleConfigReload)).Methods("POST")
+	router.Handle("/api/v1/airports/{code}/enable", protected(tracker.handleSetAirportEnabled(true))).Methods("POST")
+	router.Handle("/api/v1/airports/{code}/disable", protected(tracker.handleSetAirportEnabled(false))).Methods("POST")
+	if envBool("MAINTENANCE_ENDPOINTS_ENABLED", false) {
+		router.Handle("/api/v1/maintenance/sweep", protected(tracker.handleMaintenanceSweep)).Methods("POST")
+	}
+	
+	// The state dump exposes every flight and the airport config, so it is opt-in too
+	if envBool("DEBUG_ENDPOINTS_ENABLED", false) {
+		router.Handle("/api/v1/debug/state", protected(tracker.handleDebugState)).Methods("GET")
+	}
 	
-	if err := http.ListenAndServe(Port, router); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	addr := listenAddress()
+	slog.Info("airport tracker listening", "address", addr, "airports", len(tracker.airportList()), "topic", flightUpdateTopic)
+	
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	
+	// SIGHUP reloads the airport config without a restart
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			tracker.reloadConfig()
+		}
+	}()
+	
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fatal("failed to listen", "address", addr, "error", err)
+	}
+	cors := corsPolicyFromEnv()
+	streamUpgrader.CheckOrigin = cors.checkOrigin
+	server := &http.Server{Handler: traceHandler(cors.middleware(router))}
+	grace := envSeconds("SHUTDOWN_GRACE_SECONDS", defaultShutdownGrace)
+	err = serve(ctx, server, listener, grace)
+	
+	// Stop the sweeper and drain the ingest queue once no more requests can
+	// arrive, allowing the drain the same grace as the requests
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), grace)
+	tracker.Shutdown(drainCtx)
+	cancelDrain()
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
+	cancel()
+	if err != nil {
+		fatal("server failed", "error", err)
 	}
+	slog.Info("shutdown complete")
 }
 
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func decodeAck(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	var ack map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &ack); err != nil {
+		t.Fatalf("decoding ack %q: %v", rec.Body.String(), err)
+	}
+	return ack
+}
+
+func TestPanicWhileProcessingIsDropped(t *testing.T) {
+	at := newTestTracker(t)
+	at.geofenceDistance = func(lat1, lon1, lat2, lon2 float64) float64 { panic("boom") }
+
+	_, err := at.processFlightUpdate(context.Background(), testUpdate("abc123", 40, -73))
+	var panicErr *processingPanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("err = %v, want a *processingPanicError", err)
+	}
+
+	body, _ := json.Marshal(testUpdate("abc123", 40, -73))
+	rec := httptest.NewRecorder()
+	at.handleFlightUpdate(rec, httptest.NewRequest(http.MethodPost, "/flight-update", strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Errorf("code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ack := decodeAck(t, rec); ack["status"] != DaprDrop || ack["outcome"] != OutcomeRejected {
+		t.Errorf("ack = %v, want DROP/rejected", ack)
+	}
+	if got := at.stats.processingPanics.Load(); got != 2 {
+		t.Errorf("processing_panics = %d, want 2", got)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestUnreadableBodyIsRetried(t *testing.T) {
+	at := newTestTracker(t)
+	rec := httptest.NewRecorder()
+	at.handleFlightUpdate(rec, httptest.NewRequest(http.MethodPost, "/flight-update", failingReader{}))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("code = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if ack := decodeAck(t, rec); ack["status"] != DaprRetry {
+		t.Errorf("status = %q, want %q", ack["status"], DaprRetry)
+	}
+}
+
+func TestPanicIsContainedAndCounted(t *testing.T) {
+	at := newTestTracker(t)
+	exact := at.geofenceDistance
+	at.geofenceDistance = func(lat1, lon1, lat2, lon2 float64) float64 {
+		if lat1 == 40.0501 {
+			panic("crafted update")
+		}
+		return exact(lat1, lon1, lat2, lon2)
+	}
+
+	if _, err := at.processFlightUpdate(context.Background(), testUpdate("bad001", 40.0501, -73)); err == nil {
+		t.Fatal("panic was not reported")
+	}
+	// Ingestion carries on with the next update
+	track(t, at, testUpdate("good01", 40.05, -73))
+	if _, ok := at.flights.Get("good01"); !ok {
+		t.Error("update after the panic was not tracked")
+	}
+
+	var stats map[string]interface{}
+	decodeBody(t, call(at.handleIngestStats, http.MethodGet, "/api/v1/ingest/stats", nil), &stats)
+	if stats["processing_panics"] != 1.0 {
+		t.Errorf("processing_panics = %v, want 1", stats["processing_panics"])
+	}
+}
+
+// storeFlight puts a flight straight into the local store
+func storeFlight(at *AirportTracker, flight TrackedFlight) {
+	at.flights.Update(at.flightKey(flight.ICAO24, flight.AirportCode), func(*TrackedFlight) *TrackedFlight { return &flight })
+}
+
+func TestFlightsByStatusBuckets(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	for _, flight := range []TrackedFlight{
+		{FlightUpdate: FlightUpdate{ICAO24: "a1"}, AirportCode: "KAAA", Status: StatusArriving},
+		{FlightUpdate: FlightUpdate{ICAO24: "a2"}, AirportCode: "KAAA", Status: StatusArriving},
+		{FlightUpdate: FlightUpdate{ICAO24: "d1"}, AirportCode: "KAAA", Status: StatusDeparting},
+		{FlightUpdate: FlightUpdate{ICAO24: "n1"}, AirportCode: "KBBB", Status: StatusNearby},
+		{FlightUpdate: FlightUpdate{ICAO24: "g1", OnGround: true}, AirportCode: "KBBB", Status: StatusNearby},
+	} {
+		storeFlight(at, flight)
+	}
+
+	type response struct {
+		Counts    map[string]int  `json:"counts"`
+		Count     int             `json:"count"`
+		Arriving  []TrackedFlight `json:"arriving"`
+		OnGround  []TrackedFlight `json:"on_ground"`
+		Departing []TrackedFlight `json:"departing"`
+	}
+	var all response
+	decodeBody(t, call(at.handleFlightsByStatus, http.MethodGet, "/api/v1/flights/by-status", nil), &all)
+	want := map[string]int{StatusArriving: 2, StatusDeparting: 1, StatusNearby: 1, onGroundBucket: 1}
+	for bucket, n := range want {
+		if all.Counts[bucket] != n {
+			t.Errorf("counts[%s] = %d, want %d", bucket, all.Counts[bucket], n)
+		}
+	}
+	if all.Count != 5 || len(all.Arriving) != 2 || len(all.OnGround) != 1 || all.OnGround[0].ICAO24 != "g1" {
+		t.Errorf("count %d, %d arriving, on_ground %v", all.Count, len(all.Arriving), all.OnGround)
+	}
+
+	var filtered response
+	decodeBody(t, call(at.handleFlightsByStatus, http.MethodGet, "/api/v1/flights/by-status?airport=KAAA", nil), &filtered)
+	if filtered.Count != 3 || filtered.Counts[StatusNearby] != 0 || len(filtered.Departing) != 1 {
+		t.Errorf("?airport=KAAA: count %d, counts %v", filtered.Count, filtered.Counts)
+	}
+
+	var lower response
+	decodeBody(t, call(at.handleFlightsByStatus, http.MethodGet, "/api/v1/flights/by-status?airport=kaaa", nil), &lower)
+	if lower.Count != 3 || len(lower.Departing) != 1 {
+		t.Errorf("?airport=kaaa: count %d, counts %v; want KAAA matched case-insensitively", lower.Count, lower.Counts)
+	}
+
+	if rec := call(at.handleFlightsByStatus, http.MethodGet, "/api/v1/flights/by-status?airport=KZZZ", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("?airport=KZZZ: code = %d, want 404", rec.Code)
+	}
+}
+
+func TestLastSeenInAirportTimezone(t *testing.T) {
+	tokyo := testAirport("RJTT", 35.55, 139.78)
+	tokyo.Timezone = "Asia/Tokyo"
+	at := newTestTracker(t, tokyo, testAirport("KTST", 40, -73))
+	instant := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	at.clock = func() time.Time { return instant }
+
+	for _, update := range []FlightUpdate{testUpdate("aaa001", 35.55, 139.78), testUpdate("bbb002", 40, -73)} {
+		update.TimePosition, update.LastContact = instant.Unix(), instant.Unix()
+		track(t, at, update)
+	}
+	for icao24, want := range map[string]string{
+		"aaa001": "2024-01-15T21:00:00+09:00",
+		"bbb002": "2024-01-15T12:00:00Z", // no timezone configured
+	} {
+		flight, ok := at.flights.Get(icao24)
+		if !ok {
+			t.Fatalf("%s not tracked", icao24)
+		}
+		if flight.LastSeenLocal != want {
+			t.Errorf("%s last_seen_local = %s, want %s", icao24, flight.LastSeenLocal, want)
+		}
+	}
+}
+
+func TestClassifyStatus(t *testing.T) {
+	airport := testAirport("KTST", 40, -73) // arrival below 3000 m, departure below 2000 m
+	for _, tc := range []struct {
+		name     string
+		altitude *float64
+		rate     *float64
+		onGround bool
+		want     string
+	}{
+		{"descending low", ptr(1500.0), ptr(-5.0), false, StatusArriving},
+		{"descending high", ptr(3500.0), ptr(-5.0), false, StatusNearby},
+		{"climbing low", ptr(800.0), ptr(8.0), false, StatusDeparting},
+		{"climbing between thresholds", ptr(2500.0), ptr(8.0), false, StatusNearby},
+		{"climbing high", ptr(5000.0), ptr(8.0), false, StatusNearby},
+		{"level low", ptr(1000.0), ptr(0.5), false, StatusNearby},
+		{"no vertical rate", ptr(1000.0), nil, false, StatusNearby},
+		{"no altitude", nil, ptr(-5.0), false, StatusNearby},
+		{"on ground", ptr(0.0), ptr(-5.0), true, StatusNearby},
+	} {
+		update := FlightUpdate{BaroAltitude: tc.altitude, VerticalRate: tc.rate, OnGround: tc.onGround}
+		altitude, ok := effectiveAltitude(update)
+		if got := classifyStatus(update, altitude, ok, airport); got != tc.want {
+			t.Errorf("%s: status %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestUpdatesWithoutPositionFixAreDropped(t *testing.T) {
+	logs := captureLogs(t, "debug")
+	at := newTestTracker(t, testAirport("DNMM", 0.2, 0.3)) // (0, 0) is in its geofence
+	noTime := testUpdate("aaa003", 0.5, 0.5)
+	noTime.TimePosition = 0
+	for _, tc := range []struct {
+		name   string
+		update FlightUpdate
+		reason string
+	}{
+		{"null island", testUpdate("aaa001", 0, 0), "no position fix (0, 0)"},
+		{"latitude out of range", testUpdate("aaa002", 91, 0.5), "latitude out of range"},
+		{"longitude out of range", testUpdate("aaa004", 0.5, -180.5), "longitude out of range"},
+		{"no time_position", noTime, "no position fix (time_position is 0)"},
+	} {
+		outcome, err := at.processFlightUpdate(context.Background(), tc.update)
+		if err != nil || outcome.result != OutcomeSkipped || outcome.reason != tc.reason {
+			t.Errorf("%s: outcome %+v, err %v; want skipped with %q", tc.name, outcome, err, tc.reason)
+		}
+	}
+	if n := at.stats.noPositionFix.Load(); n != 4 {
+		t.Errorf("no_position_fix = %d, want 4", n)
+	}
+	if at.flights.Len() != 0 {
+		t.Errorf("%d flights tracked from updates without a fix", at.flights.Len())
+	}
+	if !strings.Contains(logs.String(), "dropping update without a position fix") {
+		t.Error("drop not logged at debug")
+	}
+
+	// On the equator but off the prime meridian is a real position
+	if outcome, err := at.processFlightUpdate(context.Background(), testUpdate("aaa005", 0, 0.5)); err != nil || outcome.result != OutcomeProcessed {
+		t.Errorf("valid update: outcome %+v, err %v", outcome, err)
+	}
+	if _, ok := at.flights.Get("aaa005"); !ok {
+		t.Error("valid update not tracked")
+	}
+}
+
+func TestICAO24AndCallsignNormalized(t *testing.T) {
+	at := newTestTracker(t)
+	for _, raw := range []struct{ icao24, callsign string }{
+		{"ABC123", "ual100  "},
+		{"  abc123 ", " UAL100"},
+		{"AbC123\t", "Ual100"},
+	} {
+		update := testUpdate(raw.icao24, 40.05, -73)
+		update.Callsign = raw.callsign
+		track(t, at, update)
+	}
+	if n := at.flights.Len(); n != 1 {
+		t.Fatalf("store entries = %d, want 1 for the same aircraft", n)
+	}
+	flight, ok := at.flights.Get("abc123")
+	if !ok || flight.ICAO24 != "abc123" || flight.Callsign != "UAL100" {
+		t.Errorf("flight = %q %q, want abc123 UAL100", flight.ICAO24, flight.Callsign)
+	}
+
+	// A blank callsign stays empty
+	blank := testUpdate("DEF456", 40.05, -73)
+	blank.Callsign = "        "
+	track(t, at, blank)
+	if flight, _ := at.flights.Get("def456"); flight.Callsign != "" {
+		t.Errorf("blank callsign stored as %q, want empty", flight.Callsign)
+	}
+}
+
+func TestVerticalPhase(t *testing.T) {
+	for _, tc := range []struct {
+		rate *float64
+		want string
+	}{
+		{ptr(8.0), PhaseClimbing},
+		{ptr(1.6), PhaseClimbing},
+		{ptr(1.5), PhaseLevel}, // the dead band is inclusive
+		{ptr(0.0), PhaseLevel},
+		{ptr(-1.5), PhaseLevel},
+		{ptr(-1.6), PhaseDescending},
+		{ptr(-8.0), PhaseDescending},
+		{nil, ""},
+	} {
+		if got := verticalPhase(tc.rate, defaultVerticalDeadBandMS); got != tc.want {
+			t.Errorf("rate %v: phase %q, want %q", tc.rate, got, tc.want)
+		}
+	}
+}
+
+func TestVerticalPhaseDeadBandConfigured(t *testing.T) {
+	t.Setenv("VERTICAL_PHASE_DEAD_BAND_MS", "3")
+	at := newTestTracker(t)
+	slow := testUpdate("abc123", 40.05, -73)
+	slow.VerticalRate = ptr(2.0)
+	noRate := testUpdate("def456", 40.05, -73)
+	track(t, at, slow, noRate)
+
+	rec := call(at.handleFlightDetail, http.MethodGet, "/api/v1/flights/abc123", map[string]string{"icao24": "abc123"})
+	var body map[string]interface{}
+	decodeBody(t, rec, &body)
+	if body["vertical_phase"] != PhaseLevel {
+		t.Errorf("vertical_phase = %v, want level inside a 3 m/s dead band", body["vertical_phase"])
+	}
+	rec = call(at.handleFlightDetail, http.MethodGet, "/api/v1/flights/def456", map[string]string{"icao24": "def456"})
+	body = nil
+	decodeBody(t, rec, &body)
+	if phase, ok := body["vertical_phase"]; ok {
+		t.Errorf("vertical_phase = %v without a vertical rate, want it omitted", phase)
+	}
+}
+
+func TestBase64EventData(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	now := time.Now().Unix()
+	flight := fmt.Sprintf(`{"icao24":"abc123","latitude":40.05,"longitude":-73,"time_position":%d,"last_contact":%d}`, now, now)
+	inner := `{"specversion":"1.0","id":"inner","data":` + flight + `}`
+	for _, tc := range []struct {
+		name string
+		body string
+	}{
+		{"plain base64 flight", `{"specversion":"1.0","id":"1","data_base64":"` + encode(flight) + `"}`},
+		{"base64 wrapped CloudEvent", `{"specversion":"1.0","id":"1","data_base64":"` + encode(inner) + `"}`},
+		{"base64 wrapped base64 CloudEvent", `{"specversion":"1.0","id":"1","data_base64":"` +
+			encode(`{"specversion":"1.0","id":"inner","data_base64":"`+encode(flight)+`"}`) + `"}`},
+	} {
+		at := newTestTracker(t)
+		rec := httptest.NewRecorder()
+		at.handleFlightUpdate(rec, httptest.NewRequest(http.MethodPost, "/flight-update", strings.NewReader(tc.body)))
+		if ack := decodeAck(t, rec); rec.Code != http.StatusOK || ack["outcome"] != OutcomeProcessed {
+			t.Errorf("%s: %d %v, want the flight processed", tc.name, rec.Code, ack)
+			continue
+		}
+		if flight, ok := at.flights.Get("abc123"); !ok || flight.AirportCode != "KTST" {
+			t.Errorf("%s: flight not tracked at KTST", tc.name)
+		}
+	}
+
+	at := newTestTracker(t)
+	rec := httptest.NewRecorder()
+	at.handleFlightUpdate(rec, httptest.NewRequest(http.MethodPost, "/flight-update", strings.NewReader(`{"data_base64":"not base64!"}`)))
+	if ack := decodeAck(t, rec); ack["status"] != DaprDrop || !strings.Contains(ack["reason"], "base64") {
+		t.Errorf("invalid base64: ack = %v, want DROP naming the base64 data", ack)
+	}
+}
+
+func TestFlightDetail(t *testing.T) {
+	at := newTestTracker(t)
+	update := testUpdate("abc123", 40.05, -73)
+	update.Callsign = "UAL100"
+	track(t, at, update)
+
+	for _, icao24 := range []string{"abc123", "ABC123", " AbC123 "} {
+		rec := call(at.handleFlightDetail, http.MethodGet, "/api/v1/flights/"+url.PathEscape(icao24), map[string]string{"icao24": icao24})
+		var flight TrackedFlight
+		decodeBody(t, rec, &flight)
+		if rec.Code != http.StatusOK || flight.ICAO24 != "abc123" || flight.Callsign != "UAL100" || flight.AirportCode != "KTST" {
+			t.Errorf("%q: %d %+v, want abc123 at KTST", icao24, rec.Code, flight)
+		}
+		if flight.AgeSeconds == nil {
+			t.Errorf("%q: age_seconds missing", icao24)
+		}
+	}
+
+	rec := call(at.handleFlightDetail, http.MethodGet, "/api/v1/flights/def456", map[string]string{"icao24": "DEF456"})
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "def456 is not tracked") {
+		t.Errorf("absent flight: %d %q, want 404", rec.Code, rec.Body)
+	}
+}
+
+func TestStatsAfterConcurrentUpdates(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				// Five aircraft per worker, each updated five times
+				update := testUpdate(fmt.Sprintf("%02x%04x", w, i%5), 40.05, -73)
+				if w == 0 {
+					update.BaroAltitude, update.VerticalRate = ptr(1000.0), ptr(-5.0)
+				}
+				if _, err := at.processFlightUpdate(context.Background(), update); err != nil {
+					t.Error(err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	track(t, at, testUpdate("ffffff", 0, 10)) // outside every geofence, still processed
+
+	var stats struct {
+		TrackedFlights   int            `json:"tracked_flights"`
+		ByStatus         map[string]int `json:"by_status"`
+		Airports         int            `json:"airports"`
+		UpdatesProcessed uint64         `json:"updates_processed"`
+		StartedAt        time.Time      `json:"started_at"`
+		UptimeSeconds    float64        `json:"uptime_seconds"`
+	}
+	decodeBody(t, call(at.handleStats, http.MethodGet, "/api/v1/stats", nil), &stats)
+	if stats.TrackedFlights != 20 || stats.Airports != 2 || stats.UpdatesProcessed != 101 {
+		t.Errorf("stats = %+v, want 20 flights, 2 airports, 101 updates", stats)
+	}
+	want := map[string]int{StatusArriving: 5, StatusDeparting: 0, StatusNearby: 15, StatusOnGround: 0}
+	if !reflect.DeepEqual(stats.ByStatus, want) {
+		t.Errorf("by_status = %v, want %v", stats.ByStatus, want)
+	}
+	if stats.StartedAt.IsZero() || stats.UptimeSeconds <= 0 || stats.UptimeSeconds > 60 {
+		t.Errorf("started_at %v, uptime %v s", stats.StartedAt, stats.UptimeSeconds)
+	}
+}
+
+func TestStatsReadSharedBackend(t *testing.T) {
+	client := newFakeRedis()
+	first, second := newTestTracker(t), newTestTracker(t)
+	for _, at := range []*AirportTracker{first, second} {
+		at.backend = &redisBackend{client: client, prefix: defaultRedisKeyPrefix, ttl: time.Minute}
+	}
+	track(t, first, testUpdate("abc123", 40.05, -73))
+
+	var stats struct {
+		TrackedFlights int            `json:"tracked_flights"`
+		ByStatus       map[string]int `json:"by_status"`
+	}
+	decodeBody(t, call(second.handleStats, http.MethodGet, "/api/v1/stats", nil), &stats)
+	if stats.TrackedFlights != 1 || stats.ByStatus[StatusNearby] != 1 {
+		t.Errorf("second replica stats = %+v, want the flight tracked by the first", stats)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GET /api/v1/map - Airports and tracked flights in one consistent snapshot.
+// With the in-memory backend every store shard is locked together while the
+// flights are copied; shared backends are read in a single List call.
+func (at *AirportTracker) handleMap(w http.ResponseWriter, r *http.Request) {
+	opts, err := at.parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	airports := at.airportList()
+	flights := at.uniqueAircraft(at.snapshotFlights())
+	generatedAt := at.now()
+
+	flights, truncation := capFlights(flights, opts.limit)
+	at.decorateFlights(flights, opts)
+
+	response := map[string]interface{}{
+		"generated_at":  generatedAt,
+		"airports":      airports,
+		"airport_count": len(airports),
+		"flights":       flights,
+		"count":         len(flights),
+		"units":         opts.units,
+	}
+	truncation.annotate(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
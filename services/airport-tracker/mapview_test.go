@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMapReturnsAirportsAndFlightsTogether(t *testing.T) {
+	polygon := testAirport("KPOL", 45.1, -72.9)
+	polygon.GeofenceType = GeofencePolygon
+	polygon.Polygon = [][]float64{{45, -73}, {45.2, -73}, {45.2, -72.8}, {45, -72.8}}
+	at := newTestTracker(t, testAirport("KTST", 40, -73), polygon)
+	high := testUpdate("aaa001", 40.05, -73)
+	high.BaroAltitude = ptr(1000.0)
+	track(t, at, high, testUpdate("bbb002", 45.1, -72.9))
+
+	rec := call(at.handleMap, http.MethodGet, "/api/v1/map?units=imperial&coord_format=string", nil)
+	var body struct {
+		Airports     []AirportConfig `json:"airports"`
+		AirportCount int             `json:"airport_count"`
+		Flights      []TrackedFlight `json:"flights"`
+		Count        int             `json:"count"`
+		Units        string          `json:"units"`
+	}
+	decodeBody(t, rec, &body)
+	if body.AirportCount != 2 || len(body.Airports) != 2 || body.Count != 2 || len(body.Flights) != 2 {
+		t.Fatalf("map has %d airports and %d flights, want 2 and 2", len(body.Airports), len(body.Flights))
+	}
+
+	airports := map[string]AirportConfig{}
+	for _, airport := range body.Airports {
+		airports[airport.ICAO] = airport
+	}
+	if len(airports["KPOL"].Polygon) != 4 {
+		t.Errorf("KPOL geometry = %v, want its polygon", airports["KPOL"].Polygon)
+	}
+	for _, flight := range body.Flights {
+		if _, ok := airports[flight.AirportCode]; !ok {
+			t.Errorf("%s is tracked at %s, which the map does not list", flight.ICAO24, flight.AirportCode)
+		}
+		if flight.Position == "" {
+			t.Errorf("%s has no formatted position", flight.ICAO24)
+		}
+		if flight.ICAO24 == "aaa001" && (flight.BaroAltitude == nil || *flight.BaroAltitude < 3280 || *flight.BaroAltitude > 3281) {
+			t.Errorf("aaa001 altitude = %v, want 1000 m in feet", flight.BaroAltitude)
+		}
+	}
+	if body.Units != UnitsImperial {
+		t.Errorf("units = %q, want imperial", body.Units)
+	}
+}
+
+func TestMapListsAircraftOnceInAllMode(t *testing.T) {
+	at := overlappingTracker(t)
+	track(t, at, testUpdate("abc123", 40.05, -73))
+
+	var body struct {
+		Flights []TrackedFlight `json:"flights"`
+		Count   int             `json:"count"`
+	}
+	decodeBody(t, call(at.handleMap, http.MethodGet, "/api/v1/map", nil), &body)
+	if body.Count != 1 || len(body.Flights) != 1 || body.Flights[0].AirportCode != "KAAA" {
+		t.Errorf("map flights = %+v, want abc123 once at the nearest airport KAAA", body.Flights)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// trackerMetrics are the Prometheus metrics served on /metrics. Each
+// tracker has its own registry so several trackers can coexist in tests.
+type trackerMetrics struct {
+	registry           *prometheus.Registry
+	updatesProcessed   prometheus.Counter
+	decodeErrors       prometheus.Counter
+	unknownAirports    prometheus.Counter
+	clockSkew          *prometheus.CounterVec
+	processingDuration prometheus.Histogram
+}
+
+func newTrackerMetrics(at *AirportTracker) *trackerMetrics {
+	m := &trackerMetrics{
+		registry: prometheus.NewRegistry(),
+		updatesProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "airport_tracker_flight_updates_processed_total",
+			Help: "Flight updates run through processFlightUpdate.",
+		}),
+		decodeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "airport_tracker_flight_update_decode_errors_total",
+			Help: "Flight update requests whose body could not be decoded.",
+		}),
+		unknownAirports: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "airport_tracker_unknown_airport_requests_total",
+			Help: "API requests naming an airport code that is not configured.",
+		}),
+		clockSkew: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "airport_tracker_clock_skewed_updates_total",
+			Help: "Flight updates with a feed time outside CLOCK_SKEW_WINDOW_SECONDS, by direction.",
+		}, []string{"direction"}),
+		processingDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "airport_tracker_process_flight_update_duration_seconds",
+			Help:    "Time spent in processFlightUpdate.",
+			Buckets: prometheus.ExponentialBuckets(0.00005, 4, 8),
+		}),
+	}
+	m.registry.MustRegister(
+		m.updatesProcessed,
+		m.decodeErrors,
+		m.unknownAirports,
+		m.clockSkew,
+		m.processingDuration,
+		trackedFlightsCollector{at: at},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	// The ingestion queue is started after the metrics, so it is looked up
+	// at scrape time; both read zero without one
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "airport_tracker_ingest_queue_depth",
+			Help: "Flight updates waiting in the ingestion queue.",
+		}, func() float64 {
+			if at.queue == nil {
+				return 0
+			}
+			return float64(at.queue.Depth())
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "airport_tracker_ingest_queue_dropped_total",
+			Help: "Flight updates refused with a 429 because the ingestion queue was full.",
+		}, func() float64 {
+			if at.queue == nil {
+				return 0
+			}
+			return float64(at.queue.dropped.Load())
+		}),
+	)
+	if p := at.statusChanges; p != nil {
+		m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "airport_tracker_status_change_publish_failures_total",
+			Help: "Status changes dead-lettered after every publish attempt failed.",
+		}, func() float64 { return float64(p.failed.Load()) }))
+	}
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *trackerMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+var trackedFlightsDesc = prometheus.NewDesc(
+	"airport_tracker_tracked_flights",
+	"Flights currently tracked, by airport and status.",
+	[]string{"airport_code", "status"}, nil,
+)
+
+// trackedFlightsCollector counts the tracked flights at scrape time, so the
+// gauge can never drift from the flights actually held. It reads through the
+// backend, so with a shared one every replica reports the same counts.
+type trackedFlightsCollector struct {
+	at *AirportTracker
+}
+
+func (c trackedFlightsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- trackedFlightsDesc
+}
+
+func (c trackedFlightsCollector) Collect(ch chan<- prometheus.Metric) {
+	type key struct{ airport, status string }
+	counts := map[key]int{}
+	for _, flight := range c.at.listFlights(nil) {
+		counts[key{flight.AirportCode, flight.Status}]++
+	}
+	for k, n := range counts {
+		ch <- prometheus.MustNewConstMetric(trackedFlightsDesc, prometheus.GaugeValue, float64(n), k.airport, k.status)
+	}
+}
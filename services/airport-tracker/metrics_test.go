@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, at *AirportTracker) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	at.metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics: code %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func TestMetricsAfterProcessingUpdates(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	descending := testUpdate("aaa001", 40.05, -73)
+	descending.BaroAltitude = ptr(1000.0)
+	descending.VerticalRate = ptr(-5.0)
+	track(t, at, descending, testUpdate("aaa002", 40.05, -73), testUpdate("bbb001", 45.05, -73))
+	track(t, at, testUpdate("zzz999", 0, 0)) // outside every geofence
+	at.handleFlightUpdate(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, flightUpdateRoute, strings.NewReader("{")))
+
+	metrics := scrape(t, at)
+	for _, line := range []string{
+		`airport_tracker_tracked_flights{airport_code="KAAA",status="arriving"} 1`,
+		`airport_tracker_tracked_flights{airport_code="KAAA",status="nearby"} 1`,
+		`airport_tracker_tracked_flights{airport_code="KBBB",status="nearby"} 1`,
+		"airport_tracker_flight_updates_processed_total 4",
+		"airport_tracker_flight_update_decode_errors_total 1",
+		"airport_tracker_process_flight_update_duration_seconds_count 4",
+	} {
+		if !strings.Contains(metrics, line) {
+			t.Errorf("metrics missing %q", line)
+		}
+	}
+
+	at.deleteFlight("aaa001")
+	if strings.Contains(scrape(t, at), `status="arriving"`) {
+		t.Error("gauge still reports the deleted arriving flight")
+	}
+}
+
+func TestTrackedFlightsGaugeReadsSharedBackend(t *testing.T) {
+	client := newFakeRedis()
+	first, second := newTestTracker(t), newTestTracker(t)
+	for _, at := range []*AirportTracker{first, second} {
+		at.backend = &redisBackend{client: client, prefix: defaultRedisKeyPrefix, ttl: time.Minute}
+	}
+	track(t, first, testUpdate("abc123", 40.05, -73))
+
+	if line := `airport_tracker_tracked_flights{airport_code="KTST",status="nearby"} 1`; !strings.Contains(scrape(t, second), line) {
+		t.Errorf("second replica metrics missing %q", line)
+	}
+}
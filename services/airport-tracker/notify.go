@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNotificationCooldown   = 5 * time.Minute
+	defaultNotificationDedupLimit = 10000
+)
+
+// TransitionEvent describes a flight entering a new status at an airport
+type TransitionEvent struct {
+	Event       string    `json:"event"`
+	ICAO24      string    `json:"icao24"`
+	Callsign    string    `json:"callsign"`
+	AirportCode string    `json:"airport_code"`
+	FromStatus  string    `json:"from_status,omitempty"`
+	Status      string    `json:"status"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// TransitionNotifier is told about status transitions that survive the
+// de-duplication window
+type TransitionNotifier interface {
+	Notify(event TransitionEvent)
+}
+
+// noopTransitionNotifier is the default and does nothing
+type noopTransitionNotifier struct{}
+
+func (noopTransitionNotifier) Notify(event TransitionEvent) {}
+
+// webhookTransitionNotifier POSTs each transition as JSON to a URL
+type webhookTransitionNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *webhookTransitionNotifier) Notify(event TransitionEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		slog.Warn("failed to encode transition", "icao24", event.ICAO24, "error", err)
+		return
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		slog.Warn("transition webhook failed", "icao24", event.ICAO24, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("transition webhook returned an error", "icao24", event.ICAO24, "http_status", resp.Status)
+	}
+}
+
+// newTransitionNotifierFromEnv returns a webhook notifier when
+// TRANSITION_WEBHOOK_URL is set and the no-op notifier otherwise
+func newTransitionNotifierFromEnv() TransitionNotifier {
+	url := envString("TRANSITION_WEBHOOK_URL", "")
+	if url == "" {
+		return noopTransitionNotifier{}
+	}
+	slog.Info("transition webhook enabled", "url", url)
+	return &webhookTransitionNotifier{url: url, client: &http.Client{Timeout: 2 * time.Second}}
+}
+
+// notificationDeduper remembers when each flight+airport+status was last
+// notified so a flapping flight does not re-notify within the cooldown.
+// At most limit keys are kept; expired keys are pruned first and the
+// oldest key is dropped if that is not enough.
+type notificationDeduper struct {
+	mu       sync.Mutex
+	notified map[string]time.Time
+	limit    int
+}
+
+func newNotificationDeduper(limit int) *notificationDeduper {
+	if limit < 1 {
+		limit = 1
+	}
+	return &notificationDeduper{notified: make(map[string]time.Time), limit: limit}
+}
+
+// Allow reports whether key may be notified at now, recording it if so
+func (d *notificationDeduper) Allow(key string, now time.Time, cooldown time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if last, ok := d.notified[key]; ok && now.Sub(last) < cooldown {
+		return false
+	}
+	if _, ok := d.notified[key]; !ok && len(d.notified) >= d.limit {
+		d.evict(now, cooldown)
+	}
+	d.notified[key] = now
+	return true
+}
+
+// evict prunes keys older than cooldown, or the single oldest key when none
+// have expired. Called with d.mu held.
+func (d *notificationDeduper) evict(now time.Time, cooldown time.Duration) {
+	var oldestKey string
+	var oldest time.Time
+	for key, last := range d.notified {
+		if now.Sub(last) >= cooldown {
+			delete(d.notified, key)
+			continue
+		}
+		if oldestKey == "" || last.Before(oldest) {
+			oldestKey, oldest = key, last
+		}
+	}
+	if len(d.notified) >= d.limit && oldestKey != "" {
+		delete(d.notified, oldestKey)
+	}
+}
+
+// notificationCooldown returns the airport's cooldown, falling back to the
+// global NOTIFICATION_COOLDOWN_SECONDS
+func (at *AirportTracker) notificationCooldown(airportCode string) time.Duration {
+	for _, airport := range at.airportList() {
+		if airport.ICAO == airportCode && airport.NotificationCooldownSeconds != nil {
+			return time.Duration(*airport.NotificationCooldownSeconds * float64(time.Second))
+		}
+	}
+	return at.notificationCooldownDefault
+}
+
+// allowTransition reports whether sink may be told that icao24 entered
+// status at airportCode at now: not unless the airport's cooldown has passed
+// since sink was last told the same. Sinks are de-duplicated separately so
+// one does not suppress the other.
+func (at *AirportTracker) allowTransition(sink, icao24, airportCode, status string, now time.Time) bool {
+	key := sink + "|" + icao24 + "|" + airportCode + "|" + status
+	return at.notifications.Allow(key, now, at.notificationCooldown(airportCode))
+}
+
+// notifyTransition sends a transition notification unless the same flight
+// already entered the same status at the same airport within the cooldown.
+// The notifier runs in its own goroutine so ingestion is never blocked.
+func (at *AirportTracker) notifyTransition(fromStatus string, flight TrackedFlight) {
+	if !at.allowTransition("webhook", flight.ICAO24, flight.AirportCode, flight.Status, flight.LastSeen) {
+		return
+	}
+	event := TransitionEvent{
+		Event:       "status_transition",
+		ICAO24:      flight.ICAO24,
+		Callsign:    flight.Callsign,
+		AirportCode: flight.AirportCode,
+		FromStatus:  fromStatus,
+		Status:      flight.Status,
+		Timestamp:   flight.LastSeen,
+	}
+	go at.notifier.Notify(event)
+}
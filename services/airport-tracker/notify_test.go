@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// channelNotifier passes each notified transition to a channel
+type channelNotifier chan TransitionEvent
+
+func (n channelNotifier) Notify(event TransitionEvent) { n <- event }
+
+// received collects want events, then checks no further event arrives
+func (n channelNotifier) received(t *testing.T, want int) []TransitionEvent {
+	t.Helper()
+	var events []TransitionEvent
+	for len(events) < want {
+		select {
+		case event := <-n:
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatalf("got %d notifications, want %d", len(events), want)
+		}
+	}
+	select {
+	case event := <-n:
+		t.Fatalf("unexpected notification %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return events
+}
+
+func TestNotificationCooldownSuppressesFlapping(t *testing.T) {
+	at := newTestTracker(t)
+	notifier := make(channelNotifier, 10)
+	at.notifier = notifier
+	at.notificationCooldownDefault = time.Minute
+
+	start := time.Now()
+	flap := []string{StatusArriving, StatusNearby, StatusArriving, StatusNearby, StatusArriving}
+	for i, status := range flap {
+		flight := TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "abc123"}, AirportCode: "KTST", Status: status, LastSeen: start.Add(time.Duration(i) * 5 * time.Second)}
+		at.notifyTransition("", flight)
+	}
+	events := notifier.received(t, 2)
+	if events[0].Status == events[1].Status {
+		t.Errorf("notified %+v, want arriving and nearby once each", events)
+	}
+
+	// Past the cooldown the same transition notifies again
+	at.notifyTransition(StatusNearby, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "abc123"}, AirportCode: "KTST", Status: StatusArriving, LastSeen: start.Add(2 * time.Minute)})
+	notifier.received(t, 1)
+}
+
+func TestNotificationCooldownPerAirport(t *testing.T) {
+	quiet := testAirport("KQTE", 45, -73)
+	quiet.NotificationCooldownSeconds = ptr(0.0)
+	at := newTestTracker(t, testAirport("KTST", 40, -73), quiet)
+	notifier := make(channelNotifier, 10)
+	at.notifier = notifier
+
+	now := time.Now()
+	for _, code := range []string{"KTST", "KTST", "KQTE", "KQTE"} {
+		at.notifyTransition("", TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "abc123"}, AirportCode: code, Status: StatusArriving, LastSeen: now})
+	}
+	// KTST uses the default cooldown; KQTE has none
+	notifier.received(t, 3)
+}
+
+func TestNotificationDeduperIsBounded(t *testing.T) {
+	d := newNotificationDeduper(2)
+	now := time.Now()
+	d.Allow("a", now, time.Hour)
+	d.Allow("b", now.Add(time.Second), time.Hour)
+	d.Allow("c", now.Add(2*time.Second), time.Hour)
+	if len(d.notified) != 2 {
+		t.Fatalf("deduper holds %d keys, want 2", len(d.notified))
+	}
+	if !d.Allow("a", now.Add(3*time.Second), time.Hour) {
+		t.Error("oldest key was not the one dropped")
+	}
+	if d.Allow("c", now.Add(4*time.Second), time.Hour) {
+		t.Error("recent key was dropped")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const defaultBatchField = "flights"
+
+// extractFlightEntries unwraps a request body (see extractEventData) and
+// splits its payload into raw flight updates. The payload may be a single
+// update, a bare array of updates, or an object holding the array under
+// the batch field (EVENT_BATCH_FIELD, "flights" by default), as in
+// {"flights": [...]}.
+func (at *AirportTracker) extractFlightEntries(r *http.Request, body []byte) ([]json.RawMessage, error) {
+	data, err := at.extractEventData(r, body)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+
+	var entries []json.RawMessage
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			at.logRejectedBody(r, "unmarshal array", data, err)
+			return nil, fmt.Errorf("failed to unmarshal flight array: %v", err)
+		}
+		return entries, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		at.logRejectedBody(r, "unmarshal data", data, err)
+		return nil, fmt.Errorf("failed to unmarshal flight data: %v", err)
+	}
+	if batch, ok := fields[at.batchField]; ok && at.batchField != "" {
+		if err := json.Unmarshal(batch, &entries); err != nil {
+			at.logRejectedBody(r, "unmarshal "+at.batchField, batch, err)
+			return nil, fmt.Errorf("%s must be an array of flight updates: %v", at.batchField, err)
+		}
+		return entries, nil
+	}
+	return []json.RawMessage{data}, nil
+}
+
+// extractFlightUpdates decodes every flight update in a request body; any
+// entry that fails to decode fails the whole body
+func (at *AirportTracker) extractFlightUpdates(r *http.Request, body []byte) ([]FlightUpdate, error) {
+	entries, err := at.extractFlightEntries(r, body)
+	if err != nil {
+		return nil, err
+	}
+	flights := make([]FlightUpdate, len(entries))
+	for i, entry := range entries {
+		if err := json.Unmarshal(entry, &flights[i]); err != nil {
+			at.logRejectedBody(r, "unmarshal entry", entry, err)
+			if len(entries) == 1 {
+				return nil, fmt.Errorf("failed to unmarshal flight data: %v", err)
+			}
+			return nil, fmt.Errorf("failed to unmarshal flight %d: %v", i, err)
+		}
+	}
+	return flights, nil
+}
+
+// outcomePrecedence orders results when summarizing a multi-update event
+var outcomePrecedence = []string{OutcomeProcessed, OutcomeQueued, OutcomeSkipped, OutcomeRejected}
+
+// summarizeOutcomes combines the outcomes of the updates in one event. The
+// result is the highest-precedence one reached by any update, so an event
+// is only rejected when every update was; the reason lists the counts.
+func summarizeOutcomes(outcomes []processOutcome) processOutcome {
+	if len(outcomes) == 1 {
+		return outcomes[0]
+	}
+	counts := map[string]int{}
+	for _, outcome := range outcomes {
+		counts[outcome.result]++
+	}
+	summary := processOutcome{result: OutcomeRejected}
+	var parts []string
+	for _, result := range outcomePrecedence {
+		if counts[result] == 0 {
+			continue
+		}
+		if len(parts) == 0 {
+			summary.result = result
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", counts[result], result))
+	}
+	summary.reason = strings.Join(parts, ", ")
+	return summary
+}
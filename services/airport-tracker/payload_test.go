@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExtractFlightUpdatesShapes(t *testing.T) {
+	one, two := `{"icao24":"aaa001"}`, `{"icao24":"bbb002"}`
+	cloudEvent := func(data string) string {
+		return `{"specversion":"1.0","type":"flight.update","source":"feeder","id":"1","data":` + data + `}`
+	}
+	for _, tc := range []struct {
+		name  string
+		field string
+		body  string
+		want  string
+	}{
+		{"single object", "", one, "aaa001"},
+		{"single cloudevent", "", cloudEvent(one), "aaa001"},
+		{"bare array", "", "[" + one + "," + two + "]", "aaa001 bbb002"},
+		{"cloudevent array", "", cloudEvent("[" + one + "," + two + "]"), "aaa001 bbb002"},
+		{"default field", "", cloudEvent(`{"flights":[` + one + "," + two + "]}"), "aaa001 bbb002"},
+		{"configured field", "states", `{"states":[` + two + "]}", "bbb002"},
+		{"empty array", "", "[]", ""},
+	} {
+		if tc.field != "" {
+			t.Setenv("EVENT_BATCH_FIELD", tc.field)
+		}
+		at := newTestTracker(t)
+		req := httptest.NewRequest(http.MethodPost, flightUpdateRoute, nil)
+		updates, err := at.extractFlightUpdates(req, []byte(tc.body))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		var got []string
+		for _, u := range updates {
+			got = append(got, u.ICAO24)
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("%s: extracted %v, want [%s]", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExtractFlightUpdatesErrors(t *testing.T) {
+	at := newTestTracker(t)
+	req := httptest.NewRequest(http.MethodPost, flightUpdateRoute, nil)
+	for body, want := range map[string]string{
+		`{"flights":{"icao24":"aaa001"}}`:     "flights must be an array",
+		`[{"icao24":"aaa001"},{"icao24":42}]`: "failed to unmarshal flight 1",
+		`{"icao24":42}`:                       "failed to unmarshal flight data",
+		`[`:                                   "failed to unmarshal flight array",
+	} {
+		if _, err := at.extractFlightUpdates(req, []byte(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestFlightUpdateAcceptsBatchField(t *testing.T) {
+	at := newTestTracker(t)
+	update := func(icao24 string) string {
+		data, _ := json.Marshal(testUpdate(icao24, 40.05, -73))
+		return string(data)
+	}
+	body := `{"flights":[` + update("aaa001") + "," + update("bbb002") + "]}"
+	at.handleFlightUpdate(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, flightUpdateRoute, strings.NewReader(body)))
+	for _, icao24 := range []string{"aaa001", "bbb002"} {
+		if _, ok := at.flights.Get(icao24); !ok {
+			t.Errorf("%s from the flights field not tracked", icao24)
+		}
+	}
+}
+
+func TestMultiUpdateEventCarriesOnPastAPanic(t *testing.T) {
+	at := newTestTracker(t)
+	exact := at.geofenceDistance
+	at.geofenceDistance = func(lat1, lon1, lat2, lon2 float64) float64 {
+		if lat1 == 40.0501 {
+			panic("crafted update")
+		}
+		return exact(lat1, lon1, lat2, lon2)
+	}
+	var entries []string
+	for _, update := range []FlightUpdate{testUpdate("aaa001", 40.05, -73), testUpdate("bad002", 40.0501, -73), testUpdate("ccc003", 40.05, -73)} {
+		data, _ := json.Marshal(update)
+		entries = append(entries, string(data))
+	}
+
+	rec := httptest.NewRecorder()
+	at.handleFlightUpdate(rec, httptest.NewRequest(http.MethodPost, flightUpdateRoute, strings.NewReader("["+strings.Join(entries, ",")+"]")))
+	ack := decodeAck(t, rec)
+	if rec.Code != http.StatusOK || ack["status"] != DaprSuccess || ack["outcome"] != OutcomeProcessed || ack["reason"] != "2 processed, 1 rejected" {
+		t.Errorf("ack = %d %v, want SUCCESS with 2 processed and 1 rejected", rec.Code, ack)
+	}
+	for _, icao24 := range []string{"aaa001", "ccc003"} {
+		if _, ok := at.flights.Get(icao24); !ok {
+			t.Errorf("%s next to the panicking update not tracked", icao24)
+		}
+	}
+	if n := at.stats.processingPanics.Load(); n != 1 {
+		t.Errorf("processing panics = %d, want 1", n)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPredictionSeconds = 10.0
+	maxPredictionSeconds     = 120.0
+)
+
+// PredictedPosition is a dead-reckoned position of a flight
+type PredictedPosition struct {
+	ICAO24      string    `json:"icao24"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	Seconds     float64   `json:"seconds"`
+	PredictedAt time.Time `json:"predicted_at"`
+
+	// From is the reported position the prediction starts at, and Midpoint
+	// lies halfway along the projected path, for animating between them
+	From     [2]float64 `json:"from"`
+	Midpoint [2]float64 `json:"midpoint"`
+}
+
+// GET /api/v1/flights/{icao24}/predict?seconds= - Project a flight forward
+// along its track at its reported velocity. The horizon defaults to 10s and
+// is clamped to 120s, as dead reckoning drifts quickly in turns.
+func (at *AirportTracker) handleFlightPredict(w http.ResponseWriter, r *http.Request) {
+	icao24 := normalizeICAO24(mux.Vars(r)["icao24"])
+
+	seconds := defaultPredictionSeconds
+	if raw := r.URL.Query().Get("seconds"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 {
+			http.Error(w, fmt.Sprintf("invalid seconds %q: expected a non-negative number", raw), http.StatusBadRequest)
+			return
+		}
+		seconds = v
+	}
+	if seconds > maxPredictionSeconds {
+		seconds = maxPredictionSeconds
+	}
+
+	flight, ok := at.lookupFlight(icao24)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Flight %s is not tracked", icao24), http.StatusNotFound)
+		return
+	}
+	if flight.Velocity == nil || flight.TrueTrack == nil {
+		http.Error(w, fmt.Sprintf("Flight %s has no velocity or track to predict from", icao24), http.StatusUnprocessableEntity)
+		return
+	}
+
+	distanceKm := *flight.Velocity * seconds / 1000
+	lat, lon := destinationPoint(flight.Latitude, flight.Longitude, *flight.TrueTrack, distanceKm)
+	midLat, midLon := greatCircleMidpoint(flight.Latitude, flight.Longitude, lat, lon)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PredictedPosition{
+		ICAO24:      flight.ICAO24,
+		Latitude:    lat,
+		Longitude:   lon,
+		Seconds:     seconds,
+		PredictedAt: flight.ObservedAt.Add(time.Duration(seconds * float64(time.Second))),
+		From:        [2]float64{flight.Latitude, flight.Longitude},
+		Midpoint:    [2]float64{midLat, midLon},
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestPredictDueNorth(t *testing.T) {
+	at := newTestTracker(t)
+	update := testUpdate("abc123", 40.05, -73)
+	update.Velocity, update.TrueTrack = ptr(250.0), ptr(0.0)
+	track(t, at, update, testUpdate("def456", 40.05, -73))
+
+	// Due north the latitude grows by distance / R radians:
+	// 250 m/s for 60 s is 15 km, 15 / 6371 rad = 0.134898°
+	for _, tc := range []struct {
+		query   string
+		seconds float64
+		dLat    float64
+	}{
+		{"?seconds=60", 60, 0.134898},
+		{"?seconds=600", maxPredictionSeconds, 0.269796}, // clamped to 120 s, 30 km
+		{"", defaultPredictionSeconds, 0.022483},
+	} {
+		rec := call(at.handleFlightPredict, http.MethodGet, "/api/v1/flights/abc123/predict"+tc.query, map[string]string{"icao24": "ABC123"})
+		var p PredictedPosition
+		decodeBody(t, rec, &p)
+		if p.Seconds != tc.seconds {
+			t.Errorf("%q: seconds = %v, want %v", tc.query, p.Seconds, tc.seconds)
+		}
+		if math.Abs(p.Latitude-(40.05+tc.dLat)) > 1e-6 || math.Abs(p.Longitude+73) > 1e-9 {
+			t.Errorf("%q: predicted (%.6f, %.6f), want (%.6f, -73)", tc.query, p.Latitude, p.Longitude, 40.05+tc.dLat)
+		}
+		if math.Abs(p.Midpoint[0]-(40.05+tc.dLat/2)) > 1e-6 || p.From != [2]float64{40.05, -73} {
+			t.Errorf("%q: from %v midpoint %v", tc.query, p.From, p.Midpoint)
+		}
+	}
+
+	for _, tc := range []struct {
+		icao24, query string
+		code          int
+	}{
+		{"def456", "", http.StatusUnprocessableEntity}, // no velocity or track
+		{"abc123", "?seconds=-5", http.StatusBadRequest},
+		{"fff000", "", http.StatusNotFound},
+	} {
+		if rec := call(at.handleFlightPredict, http.MethodGet, "/api/v1/flights/"+tc.icao24+"/predict"+tc.query, map[string]string{"icao24": tc.icao24}); rec.Code != tc.code {
+			t.Errorf("%s%s: code = %d, want %d", tc.icao24, tc.query, rec.Code, tc.code)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const (
+	defaultProximityThresholdKm = 2.0
+	defaultProximityAltitudeM   = 300.0
+	kmPerDegreeLatitude         = 111.32
+)
+
+// ProximityPair is two tracked flights closer than the proximity threshold
+type ProximityPair struct {
+	ICAO24A       string  `json:"icao24_a"`
+	ICAO24B       string  `json:"icao24_b"`
+	CallsignA     string  `json:"callsign_a"`
+	CallsignB     string  `json:"callsign_b"`
+	DistanceKm    float64 `json:"distance_km"`
+	AltitudeDiffM float64 `json:"altitude_diff_m"`
+}
+
+// findProximityPairs returns every pair of flights within thresholdKm
+// horizontally and altitudeBandM vertically. Flights are sorted by latitude
+// so each one is only compared against neighbours inside the latitude
+// window, instead of every other flight. Flights without an altitude are
+// skipped since their vertical separation is unknown.
+func findProximityPairs(flights []TrackedFlight, thresholdKm, altitudeBandM float64) []ProximityPair {
+	type candidate struct {
+		flight   *TrackedFlight
+		altitude float64
+	}
+	candidates := make([]candidate, 0, len(flights))
+	for i := range flights {
+		if altitude, ok := effectiveAltitude(flights[i].FlightUpdate); ok {
+			candidates = append(candidates, candidate{flight: &flights[i], altitude: altitude})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].flight.Latitude < candidates[j].flight.Latitude
+	})
+
+	windowDeg := thresholdKm / kmPerDegreeHaversine
+	pairs := []ProximityPair{}
+	for i := range candidates {
+		a := candidates[i]
+		for j := i + 1; j < len(candidates); j++ {
+			b := candidates[j]
+			if b.flight.Latitude-a.flight.Latitude > windowDeg {
+				break
+			}
+			altDiff := math.Abs(a.altitude - b.altitude)
+			if altDiff > altitudeBandM {
+				continue
+			}
+			distance := haversineDistance(a.flight.Latitude, a.flight.Longitude, b.flight.Latitude, b.flight.Longitude)
+			if distance > thresholdKm {
+				continue
+			}
+			first, second := a.flight, b.flight
+			if second.ICAO24 < first.ICAO24 {
+				first, second = second, first
+			}
+			pairs = append(pairs, ProximityPair{
+				ICAO24A:       first.ICAO24,
+				ICAO24B:       second.ICAO24,
+				CallsignA:     first.Callsign,
+				CallsignB:     second.Callsign,
+				DistanceKm:    distance,
+				AltitudeDiffM: altDiff,
+			})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].DistanceKm < pairs[j].DistanceKm })
+	return pairs
+}
+
+// proximityGroups merges overlapping pairs into groups of two or more
+// aircraft, e.g. a three-ship formation
+func proximityGroups(pairs []ProximityPair) [][]string {
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(x string) string {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	for _, p := range pairs {
+		for _, id := range []string{p.ICAO24A, p.ICAO24B} {
+			if _, ok := parent[id]; !ok {
+				parent[id] = id
+			}
+		}
+		parent[find(p.ICAO24A)] = find(p.ICAO24B)
+	}
+
+	members := map[string][]string{}
+	for id := range parent {
+		root := find(id)
+		members[root] = append(members[root], id)
+	}
+	groups := [][]string{}
+	for _, group := range members {
+		sort.Strings(group)
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
+
+// queryFloat parses an optional float query parameter
+func queryFloat(r *http.Request, name string, def float64) (float64, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid %s %q", name, raw)
+	}
+	return v, nil
+}
+
+// GET /api/v1/flights/proximity?threshold=&altitude_band= - Get flights in
+// close proximity (formation or potential conflict)
+func (at *AirportTracker) handleProximity(w http.ResponseWriter, r *http.Request) {
+	threshold, err := queryFloat(r, "threshold", at.proximityThresholdKm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	band, err := queryFloat(r, "altitude_band", at.proximityAltitudeBandM)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pairs := findProximityPairs(at.uniqueAircraft(at.listFlights(nil)), threshold, band)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"threshold_km":    threshold,
+		"altitude_band_m": band,
+		"pairs":           pairs,
+		"groups":          proximityGroups(pairs),
+		"count":           len(pairs),
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestProximityFindsOnlyCloseFlights(t *testing.T) {
+	at := newTestTracker(t)
+	flight := func(icao24 string, lat, lon, altitude float64) FlightUpdate {
+		update := testUpdate(icao24, lat, lon)
+		update.BaroAltitude = ptr(altitude)
+		return update
+	}
+	track(t, at,
+		flight("aaa001", 40.10, -73.00, 1000),
+		flight("bbb002", 40.105, -73.00, 1100), // ~0.56 km from aaa001
+		flight("ccc003", 40.30, -73.00, 1000),  // ~22 km away
+		flight("ddd004", 40.10, -73.005, 5000), // overhead, far above the band
+	)
+
+	rec := call(at.handleProximity, http.MethodGet, "/api/v1/flights/proximity?threshold=1", nil)
+	var body struct {
+		Pairs  []ProximityPair `json:"pairs"`
+		Groups [][]string      `json:"groups"`
+		Count  int             `json:"count"`
+	}
+	decodeBody(t, rec, &body)
+	if body.Count != 1 || len(body.Pairs) != 1 {
+		t.Fatalf("pairs = %+v, want only aaa001/bbb002", body.Pairs)
+	}
+	pair := body.Pairs[0]
+	if pair.ICAO24A != "aaa001" || pair.ICAO24B != "bbb002" || pair.AltitudeDiffM != 100 {
+		t.Errorf("pair = %+v, want aaa001/bbb002 100 m apart vertically", pair)
+	}
+	if len(body.Groups) != 1 || len(body.Groups[0]) != 2 {
+		t.Errorf("groups = %v, want one group of two", body.Groups)
+	}
+}
+
+func TestProximityGroupsMergeFormations(t *testing.T) {
+	groups := proximityGroups([]ProximityPair{
+		{ICAO24A: "aaa001", ICAO24B: "bbb002"},
+		{ICAO24A: "bbb002", ICAO24B: "ccc003"},
+		{ICAO24A: "eee005", ICAO24B: "fff006"},
+	})
+	if len(groups) != 2 || len(groups[0]) != 3 || len(groups[1]) != 2 {
+		t.Errorf("groups = %v, want a three-ship and a pair", groups)
+	}
+}
+
+func TestProximityRejectsInvalidThreshold(t *testing.T) {
+	at := newTestTracker(t)
+	for _, query := range []string{"?threshold=-1", "?threshold=NaN", "?altitude_band=x"} {
+		if rec := call(at.handleProximity, http.MethodGet, "/api/v1/flights/proximity"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: code = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/time/rate"
+)
+
+// newIngestLimiterFromEnv returns a token bucket shared by the ingestion
+// endpoints, refilled at INGEST_RATE_LIMIT requests per second with room
+// for INGEST_RATE_BURST requests (default: one second's worth). It returns
+// nil, disabling the limit, when INGEST_RATE_LIMIT is unset or not positive.
+func newIngestLimiterFromEnv() *rate.Limiter {
+	limit := envFloat("INGEST_RATE_LIMIT", 0)
+	if limit <= 0 {
+		return nil
+	}
+	burst := envInt("INGEST_RATE_BURST", int(math.Max(1, math.Ceil(limit))))
+	if burst < 1 {
+		burst = 1
+	}
+	slog.Info("ingest rate limit enabled", "requests_per_second", limit, "burst", burst)
+	return rate.NewLimiter(rate.Limit(limit), burst)
+}
+
+// limitIngest rejects requests beyond limiter's rate with a 429 retry
+// acknowledgement and a Retry-After header. With no limiter it returns next
+// unchanged.
+func limitIngest(limiter *rate.Limiter, next http.Handler) http.Handler {
+	if limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reservation := limiter.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			writeAck(w, http.StatusTooManyRequests, DaprRetry, OutcomeRetry, "ingest rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIngestLimitRejectsBurstWithRetryAfter(t *testing.T) {
+	t.Setenv("INGEST_RATE_LIMIT", "1")
+	t.Setenv("INGEST_RATE_BURST", "3")
+	handler := limitIngest(newIngestLimiterFromEnv(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	limited := 0
+	for i := 0; i < 10; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, flightUpdateRoute, nil))
+		if rec.Code == http.StatusTooManyRequests {
+			limited++
+			if rec.Header().Get("Retry-After") == "" {
+				t.Error("429 without a Retry-After header")
+			}
+		}
+	}
+	if limited != 7 {
+		t.Errorf("%d of 10 requests limited, want 7 past a burst of 3", limited)
+	}
+}
+
+func TestIngestLimitDisabledByDefault(t *testing.T) {
+	if limiter := newIngestLimiterFromEnv(); limiter != nil {
+		t.Errorf("limiter = %v with INGEST_RATE_LIMIT unset, want nil", limiter)
+	}
+}
+
+func TestUnauthenticatedRequestsDoNotSpendIngestBudget(t *testing.T) {
+	t.Setenv("INGEST_RATE_LIMIT", "1")
+	t.Setenv("INGEST_RATE_BURST", "1")
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	// Wired as in main: the key check runs before the limiter
+	handler := requireAPIKey("secret", limitIngest(newIngestLimiterFromEnv(), ok))
+
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, flightUpdateRoute, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("unauthenticated request: code %d, want 401", rec.Code)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, flightUpdateRoute, nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("authenticated request after rejected ones: code %d, want 200", rec.Code)
+	}
+}
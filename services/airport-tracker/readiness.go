@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const defaultMinAirports = 1
+
+// readinessCheck is one named condition /ready requires; Detail explains a
+// failure
+type readinessCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// readinessChecks evaluates every condition the service needs before it
+// should receive traffic
+func (at *AirportTracker) readinessChecks() []readinessCheck {
+	loaded := len(at.airportList())
+	airports := readinessCheck{Name: "airports_loaded", OK: loaded >= at.minAirports}
+	if !airports.OK {
+		airports.Detail = fmt.Sprintf("%d airports loaded, at least %d required", loaded, at.minAirports)
+	}
+	checks := []readinessCheck{airports}
+
+	// The sidecar only matters when flights are persisted through it
+	if at.stateStore != nil {
+		sidecar := readinessCheck{Name: "dapr_sidecar", OK: true}
+		if err := at.stateStore.sidecarHealthy(); err != nil {
+			sidecar.OK = false
+			sidecar.Detail = err.Error()
+		}
+		checks = append(checks, sidecar)
+	}
+	return checks
+}
+
+// GET /health/ready (and /ready) - Readiness probe; 503 with the first
+// failing check as the reason until every readiness check passes
+func (at *AirportTracker) handleReady(w http.ResponseWriter, r *http.Request) {
+	checks := at.readinessChecks()
+	response := map[string]interface{}{
+		"status": "ready",
+		"checks": checks,
+	}
+	code := http.StatusOK
+	for _, check := range checks {
+		if !check.OK {
+			code = http.StatusServiceUnavailable
+			response["status"] = "not_ready"
+			response["reason"] = check.Detail
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
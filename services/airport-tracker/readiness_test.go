@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadinessRequiresMinimumAirports(t *testing.T) {
+	t.Setenv("READY_MIN_AIRPORTS", "3")
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+
+	rec := call(at.handleReady, http.MethodGet, "/ready", nil)
+	var body struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusServiceUnavailable || body.Status != "not_ready" {
+		t.Fatalf("with 2 of 3 airports: %d %s, want 503 not_ready", rec.Code, body.Status)
+	}
+	if body.Reason != "2 airports loaded, at least 3 required" {
+		t.Errorf("reason = %q", body.Reason)
+	}
+
+	at.minAirports = 2
+	if rec := call(at.handleReady, http.MethodGet, "/ready", nil); rec.Code != http.StatusOK {
+		t.Errorf("with 2 of 2 airports: code %d, want 200", rec.Code)
+	}
+}
+
+func TestReadinessDefaultsToOneAirport(t *testing.T) {
+	at := newTestTracker(t)
+	if at.minAirports != defaultMinAirports {
+		t.Fatalf("minAirports = %d, want %d", at.minAirports, defaultMinAirports)
+	}
+	if rec := call(at.handleReady, http.MethodGet, "/ready", nil); rec.Code != http.StatusOK {
+		t.Errorf("code = %d, want 200", rec.Code)
+	}
+}
+
+func TestLivenessIgnoresReadiness(t *testing.T) {
+	t.Setenv("READY_MIN_AIRPORTS", "2")
+	at := newTestTracker(t)
+	if rec := call(at.handleReady, http.MethodGet, "/health/ready", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ready code = %d, want 503", rec.Code)
+	}
+	rec := call(at.handleHealth, http.MethodGet, "/health/live", nil)
+	var body struct {
+		Status string `json:"status"`
+	}
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusOK || body.Status != "healthy" {
+		t.Errorf("live = %d %q, want 200 healthy while not ready", rec.Code, body.Status)
+	}
+}
+
+func TestReadinessChecksSidecarWhenPersisting(t *testing.T) {
+	store := withStateStore(t)
+	at := newTestTracker(t)
+	rec := call(at.handleReady, http.MethodGet, "/health/ready", nil)
+	var body struct {
+		Status string           `json:"status"`
+		Reason string           `json:"reason"`
+		Checks []readinessCheck `json:"checks"`
+	}
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusOK || len(body.Checks) != 2 || body.Checks[1].Name != "dapr_sidecar" {
+		t.Fatalf("ready = %d %+v, want 200 with a dapr_sidecar check", rec.Code, body)
+	}
+
+	store.mu.Lock()
+	store.down = true
+	store.mu.Unlock()
+	rec = call(at.handleReady, http.MethodGet, "/health/ready", nil)
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusServiceUnavailable || body.Status != "not_ready" || !strings.Contains(body.Reason, "Dapr sidecar unreachable") {
+		t.Errorf("with the sidecar down: %d %s %q, want 503 naming the sidecar", rec.Code, body.Status, body.Reason)
+	}
+}
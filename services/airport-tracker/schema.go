@@ -0,0 +1,135 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+)
+
+// Schema validation modes (SCHEMA_VALIDATION)
+const (
+	SchemaValidationOff    = "off"
+	SchemaValidationFlag   = "flag"
+	SchemaValidationReject = "reject"
+)
+
+var icao24Pattern = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// schemaConstraint is a single named expectation about a decoded update
+type schemaConstraint struct {
+	name  string
+	check func(FlightUpdate) bool
+}
+
+func withinOptional(v *float64, min, max float64) bool {
+	return v == nil || (*v >= min && *v <= max)
+}
+
+// schemaConstraints guards against upstream schema drift; a publisher that
+// renames or rescales a field shows up as a spike in one of these counters.
+var schemaConstraints = []schemaConstraint{
+	{"icao24_required", func(u FlightUpdate) bool { return strings.TrimSpace(u.ICAO24) != "" }},
+	{"icao24_format", func(u FlightUpdate) bool {
+		return u.ICAO24 == "" || icao24Pattern.MatchString(strings.TrimSpace(u.ICAO24))
+	}},
+	{"last_contact_required", func(u FlightUpdate) bool { return u.LastContact > 0 }},
+	{"latitude_range", func(u FlightUpdate) bool { return u.Latitude >= -90 && u.Latitude <= 90 }},
+	{"longitude_range", func(u FlightUpdate) bool { return u.Longitude >= -180 && u.Longitude <= 180 }},
+	{"baro_altitude_range", func(u FlightUpdate) bool { return withinOptional(u.BaroAltitude, -1500, 25000) }},
+	{"geo_altitude_range", func(u FlightUpdate) bool { return withinOptional(u.GeoAltitude, -1500, 25000) }},
+	{"velocity_range", func(u FlightUpdate) bool { return withinOptional(u.Velocity, 0, 700) }},
+	{"true_track_range", func(u FlightUpdate) bool { return withinOptional(u.TrueTrack, 0, 360) }},
+	{"vertical_rate_range", func(u FlightUpdate) bool { return withinOptional(u.VerticalRate, -150, 150) }},
+}
+
+// schemaValidator checks decoded updates against the enabled constraints
+// and counts violations per constraint.
+type schemaValidator struct {
+	mode        string
+	constraints []schemaConstraint
+	violations  map[string]*atomic.Uint64
+}
+
+// newSchemaValidatorFromEnv reads SCHEMA_VALIDATION (off, flag, reject) and
+// SCHEMA_CONSTRAINTS_DISABLED (comma-separated constraint names).
+func newSchemaValidatorFromEnv() *schemaValidator {
+	mode := strings.ToLower(envString("SCHEMA_VALIDATION", SchemaValidationOff))
+	switch mode {
+	case SchemaValidationFlag, SchemaValidationReject:
+	case "", SchemaValidationOff:
+		mode = SchemaValidationOff
+	default:
+		slog.Warn("unknown SCHEMA_VALIDATION, validation disabled", "value", mode)
+		mode = SchemaValidationOff
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range strings.Split(envString("SCHEMA_CONSTRAINTS_DISABLED", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			disabled[name] = true
+		}
+	}
+
+	v := &schemaValidator{mode: mode, violations: make(map[string]*atomic.Uint64)}
+	for _, c := range schemaConstraints {
+		if disabled[c.name] {
+			continue
+		}
+		v.constraints = append(v.constraints, c)
+		v.violations[c.name] = &atomic.Uint64{}
+	}
+	if mode != SchemaValidationOff {
+		slog.Info("schema validation enabled", "mode", mode, "constraints", len(v.constraints))
+	}
+	return v
+}
+
+// Validate returns the names of violated constraints, counting each one.
+// It returns nil when validation is off.
+func (v *schemaValidator) Validate(update FlightUpdate) []string {
+	if v.mode == SchemaValidationOff {
+		return nil
+	}
+	var violated []string
+	for _, c := range v.constraints {
+		if !c.check(update) {
+			v.violations[c.name].Add(1)
+			violated = append(violated, c.name)
+		}
+	}
+	return violated
+}
+
+// ViolationCounts returns a snapshot of the per-constraint counters
+func (v *schemaValidator) ViolationCounts() map[string]uint64 {
+	counts := make(map[string]uint64, len(v.violations))
+	for name, counter := range v.violations {
+		counts[name] = counter.Load()
+	}
+	return counts
+}
+
+//go:embed schemas/*.schema.json
+var schemaFiles embed.FS
+
+// GET /api/v1/schema/{type} - JSON Schema for flight updates or airport config
+func handleSchema(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["type"]
+	if name != "flight" && name != "airport" {
+		http.Error(w, fmt.Sprintf("Unknown schema %q: expected flight or airport", name), http.StatusNotFound)
+		return
+	}
+	data, err := schemaFiles.ReadFile("schemas/" + name + ".schema.json")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read schema: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(data)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSchemaRejectsMissingRequiredFields(t *testing.T) {
+	t.Setenv("SCHEMA_VALIDATION", "reject")
+	at := newTestTracker(t)
+
+	missing := testUpdate("", 40.05, -73)
+	missing.LastContact = 0
+	outcome, err := at.ingest(context.Background(), missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.result != OutcomeRejected {
+		t.Fatalf("outcome = %+v, want rejected", outcome)
+	}
+	counts := at.schema.ViolationCounts()
+	if counts["icao24_required"] != 1 || counts["last_contact_required"] != 1 || counts["latitude_range"] != 0 {
+		t.Errorf("violation counts = %v", counts)
+	}
+
+	if outcome, _ := at.ingest(context.Background(), testUpdate("abc123", 40.05, -73)); outcome.result != OutcomeProcessed {
+		t.Errorf("valid update: outcome %+v, want processed", outcome)
+	}
+}
+
+func TestSchemaFlagModeKeepsProcessing(t *testing.T) {
+	t.Setenv("SCHEMA_VALIDATION", "flag")
+	at := newTestTracker(t)
+	update := testUpdate("abc123", 40.05, -73)
+	update.LastContact = 0
+	if outcome, _ := at.ingest(context.Background(), update); outcome.result != OutcomeProcessed {
+		t.Errorf("outcome = %+v, want processed", outcome)
+	}
+	if n := at.schema.ViolationCounts()["last_contact_required"]; n != 1 {
+		t.Errorf("last_contact_required = %d, want 1", n)
+	}
+}
+
+func TestSchemaConstraintsCanBeDisabled(t *testing.T) {
+	t.Setenv("SCHEMA_VALIDATION", "reject")
+	t.Setenv("SCHEMA_CONSTRAINTS_DISABLED", "last_contact_required, velocity_range")
+	v := newSchemaValidatorFromEnv()
+	update := FlightUpdate{ICAO24: "abc123", Velocity: ptr(900.0)}
+	if violated := v.Validate(update); len(violated) != 0 {
+		t.Errorf("violations %v with both constraints disabled", violated)
+	}
+	update.ICAO24 = "not-hex"
+	if violated := fmt.Sprint(v.Validate(update)); violated != "[icao24_format]" {
+		t.Errorf("violations %s, want [icao24_format]", violated)
+	}
+}
+
+func TestSchemaValidationOffByDefault(t *testing.T) {
+	if violated := newSchemaValidatorFromEnv().Validate(FlightUpdate{}); violated != nil {
+		t.Errorf("violations %v with validation off", violated)
+	}
+}
+
+// validateJSON checks value against the subset of JSON Schema the served
+// schemas use, returning a description of each failure. root resolves
+// "#/$defs/..." references.
+func validateJSON(root, schema map[string]interface{}, value interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		def := strings.TrimPrefix(ref, "#/$defs/")
+		return validateJSON(root, root["$defs"].(map[string]interface{})[def].(map[string]interface{}), value, path)
+	}
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types, ok := schema["type"]; ok {
+		allowed, isList := types.([]interface{})
+		if !isList {
+			allowed = []interface{}{types}
+		}
+		matched := false
+		for _, t := range allowed {
+			matched = matched || jsonTypeMatches(t.(string), value)
+		}
+		if !matched {
+			fail("type %v, want %v", value, types)
+			return problems
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, v := range enum {
+			found = found || v == value
+		}
+		if !found {
+			fail("%v not in %v", value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			fail("%g below minimum %g", v, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			fail("%g above maximum %g", v, max)
+		}
+		if min, ok := schema["exclusiveMinimum"].(float64); ok && v <= min {
+			fail("%g not above %g", v, min)
+		}
+	case string:
+		if n, ok := schema["minLength"].(float64); ok && len(v) < int(n) {
+			fail("shorter than %g", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(v) {
+			fail("%q does not match %s", v, pattern)
+		}
+	case []interface{}:
+		if n, ok := schema["minItems"].(float64); ok && len(v) < int(n) {
+			fail("fewer than %g items", n)
+		}
+		if n, ok := schema["maxItems"].(float64); ok && len(v) > int(n) {
+			fail("more than %g items", n)
+		}
+		prefix, _ := schema["prefixItems"].([]interface{})
+		for i, item := range v {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if i < len(prefix) {
+				problems = append(problems, validateJSON(root, prefix[i].(map[string]interface{}), item, itemPath)...)
+			} else if items, ok := schema["items"].(map[string]interface{}); ok {
+				problems = append(problems, validateJSON(root, items, item, itemPath)...)
+			}
+		}
+	case map[string]interface{}:
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := v[name.(string)]; !ok {
+				fail("missing %s", name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, field := range v {
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				problems = append(problems, validateJSON(root, property, field, path+"."+name)...)
+			}
+		}
+	}
+	return problems
+}
+
+func jsonTypeMatches(t string, value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case []interface{}:
+		return t == "array"
+	case map[string]interface{}:
+		return t == "object"
+	}
+	return false
+}
+
+// servedSchema fetches a schema from the endpoint
+func servedSchema(t *testing.T, name string) map[string]interface{} {
+	t.Helper()
+	rec := call(handleSchema, http.MethodGet, "/api/v1/schema/"+name, map[string]string{"type": name})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET schema %s: code %d", name, rec.Code)
+	}
+	var schema map[string]interface{}
+	decodeBody(t, rec, &schema)
+	return schema
+}
+
+// asJSONValue round-trips v through JSON into generic values
+func asJSONValue(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestServedSchemasValidateSamples(t *testing.T) {
+	flight := servedSchema(t, "flight")
+	valid := testUpdate("abc123", 40.05, -73)
+	valid.BaroAltitude = ptr(1200.0)
+	if problems := validateJSON(flight, flight, asJSONValue(t, valid), "flight"); len(problems) > 0 {
+		t.Errorf("valid flight update rejected: %v", problems)
+	}
+	invalid := map[string]interface{}{"icao24": "xyz", "latitude": 91, "longitude": -73, "velocity": -1}
+	if problems := validateJSON(flight, flight, asJSONValue(t, invalid), "flight"); len(problems) != 4 {
+		t.Errorf("invalid flight update: problems %v, want pattern, range, velocity and last_contact", problems)
+	}
+
+	airport := servedSchema(t, "airport")
+	polygon := testAirport("KPOL", 40, -73)
+	polygon.GeofenceType = GeofencePolygon
+	polygon.Polygon = [][]float64{{40, -73}, {40.1, -73}, {40.1, -72.9}}
+	for _, a := range []AirportConfig{testAirport("KTST", 40, -73), polygon} {
+		if problems := validateJSON(airport, airport, asJSONValue(t, a), a.ICAO); len(problems) > 0 {
+			t.Errorf("valid airport %s rejected: %v", a.ICAO, problems)
+		}
+	}
+	bad := map[string]interface{}{
+		"icao": "", "latitude": 40, "longitude": -73, "radius_km": 10, "radius_unit": "mi",
+		"arrival_threshold_m": 3000, "departure_threshold_m": 2000,
+		"geofence_type": "hexagon", "polygon": []interface{}{[]interface{}{40, -200}},
+	}
+	if problems := validateJSON(airport, airport, asJSONValue(t, bad), "airport"); len(problems) != 5 {
+		t.Errorf("invalid airport: problems %v, want icao, radius_unit, geofence_type, polygon size and point", problems)
+	}
+
+	if rec := call(handleSchema, http.MethodGet, "/api/v1/schema/runway", map[string]string{"type": "runway"}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown schema code = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSchemasCoverStructTags(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		typ        reflect.Type
+		outputOnly map[string]bool
+	}{
+		{"flight", reflect.TypeOf(FlightUpdate{}), nil},
+		// Set at load for the airports listing, ignored in the config
+		{"airport", reflect.TypeOf(AirportConfig{}), map[string]bool{"configured_radius": true, "configured_radius_unit": true}},
+	} {
+		properties := servedSchema(t, tc.name)["properties"].(map[string]interface{})
+		tags := map[string]bool{}
+		for i := 0; i < tc.typ.NumField(); i++ {
+			tag, _, _ := strings.Cut(tc.typ.Field(i).Tag.Get("json"), ",")
+			if tag == "" || tag == "-" || tc.outputOnly[tag] {
+				continue
+			}
+			tags[tag] = true
+			if _, ok := properties[tag]; !ok {
+				t.Errorf("%s schema is missing %s", tc.name, tag)
+			}
+		}
+		for name := range properties {
+			if !tags[name] {
+				t.Errorf("%s schema property %s has no struct field", tc.name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultShutdownGrace = 15 * time.Second
+
+// listenAddress resolves the address to bind: LISTEN_ADDR (host:port) wins
+// over PORT, and Port is used when neither is set.
+func listenAddress() string {
+	if addr := strings.TrimSpace(envString("LISTEN_ADDR", "")); addr != "" {
+		return addr
+	}
+	if port := strings.TrimSpace(envString("PORT", "")); port != "" {
+		return ":" + strings.TrimPrefix(port, ":")
+	}
+	return Port
+}
+
+// serve runs server on listener until ctx is cancelled, then shuts it down,
+// giving in-flight requests up to grace to finish. It returns nil after a
+// clean shutdown.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, grace time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining requests", "grace", grace.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServeDrainsInFlightRequestsOnShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "done")
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serve(ctx, server, listener, 5*time.Second) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{string(body), err}
+	}()
+
+	<-started
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("serve returned %v, want a clean shutdown", err)
+	}
+	if r := <-response; r.err != nil || r.body != "done" {
+		t.Errorf("in-flight request got %q, %v; want it completed", r.body, r.err)
+	}
+	if _, err := http.Get("http://" + listener.Addr().String() + "/"); err == nil {
+		t.Error("server still accepting connections after shutdown")
+	}
+}
+
+func TestListenAddress(t *testing.T) {
+	for _, tc := range []struct {
+		listenAddr, port, want string
+	}{
+		{"", "", Port},
+		{"", "8080", ":8080"},
+		{"", ":8081", ":8081"},
+		{"127.0.0.1:9000", "8080", "127.0.0.1:9000"},
+		{" 0.0.0.0:3004 ", "", "0.0.0.0:3004"},
+	} {
+		t.Setenv("LISTEN_ADDR", tc.listenAddr)
+		t.Setenv("PORT", tc.port)
+		if got := listenAddress(); got != tc.want {
+			t.Errorf("LISTEN_ADDR=%q PORT=%q: %q, want %q", tc.listenAddr, tc.port, got, tc.want)
+		}
+	}
+}
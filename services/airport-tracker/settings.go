@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sources a setting's effective value can come from
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+)
+
+// effectiveSetting is a resolved runtime setting and where it came from
+type effectiveSetting struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// effectiveSettings records every setting read through the env helpers so
+// /api/v1/config/effective can show what is actually in effect
+var (
+	effectiveSettingsMu sync.Mutex
+	effectiveSettings   = map[string]effectiveSetting{}
+)
+
+// recordSetting stores the resolved value of a setting, masking secrets
+// and the credentials embedded in URL and address settings
+func recordSetting(name string, value interface{}, source string) {
+	if isSecretSetting(name) && value != "" {
+		value = "********"
+	} else if raw, ok := value.(string); ok {
+		value = withoutUserinfo(name, raw)
+	}
+	effectiveSettingsMu.Lock()
+	defer effectiveSettingsMu.Unlock()
+	effectiveSettings[name] = effectiveSetting{Name: name, Value: value, Source: source}
+}
+
+func isSecretSetting(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range []string{"PASSWORD", "SECRET", "TOKEN", "API_KEY"} {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutUserinfo strips credentials (user:pass@) from a URL-valued setting,
+// such as a webhook or OTLP endpoint, or from a scheme-less *_ADDR address
+// such as user:pass@redis:6379
+func withoutUserinfo(name, value string) string {
+	if strings.Contains(value, "://") {
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			u.User = nil
+			return u.String()
+		}
+		return value
+	}
+	if strings.HasSuffix(strings.ToUpper(name), "_ADDR") {
+		if at := strings.LastIndex(value, "@"); at >= 0 {
+			return value[at+1:]
+		}
+	}
+	return value
+}
+
+// envString returns an environment variable, or def when it is unset
+func envString(name, def string) string {
+	if raw := os.Getenv(name); raw != "" {
+		recordSetting(name, raw, SourceEnv)
+		return raw
+	}
+	recordSetting(name, def, SourceDefault)
+	return def
+}
+
+// envInt returns the integer value of an environment variable, or def when
+// it is unset or malformed.
+func envInt(name string, def int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		recordSetting(name, def, SourceDefault)
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		slog.Warn("invalid setting, using default", "name", name, "value", raw, "default", def)
+		recordSetting(name, def, SourceDefault)
+		return def
+	}
+	recordSetting(name, v, SourceEnv)
+	return v
+}
+
+// envFloat returns the float value of an environment variable, or def when
+// it is unset or malformed.
+func envFloat(name string, def float64) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		recordSetting(name, def, SourceDefault)
+		return def
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		slog.Warn("invalid setting, using default", "name", name, "value", raw, "default", def)
+		recordSetting(name, def, SourceDefault)
+		return def
+	}
+	recordSetting(name, v, SourceEnv)
+	return v
+}
+
+// envBool returns the boolean value of an environment variable, or def when
+// it is unset or malformed.
+func envBool(name string, def bool) bool {
+	raw := os.Getenv(name)
+	if raw == "" {
+		recordSetting(name, def, SourceDefault)
+		return def
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		slog.Warn("invalid setting, using default", "name", name, "value", raw, "default", def)
+		recordSetting(name, def, SourceDefault)
+		return def
+	}
+	recordSetting(name, v, SourceEnv)
+	return v
+}
+
+// envSeconds returns a duration configured as a number of seconds, or def
+// when it is unset, malformed or not positive.
+func envSeconds(name string, def time.Duration) time.Duration {
+	seconds := envFloat(name, def.Seconds())
+	if seconds <= 0 {
+		slog.Warn("invalid setting, using default", "name", name, "value", seconds, "default", def.String())
+		recordSetting(name, def.Seconds(), SourceDefault)
+		return def
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// GET /api/v1/config/effective - Resolved runtime settings and their sources
+func handleEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	effectiveSettingsMu.Lock()
+	settings := make([]effectiveSetting, 0, len(effectiveSettings))
+	for _, setting := range effectiveSettings {
+		settings = append(settings, setting)
+	}
+	effectiveSettingsMu.Unlock()
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": settings,
+		"count":    len(settings),
+	})
+}
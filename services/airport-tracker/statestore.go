@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const stateQueryPageSize = 500
+
+// daprStateStore persists tracked flights in a Dapr state store under their
+// store key (see flightKey) so a restarted replica can rehydrate its
+// flights. Loading uses the state query API, so the store must support
+// queries (e.g. Redis with RediSearch, PostgreSQL, MongoDB).
+type daprStateStore struct {
+	baseURL string // http://localhost:<port>/v1.0/state/<store>
+	store   string
+	port    int
+	ttl     time.Duration
+	client  *http.Client
+}
+
+// newStateStoreFromEnv returns a state store when STATE_STORE_NAME is set,
+// and nil otherwise
+func newStateStoreFromEnv(ttl time.Duration) *daprStateStore {
+	store := envString("STATE_STORE_NAME", "")
+	if store == "" {
+		return nil
+	}
+	port := envInt("DAPR_HTTP_PORT", defaultDaprHTTPPort)
+	slog.Info("persisting tracked flights to Dapr state store", "store", store)
+	return &daprStateStore{
+		baseURL: fmt.Sprintf("http://localhost:%d/v1.0/state/%s", port, url.PathEscape(store)),
+		store:   store,
+		port:    port,
+		ttl:     ttl,
+		client:  &http.Client{Timeout: daprPublishTimeout},
+	}
+}
+
+type stateItem struct {
+	Key      string            `json:"key"`
+	Value    TrackedFlight     `json:"value"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Save writes flight under key, its store key. Entries expire after ttl so
+// flights missed by the sweeper (e.g. during downtime) do not linger.
+func (s *daprStateStore) Save(ctx context.Context, key string, flight TrackedFlight) error {
+	item := stateItem{Key: key, Value: flight}
+	if s.ttl > 0 {
+		item.Metadata = map[string]string{"ttlInSeconds": strconv.Itoa(int(s.ttl.Seconds()))}
+	}
+	payload, err := json.Marshal([]stateItem{item})
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, http.MethodPost, s.baseURL, payload, nil)
+}
+
+// Delete removes the flight stored under key
+func (s *daprStateStore) Delete(ctx context.Context, key string) error {
+	return s.do(ctx, http.MethodDelete, s.baseURL+"/"+url.PathEscape(key), nil, nil)
+}
+
+// Load returns every stored flight, following query pagination tokens
+func (s *daprStateStore) Load(ctx context.Context) ([]TrackedFlight, error) {
+	endpoint := fmt.Sprintf("http://localhost:%d/v1.0-alpha1/state/%s/query", s.port, url.PathEscape(s.store))
+	var flights []TrackedFlight
+	token := ""
+	for {
+		query := map[string]interface{}{"page": map[string]interface{}{"limit": stateQueryPageSize}}
+		if token != "" {
+			query["page"].(map[string]interface{})["token"] = token
+		}
+		payload, err := json.Marshal(query)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Results []struct {
+				Key   string        `json:"key"`
+				Data  TrackedFlight `json:"data"`
+				Error string        `json:"error"`
+			} `json:"results"`
+			Token string `json:"token"`
+		}
+		if err := s.do(ctx, http.MethodPost, endpoint, payload, &result); err != nil {
+			return nil, err
+		}
+		for _, r := range result.Results {
+			if r.Error != "" || r.Data.ICAO24 == "" {
+				continue
+			}
+			flights = append(flights, r.Data)
+		}
+		if result.Token == "" || len(result.Results) == 0 {
+			return flights, nil
+		}
+		token = result.Token
+	}
+}
+
+// sidecarHealthy checks the Dapr sidecar's health endpoint
+func (s *daprStateStore) sidecarHealthy() error {
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+	endpoint := fmt.Sprintf("http://localhost:%d/v1.0/healthz", s.port)
+	if err := s.do(ctx, http.MethodGet, endpoint, nil, nil); err != nil {
+		return fmt.Errorf("Dapr sidecar unreachable: %w", err)
+	}
+	return nil
+}
+
+// do sends a request to the sidecar, decoding a JSON response into out when
+// it is non-nil
+func (s *daprStateStore) do(ctx context.Context, method, endpoint string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("state store %s returned %s", s.store, resp.Status)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// rehydrate loads persisted flights into the local store. Failures are
+// logged and the tracker starts empty.
+func (at *AirportTracker) rehydrate() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	flights, err := at.stateStore.Load(ctx)
+	if err != nil {
+		slog.Warn("failed to rehydrate flights from state store", "error", err)
+		return
+	}
+	for i := range flights {
+		flight := flights[i]
+		at.flights.Update(at.flightKey(flight.ICAO24, flight.AirportCode), func(prev *TrackedFlight) *TrackedFlight {
+			if prev != nil {
+				return nil // a live update already arrived
+			}
+			return &flight
+		})
+	}
+	slog.Info("rehydrated flights from state store", "count", len(flights))
+}
+
+// persistFlight saves a tracked flight to the state store, if enabled
+func (at *AirportTracker) persistFlight(flight TrackedFlight) {
+	if at.stateStore == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+	if err := at.stateStore.Save(ctx, at.flightKey(flight.ICAO24, flight.AirportCode), flight); err != nil {
+		slog.Warn("failed to persist flight", "icao24", flight.ICAO24, "error", err)
+	}
+}
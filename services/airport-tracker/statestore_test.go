@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStateStore serves the Dapr state and query APIs from memory, one
+// result per query page so pagination tokens are exercised
+type fakeStateStore struct {
+	mu     sync.Mutex
+	values map[string]json.RawMessage
+	ttls   map[string]string
+	down   bool // the sidecar health check fails
+}
+
+func (s *fakeStateStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.URL.Path == "/v1.0/healthz":
+		if s.down {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1.0/state/flights":
+		var items []struct {
+			Key      string            `json:"key"`
+			Value    json.RawMessage   `json:"value"`
+			Metadata map[string]string `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&items)
+		for _, item := range items {
+			s.values[item.Key] = item.Value
+			s.ttls[item.Key] = item.Metadata["ttlInSeconds"]
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v1.0/state/flights/"):
+		key, _ := url.PathUnescape(strings.TrimPrefix(r.URL.Path, "/v1.0/state/flights/"))
+		delete(s.values, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1.0-alpha1/state/flights/query":
+		var query struct {
+			Page struct {
+				Token string `json:"token"`
+			} `json:"page"`
+		}
+		json.NewDecoder(r.Body).Decode(&query)
+		keys := make([]string, 0, len(s.values))
+		for key := range s.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		start, _ := strconv.Atoi(query.Page.Token)
+		type result struct {
+			Key  string          `json:"key"`
+			Data json.RawMessage `json:"data"`
+		}
+		response := struct {
+			Results []result `json:"results"`
+			Token   string   `json:"token,omitempty"`
+		}{Results: []result{}}
+		if start < len(keys) {
+			response.Results = append(response.Results, result{keys[start], s.values[keys[start]]})
+			response.Token = strconv.Itoa(start + 1)
+		}
+		json.NewEncoder(w).Encode(response)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeStateStore) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := []string{}
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// withStateStore points STATE_STORE_NAME and DAPR_HTTP_PORT at a fake store
+func withStateStore(t *testing.T) *fakeStateStore {
+	store := &fakeStateStore{values: map[string]json.RawMessage{}, ttls: map[string]string{}}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+	t.Setenv("STATE_STORE_NAME", "flights")
+	t.Setenv("DAPR_HTTP_PORT", u.Port())
+	return store
+}
+
+func TestStateStoreSaveAndRehydrate(t *testing.T) {
+	store := withStateStore(t)
+	first := newTestTracker(t)
+	if first.stateStore == nil {
+		t.Fatal("state store not enabled by STATE_STORE_NAME")
+	}
+	arriving := testUpdate("abc123", 40.05, -73)
+	arriving.Callsign = "UAL100"
+	arriving.BaroAltitude, arriving.VerticalRate = ptr(1000.0), ptr(-5.0)
+	track(t, first, arriving, testUpdate("def456", 40.1, -73))
+
+	if keys := store.keys(); strings.Join(keys, " ") != "abc123 def456" {
+		t.Fatalf("stored keys = %v, want abc123 def456", keys)
+	}
+	if ttl := store.ttls["abc123"]; ttl != strconv.Itoa(int(first.maxTTL().Seconds())) {
+		t.Errorf("ttlInSeconds = %q, want the longest flight TTL", ttl)
+	}
+
+	// A restarted replica loads both flights across query pages
+	second := newTestTracker(t)
+	if got := second.flights.Len(); got != 2 {
+		t.Fatalf("rehydrated %d flights, want 2", got)
+	}
+	flight, _ := second.flights.Get("abc123")
+	if flight.Callsign != "UAL100" || flight.Status != StatusArriving || flight.AirportCode != "KTST" {
+		t.Errorf("rehydrated flight = %+v", flight)
+	}
+}
+
+func TestStateStoreDeletesExpiredFlights(t *testing.T) {
+	store := withStateStore(t)
+	at := newTestTracker(t)
+	track(t, at, testUpdate("abc123", 40.05, -73))
+	if removed := at.sweepStale(time.Now().Add(time.Hour)); removed != 1 {
+		t.Fatalf("swept %d flights, want 1", removed)
+	}
+	if keys := store.keys(); len(keys) != 0 {
+		t.Errorf("state store still holds %v after expiry", keys)
+	}
+}
+
+func TestStateStoreDisabledWithoutName(t *testing.T) {
+	t.Setenv("STATE_STORE_NAME", "")
+	if at := newTestTracker(t); at.stateStore != nil {
+		t.Error("state store enabled without STATE_STORE_NAME")
+	}
+}
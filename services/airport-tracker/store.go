@@ -0,0 +1,225 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+const defaultStoreShards = 16
+
+// flightStore holds tracked flights in independently locked shards keyed by
+// a hash of the ICAO24 address, so updates for different aircraft rarely
+// contend and readers never block the whole store.
+//
+// Stored flights are never modified in place; Update swaps in a new value.
+// Get, Collect and Snapshot return shallow copies whose slices and pointers
+// (History, Tags, the optional measurements) are shared with the store, so
+// callers may read them after the lock is released but must replace rather
+// than write through them.
+type flightStore struct {
+	shards []*flightShard
+}
+
+type flightShard struct {
+	mu      sync.RWMutex
+	flights map[string]*TrackedFlight
+}
+
+func newFlightStore(shardCount int) *flightStore {
+	if shardCount < 1 {
+		shardCount = 1
+	}
+	store := &flightStore{shards: make([]*flightShard, shardCount)}
+	for i := range store.shards {
+		store.shards[i] = &flightShard{flights: make(map[string]*TrackedFlight)}
+	}
+	return store
+}
+
+func (s *flightStore) shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(s.shards)))
+}
+
+func (s *flightStore) shardFor(key string) *flightShard {
+	return s.shards[s.shardIndex(key)]
+}
+
+// Get returns a copy of the flight stored under key
+func (s *flightStore) Get(key string) (TrackedFlight, bool) {
+	shard := s.shardFor(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	flight, ok := shard.flights[key]
+	if !ok {
+		return TrackedFlight{}, false
+	}
+	return *flight, true
+}
+
+// Update atomically replaces the flight under key with the result of fn,
+// which receives the current value (nil when absent). Returning nil leaves
+// the store unchanged. fn runs under the shard's write lock.
+func (s *flightStore) Update(key string, fn func(prev *TrackedFlight) *TrackedFlight) {
+	shard := s.shardFor(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if next := fn(shard.flights[key]); next != nil {
+		shard.flights[key] = next
+	}
+}
+
+// flightWrite is one change applied by Apply. fn receives the current flight
+// (nil when absent) and returns its replacement, or nil to leave it as is;
+// with remove set the flight is deleted instead. done, when set, is called
+// once the locks are released with the flight before and after the write,
+// either of which may be nil.
+type flightWrite struct {
+	key    string
+	fn     func(prev *TrackedFlight) *TrackedFlight
+	remove bool
+	done   func(prev, next *TrackedFlight)
+}
+
+// Apply performs writes in order under a single acquisition of the shard
+// locks they touch, so no reader sees part of the batch, and then calls
+// their done functions in the same order.
+func (s *flightStore) Apply(writes []flightWrite) {
+	if len(writes) == 0 {
+		return
+	}
+	prevs := make([]*TrackedFlight, len(writes))
+	nexts := make([]*TrackedFlight, len(writes))
+	s.applyLocked(writes, prevs, nexts)
+	for i, write := range writes {
+		if write.done != nil {
+			write.done(prevs[i], nexts[i])
+		}
+	}
+}
+
+func (s *flightStore) applyLocked(writes []flightWrite, prevs, nexts []*TrackedFlight) {
+	// Lock in shard order, as Snapshot does, so batches cannot deadlock
+	touched := make([]bool, len(s.shards))
+	for _, write := range writes {
+		touched[s.shardIndex(write.key)] = true
+	}
+	for i, shard := range s.shards {
+		if touched[i] {
+			shard.mu.Lock()
+		}
+	}
+	defer func() {
+		for i, shard := range s.shards {
+			if touched[i] {
+				shard.mu.Unlock()
+			}
+		}
+	}()
+
+	for i, write := range writes {
+		shard := s.shardFor(write.key)
+		prev := shard.flights[write.key]
+		prevs[i] = prev
+		switch {
+		case write.remove:
+			delete(shard.flights, write.key)
+		case write.fn != nil:
+			if next := write.fn(prev); next != nil {
+				shard.flights[write.key] = next
+				nexts[i] = next
+			}
+		}
+	}
+}
+
+// Delete removes the flight under key, reporting whether it was present
+func (s *flightStore) Delete(key string) bool {
+	shard := s.shardFor(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	_, ok := shard.flights[key]
+	delete(shard.flights, key)
+	return ok
+}
+
+// Collect returns copies of every flight accepted by match (all flights when
+// match is nil). Each shard is read-locked only while it is being copied.
+func (s *flightStore) Collect(match func(*TrackedFlight) bool) []TrackedFlight {
+	flights := []TrackedFlight{}
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		for _, flight := range shard.flights {
+			if match == nil || match(flight) {
+				flights = append(flights, *flight)
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	return flights
+}
+
+// Snapshot returns copies of every flight as of a single instant: all shards
+// are read-locked together, in order, while they are copied.
+func (s *flightStore) Snapshot() []TrackedFlight {
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+	}
+	defer func() {
+		for _, shard := range s.shards {
+			shard.mu.RUnlock()
+		}
+	}()
+	flights := []TrackedFlight{}
+	for _, shard := range s.shards {
+		for _, flight := range shard.flights {
+			flights = append(flights, *flight)
+		}
+	}
+	return flights
+}
+
+// Len returns the number of tracked flights across all shards
+func (s *flightStore) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		n += len(shard.flights)
+		shard.mu.RUnlock()
+	}
+	return n
+}
+
+// Count returns how many flights match accepts without copying any of them.
+// Each shard is read-locked only while it is scanned.
+func (s *flightStore) Count(match func(*TrackedFlight) bool) int {
+	n := 0
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		for _, flight := range shard.flights {
+			if match(flight) {
+				n++
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	return n
+}
+
+// DeleteWhere removes every flight accepted by match and returns copies of
+// the removed flights. Each shard is write-locked only while it is scanned.
+func (s *flightStore) DeleteWhere(match func(*TrackedFlight) bool) []TrackedFlight {
+	var removed []TrackedFlight
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		for key, flight := range shard.flights {
+			if match(flight) {
+				removed = append(removed, *flight)
+				delete(shard.flights, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return removed
+}
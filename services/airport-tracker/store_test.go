@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func storeWith(shards, n int) *flightStore {
+	store := newFlightStore(shards)
+	for i := 0; i < n; i++ {
+		icao24 := fmt.Sprintf("a%05d", i)
+		status := StatusNearby
+		if i%3 == 0 {
+			status = StatusArriving
+		}
+		store.Update(icao24, func(*TrackedFlight) *TrackedFlight {
+			return &TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: icao24}, Status: status}
+		})
+	}
+	return store
+}
+
+func icaos(flights []TrackedFlight) []string {
+	keys := make([]string, 0, len(flights))
+	for _, flight := range flights {
+		keys = append(keys, flight.ICAO24)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestShardedReadsAggregateAcrossShards(t *testing.T) {
+	const n = 300
+	arriving := func(f *TrackedFlight) bool { return f.Status == StatusArriving }
+	want := icaos(storeWith(1, n).Collect(nil))
+	for _, shards := range []int{0, 1, 7, 16, 64} {
+		store := storeWith(shards, n)
+		if got := store.Len(); got != n {
+			t.Errorf("%d shards: Len = %d, want %d", shards, got, n)
+		}
+		if got := icaos(store.Collect(nil)); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%d shards: Collect returned %d flights, want %d", shards, len(got), n)
+		}
+		if got := icaos(store.Snapshot()); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%d shards: Snapshot returned %d flights, want %d", shards, len(got), n)
+		}
+		if got := store.Count(arriving); got != n/3 {
+			t.Errorf("%d shards: Count = %d, want %d", shards, got, n/3)
+		}
+		if got := len(store.Collect(arriving)); got != n/3 {
+			t.Errorf("%d shards: Collect(arriving) = %d, want %d", shards, got, n/3)
+		}
+		if removed := store.DeleteWhere(arriving); len(removed) != n/3 || store.Len() != n-n/3 {
+			t.Errorf("%d shards: DeleteWhere removed %d, %d left", shards, len(removed), store.Len())
+		}
+	}
+}
+
+// BenchmarkStoreConcurrentUpdates shows contention falling as shards are
+// added: parallel writers update distinct aircraft while a reader scans
+func BenchmarkStoreConcurrentUpdates(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("a%05d", i)
+	}
+	for _, shards := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			store := newFlightStore(shards)
+			stop := make(chan struct{})
+			var reader sync.WaitGroup
+			reader.Add(1)
+			go func() {
+				defer reader.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						store.Len()
+					}
+				}
+			}()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					store.Update(keys[i%len(keys)], func(prev *TrackedFlight) *TrackedFlight {
+						return &TrackedFlight{LastSeenLocal: "x"}
+					})
+					i++
+				}
+			})
+			b.StopTimer()
+			close(stop)
+			reader.Wait()
+		})
+	}
+}
+
+func TestSnapshotCopiesAreIndependent(t *testing.T) {
+	store := storeWith(4, 10)
+	snapshot := store.Snapshot()
+	for i := range snapshot {
+		snapshot[i].Status = StatusLeft
+		snapshot[i].Callsign = "CHANGED"
+	}
+	for _, flight := range store.Snapshot() {
+		if flight.Status == StatusLeft || flight.Callsign == "CHANGED" {
+			t.Fatalf("changing a snapshot changed the stored %s", flight.ICAO24)
+		}
+	}
+}
+
+// Run with -race: listing encodes copies while updates replace the stored
+// flights, so the two never touch the same memory
+func TestListingWhileProcessingUpdates(t *testing.T) {
+	at := newTestTracker(t)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				update := testUpdate(fmt.Sprintf("%02x%04x", w, i%20), 40+float64(i%10)*0.01, -73)
+				update.BaroAltitude, update.VerticalRate, update.Velocity = ptr(float64(1000+i)), ptr(-5.0), ptr(100.0)
+				if _, err := at.processFlightUpdate(context.Background(), update); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	for target, handler := range map[string]http.HandlerFunc{
+		"/api/v1/flights/all?units=imperial":              at.handleAllFlights,
+		"/api/v1/airports/KTST/arrivals?coord_format=dms": at.handleArrivals,
+		"/api/v1/airports/KTST/nearby":                    at.handleNearby,
+	} {
+		wg.Add(1)
+		go func(target string, handler http.HandlerFunc) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if rec := call(handler, http.MethodGet, target, map[string]string{"code": "KTST"}); rec.Code != http.StatusOK {
+					t.Errorf("%s: code %d", target, rec.Code)
+					return
+				}
+			}
+		}(target, handler)
+	}
+	wg.Wait()
+
+	if n := at.flights.Len(); n != 80 {
+		t.Errorf("tracked %d flights, want 80", n)
+	}
+	for _, flight := range at.flights.Snapshot() {
+		if *flight.Velocity != 100 {
+			t.Fatalf("%s velocity = %v: an imperial response changed stored state", flight.ICAO24, *flight.Velocity)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamClientBuffer = 64
+	streamWriteTimeout = 5 * time.Second
+)
+
+// flightHub fans tracked-flight updates out to WebSocket clients. Publish
+// never blocks: a client whose buffer is full is disconnected instead.
+type flightHub struct {
+	mu      sync.Mutex
+	clients map[*streamClient]struct{}
+}
+
+// streamClient is one subscriber; airport filters updates when non-empty
+type streamClient struct {
+	send    chan TrackedFlight
+	airport string
+}
+
+func newFlightHub() *flightHub {
+	return &flightHub{clients: make(map[*streamClient]struct{})}
+}
+
+func (h *flightHub) subscribe(airport string) *streamClient {
+	client := &streamClient{send: make(chan TrackedFlight, streamClientBuffer), airport: airport}
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+	return client
+}
+
+// unsubscribe removes client and closes its channel; it is safe to call
+// more than once
+func (h *flightHub) unsubscribe(client *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+	}
+}
+
+// Publish queues flight for every interested client, dropping slow ones
+func (h *flightHub) Publish(flight TrackedFlight) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if client.airport != "" && !strings.EqualFold(client.airport, flight.AirportCode) {
+			continue
+		}
+		select {
+		case client.send <- flight:
+		default:
+			slog.Warn("disconnecting slow stream client", "airport", client.airport)
+			delete(h.clients, client)
+			close(client.send)
+		}
+	}
+}
+
+var streamUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// GET /api/v1/flights/stream?airport= - WebSocket feed of tracked flights
+// as they are updated
+func (at *AirportTracker) handleFlightStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has already replied with an error
+	}
+	defer conn.Close()
+
+	client := at.hub.subscribe(r.URL.Query().Get("airport"))
+	defer at.hub.unsubscribe(client)
+
+	// Drain client messages so close frames are processed
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case flight, ok := <-client.send:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow"),
+					time.Now().Add(streamWriteTimeout))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(flight); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
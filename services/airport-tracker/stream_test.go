@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func (h *flightHub) clientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+func TestStreamPushesFilteredUpdates(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	server := httptest.NewServer(http.HandlerFunc(at.handleFlightStream))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?airport=kaaa", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// The handler subscribes just after the upgrade completes
+	for deadline := time.Now().Add(time.Second); at.hub.clientCount() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("stream client never subscribed")
+		}
+	}
+
+	track(t, at, testUpdate("bbb001", 45.05, -73), testUpdate("aaa001", 40.05, -73))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var flight TrackedFlight
+	if err := conn.ReadJSON(&flight); err != nil {
+		t.Fatal(err)
+	}
+	if flight.ICAO24 != "aaa001" || flight.AirportCode != "KAAA" {
+		t.Errorf("pushed %s at %s, want aaa001 at KAAA only", flight.ICAO24, flight.AirportCode)
+	}
+
+	conn.Close()
+	for deadline := time.Now().Add(time.Second); at.hub.clientCount() != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("closed client still subscribed")
+		}
+	}
+}
+
+func TestHubDropsSlowClients(t *testing.T) {
+	hub := newFlightHub()
+	slow := hub.subscribe("")
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i <= streamClientBuffer; i++ {
+			hub.Publish(TrackedFlight{AirportCode: "KTST"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow client")
+	}
+	if hub.clientCount() != 0 {
+		t.Error("slow client still subscribed")
+	}
+	n := 0
+	for range slow.send {
+		n++
+	}
+	if n != streamClientBuffer {
+		t.Errorf("slow client received %d buffered flights, want %d before being closed", n, streamClientBuffer)
+	}
+	hub.unsubscribe(slow) // already removed; must not panic
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const summaryDateLayout = "2006-01-02"
+
+// DailyCounts is the number of flights that started arriving or departing
+// at an airport during one local day
+type DailyCounts struct {
+	Date       string `json:"date"`
+	Arrivals   int    `json:"arrivals"`
+	Departures int    `json:"departures"`
+}
+
+// airportDays keeps only the current and previous day for one airport
+type airportDays struct {
+	current  DailyCounts
+	previous DailyCounts
+}
+
+// dailySummary accumulates arrival and departure transitions per airport.
+// Days roll over at resetHour local time in each airport's timezone.
+type dailySummary struct {
+	mu        sync.Mutex
+	resetHour int
+	airports  map[string]*airportDays
+}
+
+func newDailySummary(resetHour int) *dailySummary {
+	if resetHour < 0 || resetHour > 23 {
+		resetHour = 0
+	}
+	return &dailySummary{resetHour: resetHour, airports: make(map[string]*airportDays)}
+}
+
+// dayOf returns the summary day that now falls in for airport
+func (s *dailySummary) dayOf(airport AirportConfig, now time.Time) time.Time {
+	local := airport.localTime(now).Add(-time.Duration(s.resetHour) * time.Hour)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// roll advances days to the day containing now. The previous day is kept
+// only if it immediately precedes the new one. Called with s.mu held.
+func (s *dailySummary) roll(airport AirportConfig, now time.Time) *airportDays {
+	days, ok := s.airports[airport.ICAO]
+	if !ok {
+		days = &airportDays{}
+		s.airports[airport.ICAO] = days
+	}
+	today := s.dayOf(airport, now)
+	date := today.Format(summaryDateLayout)
+	if days.current.Date == date {
+		return days
+	}
+	yesterday := today.AddDate(0, 0, -1).Format(summaryDateLayout)
+	if days.current.Date == yesterday {
+		days.previous = days.current
+	} else {
+		days.previous = DailyCounts{Date: yesterday}
+	}
+	days.current = DailyCounts{Date: date}
+	return days
+}
+
+// Record counts a flight entering status at airport
+func (s *dailySummary) Record(airport AirportConfig, status string, now time.Time) {
+	if status != StatusArriving && status != StatusDeparting {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	days := s.roll(airport, now)
+	if status == StatusArriving {
+		days.current.Arrivals++
+	} else {
+		days.current.Departures++
+	}
+}
+
+// Snapshot returns the counts for the day containing now and the day before
+func (s *dailySummary) Snapshot(airport AirportConfig, now time.Time) (current, previous DailyCounts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	days := s.roll(airport, now)
+	return days.current, days.previous
+}
+
+// airportByCode returns the configured airport with the given ICAO code
+func (at *AirportTracker) airportByCode(code string) (AirportConfig, bool) {
+	for _, airport := range at.airportList() {
+		if strings.EqualFold(airport.ICAO, code) {
+			return airport, true
+		}
+	}
+	return AirportConfig{}, false
+}
+
+// GET /api/v1/airports/{code}/daily-summary - Arrivals and departures today and yesterday
+func (at *AirportTracker) handleDailySummary(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	airport, ok := at.airportByCode(code)
+	if !ok {
+		at.metrics.unknownAirports.Inc()
+		http.Error(w, fmt.Sprintf("Unknown airport %q", code), http.StatusNotFound)
+		return
+	}
+
+	now := at.now()
+	current, previous := at.dailySummary.Snapshot(airport, now)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"airport_code": airport.ICAO,
+		"timezone":     airport.localTime(now).Location().String(),
+		"reset_hour":   at.dailySummary.resetHour,
+		"today":        current,
+		"yesterday":    previous,
+	})
+}
+
+// GET /api/v1/airports/{code}/summary - Flight counts by status at an
+// airport, for dashboards that do not need the flights themselves
+func (at *AirportTracker) handleAirportSummary(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	airport, ok := at.airportByCode(code)
+	if !ok {
+		at.metrics.unknownAirports.Inc()
+		http.Error(w, fmt.Sprintf("Unknown airport %q", code), http.StatusNotFound)
+		return
+	}
+
+	flights := at.listFlights(func(flight *TrackedFlight) bool {
+		return strings.EqualFold(flight.AirportCode, airport.ICAO)
+	})
+	counts := map[string]int{StatusArriving: 0, StatusDeparting: 0, StatusNearby: 0, StatusOnGround: 0}
+	for _, flight := range flights {
+		counts[flight.Status]++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"airport_code":  airport.ICAO,
+		StatusArriving:  counts[StatusArriving],
+		StatusDeparting: counts[StatusDeparting],
+		StatusNearby:    counts[StatusNearby],
+		StatusOnGround:  counts[StatusOnGround],
+		"total":         len(flights),
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDailySummaryResetsAtLocalMidnight(t *testing.T) {
+	airport := testAirport("KNYC", 40.64, -73.78)
+	airport.Timezone = "America/New_York"
+	at := newTestTracker(t, airport)
+	clock := time.Date(2024, 1, 16, 3, 0, 0, 0, time.UTC) // 22:00 on the 15th in New York
+	at.clock = func() time.Time { return clock }
+	loaded, _ := at.airportByCode("KNYC")
+
+	at.dailySummary.Record(loaded, StatusArriving, clock)
+	at.dailySummary.Record(loaded, StatusArriving, clock.Add(time.Minute))
+	at.dailySummary.Record(loaded, StatusDeparting, clock.Add(2*time.Minute))
+	at.dailySummary.Record(loaded, StatusNearby, clock.Add(3*time.Minute))
+
+	summary := func() (today, yesterday DailyCounts) {
+		var body struct {
+			Today     DailyCounts `json:"today"`
+			Yesterday DailyCounts `json:"yesterday"`
+		}
+		decodeBody(t, call(at.handleDailySummary, http.MethodGet, "/api/v1/airports/KNYC/daily-summary", map[string]string{"code": "knyc"}), &body)
+		return body.Today, body.Yesterday
+	}
+	if today, _ := summary(); today != (DailyCounts{Date: "2024-01-15", Arrivals: 2, Departures: 1}) {
+		t.Errorf("before midnight: today = %+v", today)
+	}
+
+	clock = clock.Add(2 * time.Hour) // past local midnight, still the 16th in UTC
+	today, yesterday := summary()
+	if today != (DailyCounts{Date: "2024-01-16"}) || yesterday != (DailyCounts{Date: "2024-01-15", Arrivals: 2, Departures: 1}) {
+		t.Errorf("after midnight: today = %+v, yesterday = %+v", today, yesterday)
+	}
+
+	clock = clock.Add(48 * time.Hour)
+	if _, yesterday := summary(); yesterday != (DailyCounts{Date: "2024-01-17"}) {
+		t.Errorf("after a quiet day: yesterday = %+v, want empty 2024-01-17", yesterday)
+	}
+}
+
+func TestDailySummaryResetHour(t *testing.T) {
+	s := newDailySummary(6)
+	airport := testAirport("KTST", 40, -73)
+	before := time.Date(2024, 1, 16, 5, 59, 0, 0, time.UTC)
+	s.Record(airport, StatusDeparting, before)
+	if today, _ := s.Snapshot(airport, before); today.Date != "2024-01-15" || today.Departures != 1 {
+		t.Errorf("before 06:00: %+v, want counted on 2024-01-15", today)
+	}
+	if today, yesterday := s.Snapshot(airport, before.Add(time.Minute)); today.Date != "2024-01-16" || yesterday.Departures != 1 {
+		t.Errorf("at 06:00: today %+v, yesterday %+v", today, yesterday)
+	}
+}
+
+func TestDailySummaryUnknownAirport(t *testing.T) {
+	at := newTestTracker(t)
+	if rec := call(at.handleDailySummary, http.MethodGet, "/api/v1/airports/KZZZ/daily-summary", map[string]string{"code": "KZZZ"}); rec.Code != http.StatusNotFound {
+		t.Errorf("code = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAirportSummaryCountsByStatus(t *testing.T) {
+	at := newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 45, -73))
+	for icao24, flight := range map[string]struct{ airport, status string }{
+		"a1": {"KAAA", StatusArriving},
+		"a2": {"KAAA", StatusArriving},
+		"d1": {"KAAA", StatusDeparting},
+		"n1": {"KAAA", StatusNearby},
+		"g1": {"KAAA", StatusOnGround},
+		"b1": {"KBBB", StatusArriving},
+	} {
+		storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: icao24}, AirportCode: flight.airport, Status: flight.status})
+	}
+
+	var body map[string]interface{}
+	decodeBody(t, call(at.handleAirportSummary, http.MethodGet, "/api/v1/airports/kaaa/summary", map[string]string{"code": "kaaa"}), &body)
+	want := map[string]interface{}{
+		"airport_code":  "KAAA",
+		StatusArriving:  2.0,
+		StatusDeparting: 1.0,
+		StatusNearby:    1.0,
+		StatusOnGround:  1.0,
+		"total":         5.0,
+	}
+	if len(body) != len(want) {
+		t.Errorf("summary = %v, want %v", body, want)
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("%s = %v, want %v", key, body[key], value)
+		}
+	}
+
+	if rec := call(at.handleAirportSummary, http.MethodGet, "/api/v1/airports/KZZZ/summary", map[string]string{"code": "KZZZ"}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown airport code = %d, want 404", rec.Code)
+	}
+}
+
+func TestAirportSummaryCountsSharedBackendFlights(t *testing.T) {
+	client := newFakeRedis()
+	first, second := newTestTracker(t), newTestTracker(t)
+	for _, at := range []*AirportTracker{first, second} {
+		at.backend = &redisBackend{client: client, prefix: defaultRedisKeyPrefix, ttl: time.Minute}
+	}
+	track(t, first, testUpdate("abc123", 40.05, -73))
+
+	var body map[string]interface{}
+	decodeBody(t, call(second.handleAirportSummary, http.MethodGet, "/api/v1/airports/KTST/summary", map[string]string{"code": "KTST"}), &body)
+	if body[StatusNearby] != 1.0 || body["total"] != 1.0 {
+		t.Errorf("second replica summary = %v, want the flight tracked by the first", body)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFlightTTL     = 5 * time.Minute
+	defaultSweepInterval = 30 * time.Second
+	defaultSweepJitter   = 0.1
+)
+
+// EvictionHook is notified of every flight the sweeper removes, e.g. to
+// emit a "left area" event. It is always called outside the store locks.
+type EvictionHook interface {
+	OnEvict(flight TrackedFlight)
+}
+
+// noopEvictionHook is the default and does nothing
+type noopEvictionHook struct{}
+
+func (noopEvictionHook) OnEvict(flight TrackedFlight) {}
+
+// webhookEvictionHook POSTs each evicted flight as JSON to a URL
+type webhookEvictionHook struct {
+	url    string
+	client *http.Client
+}
+
+func (h *webhookEvictionHook) OnEvict(flight TrackedFlight) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"event":        "flight_evicted",
+		"icao24":       flight.ICAO24,
+		"callsign":     flight.Callsign,
+		"airport_code": flight.AirportCode,
+		"status":       flight.Status,
+		"last_seen":    flight.LastSeen,
+	})
+	if err != nil {
+		slog.Warn("failed to encode eviction", "icao24", flight.ICAO24, "error", err)
+		return
+	}
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		slog.Warn("eviction webhook failed", "icao24", flight.ICAO24, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("eviction webhook returned an error", "icao24", flight.ICAO24, "http_status", resp.Status)
+	}
+}
+
+// newEvictionHookFromEnv returns a webhook hook when EVICTION_WEBHOOK_URL is
+// set and the no-op hook otherwise
+func newEvictionHookFromEnv() EvictionHook {
+	url := envString("EVICTION_WEBHOOK_URL", "")
+	if url == "" {
+		return noopEvictionHook{}
+	}
+	slog.Info("eviction webhook enabled", "url", url)
+	return &webhookEvictionHook{url: url, client: &http.Client{Timeout: 2 * time.Second}}
+}
+
+// parseStatusTTLs parses FLIGHT_TTL_BY_STATUS, a comma-separated list of
+// status=seconds pairs such as "arriving=30,departing=30,nearby=300"
+func parseStatusTTLs(raw string) (map[string]time.Duration, error) {
+	ttls := map[string]time.Duration{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		status, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid status TTL %q: expected status=seconds", pair)
+		}
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid TTL for status %q: %q", status, value)
+		}
+		ttls[strings.ToLower(strings.TrimSpace(status))] = time.Duration(seconds * float64(time.Second))
+	}
+	return ttls, nil
+}
+
+// statusTTLsFromEnv reads FLIGHT_TTL_BY_STATUS, ignoring it if malformed
+func statusTTLsFromEnv() map[string]time.Duration {
+	ttls, err := parseStatusTTLs(envString("FLIGHT_TTL_BY_STATUS", ""))
+	if err != nil {
+		slog.Warn("ignoring FLIGHT_TTL_BY_STATUS", "error", err)
+		return map[string]time.Duration{}
+	}
+	return ttls
+}
+
+// ttlFor returns how long a flight with the given status is kept without
+// updates: the per-status TTL when configured, otherwise the default TTL
+func (at *AirportTracker) ttlFor(status string) time.Duration {
+	if ttl, ok := at.statusTTLs[status]; ok {
+		return ttl
+	}
+	return at.flightTTL
+}
+
+// maxTTL returns the longest time any flight is kept without updates
+func (at *AirportTracker) maxTTL() time.Duration {
+	longest := at.flightTTL
+	for _, ttl := range at.statusTTLs {
+		if ttl > longest {
+			longest = ttl
+		}
+	}
+	return longest
+}
+
+// sweepStale removes flights whose observation time (see observedAt) is
+// older than their status's TTL as of now and returns how many were
+// evicted. The eviction hook and backend deletes run after the store locks
+// are released so a slow hook cannot block ingestion.
+func (at *AirportTracker) sweepStale(now time.Time) int {
+	evicted := at.flights.DeleteWhere(func(flight *TrackedFlight) bool {
+		return flightAge(flight, now) > at.ttlFor(flight.Status)
+	})
+
+	for _, flight := range evicted {
+		at.deleteRemote(at.flightKey(flight.ICAO24, flight.AirportCode))
+		at.evictionHook.OnEvict(flight)
+	}
+	if len(evicted) > 0 {
+		slog.Info("evicted stale flights", "count", len(evicted))
+	}
+	return len(evicted)
+}
+
+// jitteredInterval returns interval randomly stretched or shortened by up
+// to jitter (a fraction of interval), so replicas started together do not
+// sweep in lockstep
+func jitteredInterval(interval time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	jitter = math.Min(jitter, 1)
+	return time.Duration(float64(interval) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+// startSweeper evicts stale flights every interval, varied by jitter, until
+// stopSweeper is called. With a coalescing ingest queue the sweep itself
+// runs on the queue worker, between batches.
+func (at *AirportTracker) startSweeper(interval time.Duration, jitter float64) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	at.stopSweeper = func() {
+		close(done)
+		<-stopped
+	}
+
+	go func() {
+		defer close(stopped)
+		timer := time.NewTimer(jitteredInterval(interval, jitter))
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				now := at.now()
+				if !at.queue.requestSweep(now) {
+					at.sweepStale(now)
+				}
+				timer.Reset(jitteredInterval(interval, jitter))
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// POST /api/v1/maintenance/sweep - Evict stale flights immediately
+func (at *AirportTracker) handleMaintenanceSweep(w http.ResponseWriter, r *http.Request) {
+	evicted := at.sweepStale(at.now())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"evicted":   evicted,
+		"remaining": at.flights.Len(),
+	})
+}
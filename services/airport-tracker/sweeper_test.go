@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHook records evicted flights. It reads the store from inside
+// OnEvict, which would deadlock if the sweeper still held a shard lock.
+type recordingHook struct {
+	at      *AirportTracker
+	mu      sync.Mutex
+	evicted []string
+}
+
+func (h *recordingHook) OnEvict(flight TrackedFlight) {
+	read := make(chan struct{})
+	go func() {
+		h.at.flights.Len()
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(time.Second):
+		panic("eviction hook called with a store lock held")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.evicted = append(h.evicted, flight.ICAO24)
+}
+
+func TestEvictionHookFiresOutsideLocks(t *testing.T) {
+	at := newTestTracker(t)
+	hook := &recordingHook{at: at}
+	at.evictionHook = hook
+	track(t, at, testUpdate("abc123", 40.05, -73), testUpdate("def456", 40.05, -73))
+
+	if n := at.sweepStale(time.Now()); n != 0 {
+		t.Fatalf("evicted %d fresh flights", n)
+	}
+	if n := at.sweepStale(time.Now().Add(at.flightTTL + time.Minute)); n != 2 {
+		t.Fatalf("evicted %d flights, want 2", n)
+	}
+	if len(hook.evicted) != 2 {
+		t.Errorf("hook saw %v, want both flights", hook.evicted)
+	}
+}
+
+func TestWebhookEvictionHookPostsFlight(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	}))
+	defer server.Close()
+
+	t.Setenv("EVICTION_WEBHOOK_URL", server.URL)
+	newEvictionHookFromEnv().OnEvict(TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "abc123"}, AirportCode: "KTST"})
+	event := <-received
+	if event["event"] != "flight_evicted" || event["icao24"] != "abc123" || event["airport_code"] != "KTST" {
+		t.Errorf("webhook payload = %v", event)
+	}
+}
+
+func TestStatusTTLsExpireFlightsSeparately(t *testing.T) {
+	at := newTestTracker(t)
+	at.flightTTL = 5 * time.Minute
+	ttls, err := parseStatusTTLs("arriving=30, NEARBY=300,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at.statusTTLs = ttls
+	seen := time.Now()
+	for icao24, status := range map[string]string{"aaa001": StatusArriving, "bbb002": StatusNearby, "ccc003": StatusDeparting} {
+		storeFlight(at, TrackedFlight{
+			FlightUpdate: FlightUpdate{ICAO24: icao24, LastContact: seen.Unix()},
+			AirportCode:  "KTST",
+			Status:       status,
+			LastSeen:     seen,
+			ObservedAt:   seen,
+		})
+	}
+
+	if n := at.sweepStale(seen.Add(time.Minute)); n != 1 {
+		t.Fatalf("after 1m evicted %d, want only the arriving flight", n)
+	}
+	if _, ok := at.flights.Get("aaa001"); ok {
+		t.Error("arriving flight kept past its 30s TTL")
+	}
+	// Departing has no TTL of its own and falls back to FLIGHT_TTL
+	if n := at.sweepStale(seen.Add(5*time.Minute + time.Second)); n != 2 {
+		t.Errorf("after 5m evicted %d, want nearby and departing", n)
+	}
+	if at.maxTTL() != 5*time.Minute {
+		t.Errorf("maxTTL = %v, want 5m", at.maxTTL())
+	}
+}
+
+func TestParseStatusTTLsRejectsMalformedPairs(t *testing.T) {
+	for _, raw := range []string{"arriving", "arriving=0", "nearby=soon"} {
+		if _, err := parseStatusTTLs(raw); err == nil {
+			t.Errorf("%q parsed", raw)
+		}
+	}
+}
+
+func TestMaintenanceSweepEvictsOnDemand(t *testing.T) {
+	at := newTestTracker(t)
+	now := time.Now()
+	at.clock = func() time.Time { return now }
+	for icao24, age := range map[string]time.Duration{"old001": 2 * at.flightTTL, "old002": at.flightTTL + time.Second, "new001": time.Second} {
+		seen := now.Add(-age)
+		storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: icao24}, AirportCode: "KTST", LastSeen: seen, ObservedAt: seen})
+	}
+
+	rec := call(at.handleMaintenanceSweep, http.MethodPost, "/api/v1/maintenance/sweep", nil)
+	var body struct {
+		Evicted   int `json:"evicted"`
+		Remaining int `json:"remaining"`
+	}
+	decodeBody(t, rec, &body)
+	if body.Evicted != 2 || body.Remaining != 1 {
+		t.Errorf("evicted %d, remaining %d; want 2 and 1", body.Evicted, body.Remaining)
+	}
+	if _, ok := at.flights.Get("new001"); !ok {
+		t.Error("fresh flight evicted")
+	}
+}
+
+func TestBackgroundSweeperEvictsStaleFlights(t *testing.T) {
+	t.Setenv("FLIGHT_TTL_SECONDS", "60")
+	t.Setenv("SWEEP_INTERVAL_SECONDS", "0.01")
+	t.Setenv("SWEEP_JITTER", "0")
+	at, err := NewAirportTracker(writeAirports(t, testAirport("KTST", 40, -73)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at.flightTTL != time.Minute {
+		t.Fatalf("flightTTL = %v, want 1m from FLIGHT_TTL_SECONDS", at.flightTTL)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	storeFlight(at, TrackedFlight{FlightUpdate: FlightUpdate{ICAO24: "old001"}, AirportCode: "KTST", LastSeen: old, ObservedAt: old})
+	track(t, at, testUpdate("new001", 40.05, -73))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := at.flights.Get("old001"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stale flight still tracked after the sweep interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := at.flights.Get("new001"); !ok {
+		t.Error("fresh flight evicted")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		at.Close()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not stop the sweeper")
+	}
+}
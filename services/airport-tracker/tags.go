@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// TagRule labels flights that satisfy every condition the rule sets.
+// List conditions match when any entry matches; unset conditions are ignored.
+type TagRule struct {
+	Label            string   `json:"label"`
+	Squawks          []string `json:"squawks,omitempty"`
+	OriginCountries  []string `json:"origin_countries,omitempty"`
+	CallsignPrefixes []string `json:"callsign_prefixes,omitempty"`
+	MinAltitudeM     *float64 `json:"min_altitude_m,omitempty"`
+	MaxAltitudeM     *float64 `json:"max_altitude_m,omitempty"`
+	MaxVelocityMS    *float64 `json:"max_velocity_ms,omitempty"`
+}
+
+// loadTagRules reads tagging rules from TAG_RULES_PATH. A missing file at the
+// default location is not an error; the service simply runs without tags.
+func (at *AirportTracker) loadTagRules() error {
+	explicit := os.Getenv("TAG_RULES_PATH") != ""
+	path := envString("TAG_RULES_PATH", DefaultTagRulesPath)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("failed to read tag rules %s: %w", path, err)
+	}
+
+	var rules []TagRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("failed to parse tag rules: %w", err)
+	}
+	for i, rule := range rules {
+		if rule.Label == "" {
+			return fmt.Errorf("tag rule %d has no label", i)
+		}
+	}
+
+	at.tagRules = rules
+	slog.Info("loaded tag rules", "count", len(rules), "path", path)
+	return nil
+}
+
+// matches reports whether the update satisfies every condition of the rule.
+func (rule TagRule) matches(update FlightUpdate) bool {
+	if len(rule.Squawks) > 0 && !containsFold(rule.Squawks, update.Squawk) {
+		return false
+	}
+	if len(rule.OriginCountries) > 0 && !containsFold(rule.OriginCountries, update.OriginCountry) {
+		return false
+	}
+	if len(rule.CallsignPrefixes) > 0 {
+		callsign := strings.ToUpper(strings.TrimSpace(update.Callsign))
+		matched := false
+		for _, prefix := range rule.CallsignPrefixes {
+			if strings.HasPrefix(callsign, strings.ToUpper(prefix)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if rule.MinAltitudeM != nil || rule.MaxAltitudeM != nil {
+		altitude, ok := effectiveAltitude(update)
+		if !ok {
+			return false
+		}
+		if rule.MinAltitudeM != nil && altitude < *rule.MinAltitudeM {
+			return false
+		}
+		if rule.MaxAltitudeM != nil && altitude > *rule.MaxAltitudeM {
+			return false
+		}
+	}
+	if rule.MaxVelocityMS != nil {
+		if update.Velocity == nil || *update.Velocity > *rule.MaxVelocityMS {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateTags returns the labels of every rule the update matches, in rule
+// order and without duplicates.
+func evaluateTags(rules []TagRule, update FlightUpdate) []string {
+	var tags []string
+	for _, rule := range rules {
+		if rule.matches(update) && !containsFold(tags, rule.Label) {
+			tags = append(tags, rule.Label)
+		}
+	}
+	return tags
+}
+
+func containsFold(values []string, target string) bool {
+	target = strings.TrimSpace(target)
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), target) {
+			return true
+		}
+	}
+	return false
+}
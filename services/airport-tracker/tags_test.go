@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEvaluateTags(t *testing.T) {
+	rules := []TagRule{
+		{Label: "emergency", Squawks: []string{"7700", "7600"}},
+		{Label: "government", CallsignPrefixes: []string{"sam", "RCH"}, OriginCountries: []string{"United States"}},
+		{Label: "low-and-slow", MaxAltitudeM: ptr(1000.0), MaxVelocityMS: ptr(60.0)},
+		{Label: "Emergency", Squawks: []string{"7700"}},
+	}
+	for _, tc := range []struct {
+		name   string
+		update FlightUpdate
+		want   string
+	}{
+		{"squawk", FlightUpdate{Squawk: "7700"}, "[emergency]"},
+		{"callsign and country", FlightUpdate{Callsign: " SAM44 ", OriginCountry: "united states"}, "[government]"},
+		{"country alone", FlightUpdate{Callsign: "DAL12", OriginCountry: "United States"}, "[]"},
+		{"low and slow", FlightUpdate{BaroAltitude: ptr(500.0), Velocity: ptr(40.0)}, "[low-and-slow]"},
+		{"low but fast", FlightUpdate{BaroAltitude: ptr(500.0), Velocity: ptr(120.0)}, "[]"},
+		{"no altitude", FlightUpdate{Velocity: ptr(40.0)}, "[]"},
+		{"several", FlightUpdate{Squawk: "7600", BaroAltitude: ptr(300.0), Velocity: ptr(30.0)}, "[emergency low-and-slow]"},
+	} {
+		if got := fmt.Sprint(evaluateTags(rules, tc.update)); got != tc.want {
+			t.Errorf("%s: tags %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTrackedFlightsCarryTags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tags.json")
+	if err := os.WriteFile(path, []byte(`[{"label": "watched", "callsign_prefixes": ["TST"]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TAG_RULES_PATH", path)
+	at := newTestTracker(t)
+
+	update := testUpdate("abc123", 40.05, -73)
+	update.Callsign = "TST100"
+	if _, err := at.processFlightUpdate(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+	flight, _ := at.flights.Get("abc123")
+	if fmt.Sprint(flight.Tags) != "[watched]" {
+		t.Errorf("tags = %v, want [watched]", flight.Tags)
+	}
+}
+
+func TestTagRulesWithoutLabelAreRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tags.json")
+	if err := os.WriteFile(path, []byte(`[{"squawks": ["7700"]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TAG_RULES_PATH", path)
+	if _, err := NewAirportTracker(writeAirports(t, testAirport("KTST", 40, -73))); err == nil {
+		t.Error("tag rule without a label was accepted")
+	}
+}
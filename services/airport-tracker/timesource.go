@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Flight time sources accepted by FLIGHT_TIME_SOURCE. The chosen field is
+// the single authority for a flight's age: eviction, confidence freshness
+// and TrackedFlight.ObservedAt all derive from it.
+//
+//	last_contact   when the feed last heard from the transponder (default)
+//	time_position  when the feed last received a position
+//	timestamp      when the publisher emitted the update
+//	received       when this service processed the update
+//
+// Feed fields are unix seconds. A missing (zero) field falls back to the
+// receive time, and feed times later than the receive time are clamped to
+// it so a skewed clock cannot keep a flight alive forever.
+const (
+	TimeSourceLastContact  = "last_contact"
+	TimeSourceTimePosition = "time_position"
+	TimeSourceTimestamp    = "timestamp"
+	TimeSourceReceived     = "received"
+)
+
+// timeSourceFromEnv reads FLIGHT_TIME_SOURCE, defaulting to last_contact
+func timeSourceFromEnv() string {
+	source := strings.ToLower(envString("FLIGHT_TIME_SOURCE", TimeSourceLastContact))
+	switch source {
+	case TimeSourceLastContact, TimeSourceTimePosition, TimeSourceTimestamp, TimeSourceReceived:
+		return source
+	}
+	slog.Warn("unknown FLIGHT_TIME_SOURCE, using last_contact", "value", source)
+	return TimeSourceLastContact
+}
+
+// observedAt returns when update was observed according to source
+func observedAt(update FlightUpdate, received time.Time, source string) time.Time {
+	var unix int64
+	switch source {
+	case TimeSourceLastContact:
+		unix = update.LastContact
+	case TimeSourceTimePosition:
+		unix = update.TimePosition
+	case TimeSourceTimestamp:
+		unix = update.Timestamp
+	}
+	if unix <= 0 {
+		return received
+	}
+	observed := time.Unix(unix, 0)
+	if observed.After(received) {
+		return received
+	}
+	return observed
+}
+
+// now is the tracker's current time. Tracking, ages, eviction and the
+// time-stamped views all read it rather than time.Now so they agree with
+// each other, and tests can substitute a clock.
+func (at *AirportTracker) now() time.Time {
+	if at.clock != nil {
+		return at.clock()
+	}
+	return time.Now()
+}
+
+// flightAge is how long ago a flight was last observed as of now
+func flightAge(flight *TrackedFlight, now time.Time) time.Duration {
+	return now.Sub(flight.ObservedAt)
+}
+
+// Clock skew actions (CLOCK_SKEW_ACTION) for feed times outside the window
+const (
+	ClockSkewClamp  = "clamp"  // replace the skewed time with the receive time
+	ClockSkewReject = "reject" // drop the update
+)
+
+// Clock skew directions, reported in logs and the skew metric
+const (
+	skewFuture = "future"
+	skewPast   = "past"
+)
+
+// clockSkewPolicy bounds how far the feed times of an update (timestamp,
+// last_contact and time_position) may stray from server time. A zero
+// window disables the check.
+type clockSkewPolicy struct {
+	window time.Duration
+	action string
+}
+
+// clockSkewPolicyFromEnv reads CLOCK_SKEW_WINDOW_SECONDS (off by default)
+// and CLOCK_SKEW_ACTION (clamp by default)
+func clockSkewPolicyFromEnv() clockSkewPolicy {
+	policy := clockSkewPolicy{
+		window: time.Duration(envFloat("CLOCK_SKEW_WINDOW_SECONDS", 0) * float64(time.Second)),
+		action: strings.ToLower(envString("CLOCK_SKEW_ACTION", ClockSkewClamp)),
+	}
+	if policy.action != ClockSkewClamp && policy.action != ClockSkewReject {
+		slog.Warn("unknown CLOCK_SKEW_ACTION, using clamp", "value", policy.action)
+		policy.action = ClockSkewClamp
+	}
+	return policy
+}
+
+// check returns update with every feed time outside the window set to now,
+// and the direction of the skew, or "" when all times are within the
+// window. A future skew wins when fields disagree.
+func (p clockSkewPolicy) check(update FlightUpdate, now time.Time) (FlightUpdate, string) {
+	if p.window <= 0 {
+		return update, ""
+	}
+	direction := ""
+	for _, field := range []*int64{&update.Timestamp, &update.LastContact, &update.TimePosition} {
+		if *field <= 0 {
+			continue
+		}
+		switch skew := time.Unix(*field, 0).Sub(now); {
+		case skew > p.window:
+			direction = skewFuture
+		case skew < -p.window:
+			if direction == "" {
+				direction = skewPast
+			}
+		default:
+			continue
+		}
+		*field = now.Unix()
+	}
+	return update, direction
+}
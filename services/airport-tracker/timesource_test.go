@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvictionUsesConfiguredTimeSource(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	update := FlightUpdate{
+		ICAO24:       "abc123",
+		Latitude:     40.05,
+		Longitude:    -73,
+		LastContact:  now.Add(-10 * time.Minute).Unix(),
+		TimePosition: now.Add(-10 * time.Second).Unix(),
+		Timestamp:    now.Add(-time.Hour).Unix(),
+	}
+	for _, tc := range []struct {
+		source  string
+		evicted bool
+	}{
+		{TimeSourceLastContact, true},
+		{TimeSourceTimePosition, false},
+		{TimeSourceTimestamp, true},
+		{TimeSourceReceived, false},
+	} {
+		t.Run(tc.source, func(t *testing.T) {
+			t.Setenv("FLIGHT_TIME_SOURCE", tc.source)
+			at := newTestTracker(t)
+			at.clock = func() time.Time { return now }
+			if _, err := at.processFlightUpdate(context.Background(), update); err != nil {
+				t.Fatal(err)
+			}
+			if got := at.sweepStale(at.now()); (got == 1) != tc.evicted {
+				t.Errorf("evicted %d flights, want evicted=%v", got, tc.evicted)
+			}
+		})
+	}
+}
+
+func TestObservedAtFallsBackToReceiveTime(t *testing.T) {
+	received := time.Unix(1_700_000_000, 0)
+	if got := observedAt(FlightUpdate{}, received, TimeSourceLastContact); !got.Equal(received) {
+		t.Errorf("missing field: got %v, want the receive time", got)
+	}
+	future := FlightUpdate{LastContact: received.Add(time.Hour).Unix()}
+	if got := observedAt(future, received, TimeSourceLastContact); !got.Equal(received) {
+		t.Errorf("future field: got %v, want it clamped to the receive time", got)
+	}
+}
+
+func TestClockSkewCheck(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	policy := clockSkewPolicy{window: 5 * time.Minute, action: ClockSkewClamp}
+	for _, tc := range []struct {
+		name      string
+		offset    time.Duration
+		direction string
+	}{
+		{"in window, ahead", 4 * time.Minute, ""},
+		{"in window, behind", -5 * time.Minute, ""},
+		{"future", 10 * time.Minute, skewFuture},
+		{"past", -2 * time.Hour, skewPast},
+	} {
+		at := now.Add(tc.offset).Unix()
+		update := FlightUpdate{Timestamp: at, LastContact: at, TimePosition: at}
+		corrected, direction := policy.check(update, now)
+		if direction != tc.direction {
+			t.Errorf("%s: direction %q, want %q", tc.name, direction, tc.direction)
+		}
+		want := at
+		if tc.direction != "" {
+			want = now.Unix()
+		}
+		if corrected.Timestamp != want || corrected.LastContact != want || corrected.TimePosition != want {
+			t.Errorf("%s: times %d/%d/%d, want %d", tc.name, corrected.Timestamp, corrected.LastContact, corrected.TimePosition, want)
+		}
+	}
+
+	// Disabled by default, and unset fields are never filled in
+	if _, direction := (clockSkewPolicy{}).check(FlightUpdate{Timestamp: now.Add(time.Hour).Unix()}, now); direction != "" {
+		t.Errorf("zero window: direction %q", direction)
+	}
+	if corrected, _ := policy.check(FlightUpdate{LastContact: now.Add(time.Hour).Unix()}, now); corrected.Timestamp != 0 {
+		t.Errorf("unset timestamp became %d", corrected.Timestamp)
+	}
+}
+
+func TestClockSkewActions(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	future := FlightUpdate{ICAO24: "abc123", Latitude: 40.05, Longitude: -73,
+		TimePosition: now.Add(time.Hour).Unix(), LastContact: now.Add(time.Hour).Unix()}
+	past := future
+	past.ICAO24, past.TimePosition, past.LastContact = "def456", now.Add(-time.Hour).Unix(), now.Add(-time.Hour).Unix()
+	for _, action := range []string{ClockSkewClamp, ClockSkewReject} {
+		t.Run(action, func(t *testing.T) {
+			t.Setenv("CLOCK_SKEW_WINDOW_SECONDS", "300")
+			t.Setenv("CLOCK_SKEW_ACTION", action)
+			logs := captureLogs(t, "warn")
+			at := newTestTracker(t)
+			at.clock = func() time.Time { return now }
+
+			for _, update := range []FlightUpdate{future, past} {
+				outcome, err := at.processFlightUpdate(context.Background(), update)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := map[string]string{ClockSkewClamp: OutcomeProcessed, ClockSkewReject: OutcomeRejected}[action]
+				if outcome.result != want {
+					t.Errorf("%s: outcome %+v, want %s", update.ICAO24, outcome, want)
+				}
+			}
+			if action == ClockSkewClamp {
+				flight, _ := at.flights.Get("abc123")
+				if flight.LastContact != now.Unix() || !flight.ObservedAt.Equal(now) {
+					t.Errorf("clamped last_contact %d, observed %v; want the server time", flight.LastContact, flight.ObservedAt)
+				}
+			}
+			metrics := scrape(t, at)
+			for _, line := range []string{
+				`airport_tracker_clock_skewed_updates_total{direction="future"} 1`,
+				`airport_tracker_clock_skewed_updates_total{direction="past"} 1`,
+			} {
+				if !strings.Contains(metrics, line) {
+					t.Errorf("metrics missing %s", line)
+				}
+			}
+			if !strings.Contains(logs.String(), "skewed") {
+				t.Error("skew not logged as a warning")
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultServiceName = "airport-tracker"
+
+// tracer resolves through the global provider, so it is a no-op until
+// setupTracing installs an exporting one
+var tracer = otel.Tracer("airport-tracker")
+
+// setupTracing installs an OTLP/HTTP tracer provider when
+// OTEL_EXPORTER_OTLP_ENDPOINT is set; the exporter reads the remaining
+// OTEL_EXPORTER_OTLP_* variables itself. The returned function flushes
+// pending spans and is safe to call when tracing is disabled.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	endpoint := envString("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", envString("OTEL_SERVICE_NAME", defaultServiceName)),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	slog.Info("exporting traces", "endpoint", endpoint)
+	return provider.Shutdown, nil
+}
+
+// traceHandler starts a server span per request, continuing any trace
+// context carried in the request headers
+func traceHandler(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, defaultServiceName)
+}
+
+// routeSpanNames renames the request span after the matched route template,
+// keeping span names low-cardinality (e.g. "GET /api/v1/flights/{icao24}")
+func routeSpanNames(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				trace.SpanFromContext(r.Context()).SetName(r.Method + " " + template)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// eventTraceContext returns the context to process a pub/sub message in.
+// Trace headers on the request take precedence; without them the
+// traceparent and tracestate extensions of the CloudEvent are used.
+func eventTraceContext(r *http.Request, body []byte) context.Context {
+	ctx := r.Context()
+	if r.Header.Get("traceparent") != "" {
+		return ctx
+	}
+	var event struct {
+		Traceparent string `json:"traceparent"`
+		Tracestate  string `json:"tracestate"`
+	}
+	if json.Unmarshal(body, &event) != nil || event.Traceparent == "" {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{"traceparent": event.Traceparent}
+	if event.Tracestate != "" {
+		carrier["tracestate"] = event.Tracestate
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// detachedSpanContext keeps the trace of ctx but drops its cancellation, so
+// queued work stays linked to the request that submitted it
+func detachedSpanContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestFlightUpdateRecordsSpanInEventTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	at := newTestTracker(t)
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	now := time.Now().Unix()
+	body := fmt.Sprintf(`{"specversion":"1.0","type":"flight.update","source":"feeder","id":"1",`+
+		`"traceparent":"00-%s-00f067aa0ba902b7-01","datacontenttype":"application/json",`+
+		`"data":{"icao24":"abc123","latitude":40.05,"longitude":-73,"time_position":%d,"last_contact":%d}}`, traceID, now, now)
+	req := httptest.NewRequest(http.MethodPost, flightUpdateRoute, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	rec := httptest.NewRecorder()
+	at.handleFlightUpdate(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("code = %d: %s", rec.Code, rec.Body)
+	}
+
+	var span *tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		if s.Name == "processFlightUpdate" {
+			span = &s
+			break
+		}
+	}
+	if span == nil {
+		t.Fatalf("no processFlightUpdate span among %d recorded", len(exporter.GetSpans()))
+	}
+	if got := span.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("trace ID = %s, want the CloudEvent's %s", got, traceID)
+	}
+	attrs := map[attribute.Key]string{}
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	for key, want := range map[attribute.Key]string{"icao24": "abc123", "airport": "KTST", "status": StatusNearby, "outcome": OutcomeProcessed} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %q, want %q", key, attrs[key], want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Tracking modes (TRACKING_MODE) for flights inside overlapping geofences
+const (
+	TrackingNearest = "nearest" // tracked only at the nearest containing airport
+	TrackingAll     = "all"     // tracked at every containing airport
+)
+
+// trackingModeFromEnv reads TRACKING_MODE, defaulting to nearest
+func trackingModeFromEnv() string {
+	mode := strings.ToLower(envString("TRACKING_MODE", TrackingNearest))
+	switch mode {
+	case TrackingNearest, TrackingAll:
+		return mode
+	}
+	slog.Warn("unknown TRACKING_MODE, using nearest", "value", mode)
+	return TrackingNearest
+}
+
+// flightKey is the key a flight is stored under. In nearest mode it is the
+// ICAO24 address; in all mode each airport gets its own entry, keyed
+// "icao24@AIRPORT".
+func (at *AirportTracker) flightKey(icao24, airportCode string) string {
+	if at.trackingMode == TrackingAll {
+		return icao24 + "@" + airportCode
+	}
+	return icao24
+}
+
+// flightKeys returns every key icao24 may be stored under
+func (at *AirportTracker) flightKeys(icao24 string) []string {
+	if at.trackingMode != TrackingAll {
+		return []string{icao24}
+	}
+	airports := at.airportList()
+	keys := make([]string, 0, len(airports))
+	for _, airport := range airports {
+		keys = append(keys, at.flightKey(icao24, airport.ICAO))
+	}
+	return keys
+}
+
+// localFlight returns a flight from the local store by ICAO24. In all mode
+// it is the entry at the nearest of the airports tracking it.
+func (at *AirportTracker) localFlight(icao24 string) (TrackedFlight, bool) {
+	var found TrackedFlight
+	ok := false
+	for _, key := range at.flightKeys(icao24) {
+		if flight, exists := at.flights.Get(key); exists && (!ok || flight.DistanceKm < found.DistanceKm) {
+			found, ok = flight, true
+		}
+	}
+	return found, ok
+}
+
+// uniqueAircraft reduces flights to one entry per aircraft for fleet-wide
+// views. In all mode an aircraft has an entry per airport and the nearest
+// one is kept, as localFlight does; order of first appearance is kept.
+func (at *AirportTracker) uniqueAircraft(flights []TrackedFlight) []TrackedFlight {
+	if at.trackingMode != TrackingAll {
+		return flights
+	}
+	index := make(map[string]int, len(flights))
+	unique := make([]TrackedFlight, 0, len(flights))
+	for _, flight := range flights {
+		i, seen := index[flight.ICAO24]
+		switch {
+		case !seen:
+			index[flight.ICAO24] = len(unique)
+			unique = append(unique, flight)
+		case flight.DistanceKm < unique[i].DistanceKm:
+			unique[i] = flight
+		}
+	}
+	return unique
+}
+
+// leftAirportWrites remove, in all mode, the entries of a flight at airports
+// whose geofence no longer contains it, so it stops being listed there as
+// soon as it is seen elsewhere. Each removal is published as a change to
+// StatusLeft.
+func (at *AirportTracker) leftAirportWrites(icao24 string, matches []airportMatch, now time.Time) []flightWrite {
+	inside := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		inside[m.airport.ICAO] = true
+	}
+	var writes []flightWrite
+	for _, airport := range at.airportList() {
+		if inside[airport.ICAO] {
+			continue
+		}
+		writes = append(writes, at.removeWrite(at.flightKey(icao24, airport.ICAO), func(left TrackedFlight) {
+			at.publishStatusChange(FlightStatusChange{
+				ICAO24:      left.ICAO24,
+				Callsign:    left.Callsign,
+				AirportCode: left.AirportCode,
+				OldAirport:  left.AirportCode,
+				OldStatus:   left.Status,
+				NewStatus:   StatusLeft,
+				Timestamp:   now,
+			})
+		}))
+	}
+	return writes
+}
+
+// deleteFlight stops tracking icao24 at every airport, reporting whether
+// it was tracked at all. With a shared backend the flight may be tracked
+// only by another replica, so the backend's entries are deleted as well.
+func (at *AirportTracker) deleteFlight(icao24 string) bool {
+	deleted := false
+	at.flights.Apply(at.deleteWrites(icao24, func(TrackedFlight) { deleted = true }))
+	if _, ok := at.backend.(memoryBackend); ok {
+		return deleted
+	}
+	remote := at.listFlights(func(flight *TrackedFlight) bool { return flight.ICAO24 == icao24 })
+	for _, flight := range remote {
+		at.deleteRemote(at.flightKey(flight.ICAO24, flight.AirportCode))
+		deleted = true
+	}
+	return deleted
+}
+
+// deleteWrites remove icao24 at every airport; see removeWrite
+func (at *AirportTracker) deleteWrites(icao24 string, removed func(TrackedFlight)) []flightWrite {
+	keys := at.flightKeys(icao24)
+	writes := make([]flightWrite, 0, len(keys))
+	for _, key := range keys {
+		writes = append(writes, at.removeWrite(key, removed))
+	}
+	return writes
+}
+
+// removeWrite removes the flight under key, then deletes it remotely and,
+// when removed is set, passes it the flight. Nothing happens when there is
+// no flight under key.
+func (at *AirportTracker) removeWrite(key string, removed func(TrackedFlight)) flightWrite {
+	return flightWrite{key: key, remove: true, done: func(prev, _ *TrackedFlight) {
+		if prev == nil {
+			return
+		}
+		at.deleteRemote(key)
+		if removed != nil {
+			removed(*prev)
+		}
+	}}
+}
+
+// deleteRemote removes a flight from the backend and the state store.
+// Failures are logged; both expire their entries eventually.
+func (at *AirportTracker) deleteRemote(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+	if err := at.backend.Delete(ctx, key); err != nil {
+		slog.Warn("failed to delete flight from backend", "key", key, "error", err)
+	}
+	if at.stateStore != nil {
+		if err := at.stateStore.Delete(ctx, key); err != nil {
+			slog.Warn("failed to delete flight from state store", "key", key, "error", err)
+		}
+	}
+}
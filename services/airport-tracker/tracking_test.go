@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// overlappingTracker tracks every containing airport, with KAAA and KBBB
+// 20 km apart so both geofences contain the point between them
+func overlappingTracker(t *testing.T) *AirportTracker {
+	t.Setenv("TRACKING_MODE", TrackingAll)
+	return newTestTracker(t, testAirport("KAAA", 40, -73), testAirport("KBBB", 40.18, -73))
+}
+
+func TestTrackingAllStoresOneEntryPerAirport(t *testing.T) {
+	at := overlappingTracker(t)
+	if _, err := at.processFlightUpdate(context.Background(), testUpdate("abc123", 40.05, -73)); err != nil {
+		t.Fatal(err)
+	}
+	if got := at.flights.Len(); got != 2 {
+		t.Fatalf("store entries = %d, want 2", got)
+	}
+	flight, ok := at.localFlight("abc123")
+	if !ok || flight.AirportCode != "KAAA" {
+		t.Fatalf("localFlight = %q, %v; want the nearest airport KAAA", flight.AirportCode, ok)
+	}
+}
+
+func TestFleetWideViewsCountAircraftOnce(t *testing.T) {
+	at := overlappingTracker(t)
+	for _, u := range []FlightUpdate{testUpdate("abc123", 40.05, -73), testUpdate("def456", 40.12, -73)} {
+		u.BaroAltitude = ptr(1000.0)
+		if _, err := at.processFlightUpdate(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unique := at.uniqueAircraft(at.flights.Collect(nil))
+	if len(unique) != 2 {
+		t.Fatalf("unique aircraft = %d, want 2", len(unique))
+	}
+	for _, flight := range unique {
+		want := map[string]string{"abc123": "KAAA", "def456": "KBBB"}[flight.ICAO24]
+		if flight.AirportCode != want {
+			t.Errorf("%s kept at %s, want nearest %s", flight.ICAO24, flight.AirportCode, want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	at.handleStats(rec, httptest.NewRequest("GET", "/api/v1/stats", nil))
+	var stats struct {
+		TrackedFlights int `json:"tracked_flights"`
+	}
+	json.NewDecoder(rec.Body).Decode(&stats)
+	if stats.TrackedFlights != 2 {
+		t.Errorf("tracked_flights = %d, want 2", stats.TrackedFlights)
+	}
+
+	rec = httptest.NewRecorder()
+	at.handleProximity(rec, httptest.NewRequest("GET", "/api/v1/flights/proximity?threshold=1", nil))
+	var proximity struct {
+		Count int `json:"count"`
+	}
+	json.NewDecoder(rec.Body).Decode(&proximity)
+	if proximity.Count != 0 {
+		t.Errorf("proximity pairs = %d, want 0 (an aircraft is not close to itself)", proximity.Count)
+	}
+}
+
+func TestLeavingAnAirportPublishesLeftChange(t *testing.T) {
+	at := overlappingTracker(t)
+	events := at.events.subscribe("KBBB")
+	defer at.events.unsubscribe(events)
+
+	at.processFlightUpdate(context.Background(), testUpdate("abc123", 40.05, -73))
+	<-events.send // entered KBBB
+
+	// South of KAAA, outside KBBB's geofence
+	at.processFlightUpdate(context.Background(), testUpdate("abc123", 39.7, -73))
+	select {
+	case change := <-events.send:
+		if change.NewStatus != StatusLeft || change.OldAirport != "KBBB" || change.ICAO24 != "abc123" {
+			t.Fatalf("change = %+v, want abc123 leaving KBBB", change)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no change published for leaving KBBB")
+	}
+	if _, ok := at.flights.Get(at.flightKey("abc123", "KBBB")); ok {
+		t.Error("entry at KBBB still stored")
+	}
+}
+
+func TestNearestAirportWithOverlappingGeofences(t *testing.T) {
+	// KNEAR's 50 km geofence holds the flight, but KFAR's 150 km one is
+	// listed first and also holds it; KOUT holds nothing but is nearest
+	far := testAirport("KFAR", 41, -73)
+	far.RadiusKm = 150
+	out := testAirport("KOUT", 40.2, -73.5)
+	out.RadiusKm = 1
+	at := newTestTracker(t, far, testAirport("KNEAR", 40, -73), out)
+
+	track(t, at, testUpdate("abc123", 40.15, -73.4))
+	flight, ok := at.flights.Get("abc123")
+	if !ok {
+		t.Fatal("flight not tracked")
+	}
+	if flight.AirportCode != "KNEAR" {
+		t.Errorf("tracked at %s, want the nearest containing airport KNEAR", flight.AirportCode)
+	}
+	want := haversineDistance(40.15, -73.4, 40.2, -73.5)
+	if flight.NearestAirport != "KOUT" || flight.NearestDistanceKm != want {
+		t.Errorf("nearest = %s at %v km, want KOUT at %v km", flight.NearestAirport, flight.NearestDistanceKm, want)
+	}
+}
+
+func TestOverlappingAirportsAssignNearestRegardlessOfOrder(t *testing.T) {
+	first := testAirport("KONE", 40, -73)
+	second := testAirport("KTWO", 40.3, -73)
+	second.ArrivalThresholdM = 500 // too low for the descent below
+	for _, order := range [][]AirportConfig{{first, second}, {second, first}} {
+		at := newTestTracker(t, order...)
+		update := testUpdate("abc123", 40.2, -73) // inside both, closer to KTWO
+		update.BaroAltitude = ptr(1000.0)
+		update.VerticalRate = ptr(-5.0)
+		track(t, at, update)
+
+		flight, _ := at.flights.Get("abc123")
+		if flight.AirportCode != "KTWO" {
+			t.Errorf("order %s first: tracked at %s, want KTWO", order[0].ICAO, flight.AirportCode)
+		}
+		// Classified against KTWO's thresholds, not KONE's
+		if flight.Status != StatusNearby {
+			t.Errorf("order %s first: status %s, want nearby under KTWO's arrival threshold", order[0].ICAO, flight.Status)
+		}
+		if want := haversineDistance(40.2, -73, 40.3, -73); flight.DistanceKm != want {
+			t.Errorf("distance %v, want %v to KTWO", flight.DistanceKm, want)
+		}
+	}
+}